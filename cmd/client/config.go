@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"blockchain-client/pkg/config"
+	"blockchain-client/pkg/ratelimit"
+)
+
+// configPath returns the config file named by -config in args, falling back
+// to CONFIG_FILE
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+// loadConfig builds the server configuration from the defaults, the config
+// file, the environment and the flags in args, each overriding the last, and
+// validates the result
+func loadConfig(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	cfg := config.Default()
+
+	path := configPath(args)
+	if path != "" {
+		if err := config.LoadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+
+	fs.String("config", path, "Path to a YAML, TOML or JSON config file (CONFIG_FILE)")
+	bindFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// bindFlags registers flags on fs that override cfg. Each flag defaults to
+// the value already in cfg, so only flags given on the command line change it.
+func bindFlags(fs *flag.FlagSet, cfg *config.Config) {
+	duration := func(d *config.Duration) *time.Duration {
+		return (*time.Duration)(d)
+	}
+
+	fs.StringVar(&cfg.Upstream.URL, "rpc", cfg.Upstream.URL, "Blockchain RPC URL")
+	fs.DurationVar(duration(&cfg.Upstream.Timeout), "upstream-timeout", time.Duration(cfg.Upstream.Timeout), "Timeout for each upstream request; 0 means none")
+	fs.BoolVar(&cfg.Upstream.VerifyBlocks, "verify-blocks", cfg.Upstream.VerifyBlocks, "Reject upstream blocks whose hash does not match their header")
+	fs.StringVar(&cfg.Server.Addr, "port", cfg.Server.Addr, "API server port")
+	fs.StringVar(&cfg.Index.DB, "index-db", cfg.Index.DB, "Path to the block store; enables the indexer when set")
+	fs.Uint64Var(&cfg.Index.Start, "index-start", cfg.Index.Start, "First block height to index")
+	fs.IntVar(&cfg.Index.Workers, "index-workers", cfg.Index.Workers, "Number of blocks fetched in parallel during backfill")
+	fs.BoolVar(&cfg.Index.Receipts, "index-receipts", cfg.Index.Receipts, "Index transaction receipts and logs")
+	fs.StringVar(&cfg.Export.Dir, "export-dir", cfg.Export.Dir, "Directory for export job output; enables the export endpoints when set")
+	fs.IntVar(&cfg.Export.MaxJobs, "export-max-jobs", cfg.Export.MaxJobs, "Number of export jobs that may run at once")
+	fs.Uint64Var(&cfg.Export.MaxBlocks, "export-max-blocks", cfg.Export.MaxBlocks, "Largest block range one export job may export")
+	fs.DurationVar(duration(&cfg.Export.Retention), "export-retention", time.Duration(cfg.Export.Retention), "How long finished export jobs stay in the job list")
+	fs.BoolVar(&cfg.Metrics.Enabled, "metrics", cfg.Metrics.Enabled, "Expose Prometheus metrics on /metrics")
+	fs.StringVar(&cfg.Tracing.Exporter, "trace-exporter", cfg.Tracing.Exporter, "Trace exporter: none, otlp, stdout or file")
+	fs.StringVar(&cfg.Tracing.Endpoint, "trace-endpoint", cfg.Tracing.Endpoint, "OTLP/HTTP collector URL; defaults to OTEL_EXPORTER_OTLP_ENDPOINT")
+	fs.StringVar(&cfg.Tracing.File, "trace-file", cfg.Tracing.File, "Output file for the file trace exporter")
+	fs.Float64Var(&cfg.Tracing.SampleRatio, "trace-sample", cfg.Tracing.SampleRatio, "Fraction of new traces to record")
+	fs.Uint64Var(&cfg.Upstream.ChainID, "chain-id", cfg.Upstream.ChainID, "Chain ID the upstream must serve for /readyz; 0 skips the check")
+	fs.DurationVar(duration(&cfg.Upstream.MaxHeadAge), "max-head-age", time.Duration(cfg.Upstream.MaxHeadAge), "Maximum age of the latest block before /readyz fails")
+	fs.DurationVar(duration(&cfg.Server.ReadHeaderTimeout), "read-header-timeout", time.Duration(cfg.Server.ReadHeaderTimeout), "Maximum time to read request headers")
+	fs.DurationVar(duration(&cfg.Server.ReadTimeout), "read-timeout", time.Duration(cfg.Server.ReadTimeout), "Maximum time to read a request")
+	fs.DurationVar(duration(&cfg.Server.WriteTimeout), "write-timeout", time.Duration(cfg.Server.WriteTimeout), "Maximum time to write a response; NDJSON streams are exempt")
+	fs.DurationVar(duration(&cfg.Server.IdleTimeout), "idle-timeout", time.Duration(cfg.Server.IdleTimeout), "Maximum time to keep idle connections open")
+	fs.IntVar(&cfg.Server.MaxHeaderBytes, "max-header-bytes", cfg.Server.MaxHeaderBytes, "Maximum size of request headers")
+	fs.Int64Var(&cfg.Server.MaxBodyBytes, "max-body-bytes", cfg.Server.MaxBodyBytes, "Maximum size of request bodies")
+	fs.DurationVar(duration(&cfg.Server.DrainDelay), "drain-delay", time.Duration(cfg.Server.DrainDelay), "Time to keep serving after readiness fails on shutdown")
+	fs.DurationVar(duration(&cfg.Server.ShutdownTimeout), "shutdown-timeout", time.Duration(cfg.Server.ShutdownTimeout), "Maximum time to wait for in-flight requests on shutdown")
+	fs.BoolVar(&cfg.Server.ValidateRequests, "validate-requests", cfg.Server.ValidateRequests, "Reject REST requests that do not match the OpenAPI document")
+	fs.StringVar(&cfg.Auth.KeysFile, "api-keys", cfg.Auth.KeysFile, "Path to the API key file; requires an API key on every request when set")
+	fs.DurationVar(duration(&cfg.Auth.ReloadInterval), "api-keys-reload", time.Duration(cfg.Auth.ReloadInterval), "How often to check the API key file for changes")
+	fs.Float64Var(&cfg.RateLimit.Rate, "rate-limit", cfg.RateLimit.Rate, "Compute units per second allowed for each client; 0 disables rate limiting")
+	fs.IntVar(&cfg.RateLimit.Burst, "rate-limit-burst", cfg.RateLimit.Burst, "Compute units a client may spend at once; defaults to one second's worth")
+	fs.Func("compute-units", "Compute unit cost overrides, e.g. eth_getLogs=75,/api/exports=200", func(v string) error {
+		costs, err := ratelimit.ParseCosts(v)
+		if err != nil {
+			return err
+		}
+		cfg.RateLimit.ComputeUnits = costs
+		return nil
+	})
+	fs.BoolVar(&cfg.RateLimit.TrustForwardedFor, "trust-forwarded-for", cfg.RateLimit.TrustForwardedFor, "Identify clients by the last X-Forwarded-For address, for use behind a load balancer")
+	fs.Func("methods", "Comma-separated JSON-RPC methods to enable; empty enables all", func(v string) error {
+		cfg.Methods = nil
+		for _, method := range strings.Split(v, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				cfg.Methods = append(cfg.Methods, method)
+			}
+		}
+		return nil
+	})
+	fs.StringVar(&cfg.Server.TLS.CertFile, "tls-cert", cfg.Server.TLS.CertFile, "TLS certificate file; serves HTTPS when set")
+	fs.StringVar(&cfg.Server.TLS.KeyFile, "tls-key", cfg.Server.TLS.KeyFile, "TLS private key file")
+	fs.StringVar(&cfg.Server.TLS.ClientCAFile, "tls-client-ca", cfg.Server.TLS.ClientCAFile, "CA bundle for verifying client certificates; requires mutual TLS when set")
+	fs.StringVar(&cfg.Server.TLS.RedirectAddr, "tls-redirect-addr", cfg.Server.TLS.RedirectAddr, "Address on which to redirect plaintext HTTP to HTTPS, e.g. :80")
+	fs.DurationVar(duration(&cfg.Server.TLS.ReloadInterval), "tls-reload-interval", time.Duration(cfg.Server.TLS.ReloadInterval), "How often to check the TLS files for changes")
+	fs.StringVar(&cfg.Logging.Level, "log-level", cfg.Logging.Level, "Log level: debug, info, warn or error")
+	fs.StringVar(&cfg.Logging.Format, "log-format", cfg.Logging.Format, "Log format: text or json")
+}
+
+// runConfig implements the config subcommand
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: blockchain-client config validate [-config file] [flags]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	if _, err := loadConfig(fs, args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("configuration is valid")
+}
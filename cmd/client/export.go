@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/export"
+)
+
+// parseHeight parses a block height given in decimal or 0x-prefixed hex
+func parseHeight(value string) (uint64, error) {
+	if strings.HasPrefix(value, "0x") {
+		return blockchain.ParseQuantity(value)
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// runExport implements the export subcommand
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	rpcURL := fs.String("rpc", blockchain.PolygonRPC, "Blockchain RPC URL")
+	from := fs.String("from", "", "First block to export")
+	to := fs.String("to", "", "Last block to export")
+	format := fs.String("format", "csv", "Output format: csv, ndjson or parquet")
+	out := fs.String("out", "export", "Output directory")
+	rotate := fs.Uint64("rotate", 1000, "Number of blocks per output file")
+	workers := fs.Int("workers", 4, "Number of blocks fetched in parallel")
+	skipReceipts := fs.Bool("skip-receipts", false, "Skip the receipts and logs tables")
+	logs := addLogFlags(fs)
+	fs.Parse(args)
+
+	logs.setup()
+
+	if envRPC := os.Getenv("BLOCKCHAIN_RPC_URL"); envRPC != "" {
+		*rpcURL = envRPC
+	}
+
+	fromBlock, err := parseHeight(*from)
+	if err != nil {
+		fatal("invalid -from block", "value", *from)
+	}
+	toBlock, err := parseHeight(*to)
+	if err != nil {
+		fatal("invalid -to block", "value", *to)
+	}
+	outFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		fatal("invalid -format", "error", err)
+	}
+
+	exporter, err := export.New(blockchain.NewClient(*rpcURL), export.Config{
+		From:         fromBlock,
+		To:           toBlock,
+		Format:       outFormat,
+		OutDir:       *out,
+		RotateBlocks: *rotate,
+		Workers:      *workers,
+		SkipReceipts: *skipReceipts,
+	})
+	if err != nil {
+		fatal("invalid export", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("exporting blocks", "from", fromBlock, "to", toBlock, "format", outFormat, "out", *out)
+	if err := exporter.Run(ctx); err != nil {
+		fatal("export failed", "error", err)
+	}
+
+	progress := exporter.Progress()
+	slog.Info("export complete", "files", len(progress.Files))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"blockchain-client/pkg/logging"
+)
+
+// logFlags holds the logging flags shared by every subcommand
+type logFlags struct {
+	level  *string
+	format *string
+}
+
+// addLogFlags registers the logging flags on fs
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		level:  fs.String("log-level", "info", "Log level: debug, info, warn or error"),
+		format: fs.String("log-format", "text", "Log format: text or json"),
+	}
+}
+
+// setup applies environment overrides and installs the configured logger as
+// the default for both slog and the log package
+func (f *logFlags) setup() {
+	if envLevel := os.Getenv("LOG_LEVEL"); envLevel != "" {
+		*f.level = envLevel
+	}
+	if envFormat := os.Getenv("LOG_FORMAT"); envFormat != "" {
+		*f.format = envFormat
+	}
+
+	logger, err := logging.New(os.Stderr, logging.Config{Level: *f.level, Format: *f.format})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+}
+
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,33 +1,317 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
 
 	"blockchain-client/pkg/api"
+	"blockchain-client/pkg/auth"
 	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/config"
+	"blockchain-client/pkg/indexer"
+	"blockchain-client/pkg/logging"
+	"blockchain-client/pkg/metrics"
+	"blockchain-client/pkg/ratelimit"
+	"blockchain-client/pkg/store"
+	"blockchain-client/pkg/tracing"
 )
 
 func main() {
-	rpcURL := flag.String("rpc", blockchain.PolygonRPC, "Blockchain RPC URL")
-	port := flag.String("port", ":8080", "API server port")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "healthcheck":
+			runHealthcheck(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		}
+	}
+
+	cfg, err := loadConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logLevel := new(slog.LevelVar)
+	logger, err := logging.New(os.Stderr, logging.Config{Level: cfg.Logging.Level, Format: cfg.Logging.Format, LevelVar: logLevel})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	var stores []*store.Store
+	defer func() {
+		for _, st := range stores {
+			st.Close()
+		}
+	}()
+
+	// Background workers stop when ctx is cancelled and are waited for
+	// before the stores and tracing are closed
+	var workers sync.WaitGroup
+	defer workers.Wait()
+
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+	}
+
+	var keys *auth.Keyring
+	if cfg.Auth.KeysFile != "" {
+		keys, err = auth.Load(cfg.Auth.KeysFile)
+		if err != nil {
+			fatal("failed to load API keys", "error", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			keys.Watch(ctx, time.Duration(cfg.Auth.ReloadInterval))
+		}()
+		slog.Info("API key authentication enabled", "keys", keys.Len())
+	}
+
+	// Options shared by every hosted chain
+	shared := []api.Option{
+		api.WithHTTPConfig(api.HTTPConfig{
+			ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+			ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+			WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+			IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+			MaxBodyBytes:      cfg.Server.MaxBodyBytes,
+			DrainDelay:        time.Duration(cfg.Server.DrainDelay),
+			ShutdownTimeout:   time.Duration(cfg.Server.ShutdownTimeout),
+		}),
+		api.WithMethods(cfg.Methods),
+		api.WithComputeUnits(cfg.RateLimit.ComputeUnits),
+	}
+	if m != nil {
+		shared = append(shared, api.WithMetrics(m))
+	}
+	if cfg.Export.Dir != "" {
+		shared = append(shared, api.WithExportDir(cfg.Export.Dir), api.WithExportLimits(api.ExportLimits{
+			MaxJobs:   cfg.Export.MaxJobs,
+			MaxBlocks: cfg.Export.MaxBlocks,
+			Retention: time.Duration(cfg.Export.Retention),
+		}))
+	}
+	if keys != nil {
+		shared = append(shared, api.WithAPIKeys(keys))
+	}
+	if cfg.Server.ValidateRequests {
+		shared = append(shared, api.WithValidation(api.ValidationConfig{Requests: true}))
+	}
+	if cfg.RateLimit.Rate > 0 {
+		// One limiter for every chain, so a client's budget is not multiplied
+		// by the number of chains it can reach
+		limiter := ratelimit.NewLimiter(ratelimit.Config{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst})
+		shared = append(shared, api.WithLimiter(limiter, cfg.RateLimit.TrustForwardedFor))
+	}
+
+	chains := cfg.HostedChains()
+	servers := make([]*api.Server, len(chains))
+	// Build the additional chains first so the default chain can host them
+	for i := len(chains) - 1; i >= 0; i-- {
+		chain := chains[i]
+		opts := slices.Clip(shared)
+		if i == 0 {
+			opts = append(opts, api.WithChains(servers[1:]...))
+			if tls := cfg.Server.TLS; tls.CertFile != "" {
+				opts = append(opts, api.WithTLS(api.TLSConfig{
+					CertFile:       tls.CertFile,
+					KeyFile:        tls.KeyFile,
+					ClientCAFile:   tls.ClientCAFile,
+					RedirectAddr:   tls.RedirectAddr,
+					ReloadInterval: time.Duration(tls.ReloadInterval),
+				}))
+			}
+		}
+
+		st, chainOpts := startChain(ctx, cfg, chain, m, i == 0, &workers)
+		if st != nil {
+			stores = append(stores, st)
+		}
+		servers[i] = api.NewServer(chain.Upstreams[0], append(opts, chainOpts...)...)
+	}
+	server := servers[0]
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reloadOnHangup(ctx, cfg, servers, logLevel)
+	}()
+
+	if err := server.Run(ctx, cfg.Server.Addr); err != nil {
+		slog.Error("server failed", "error", err)
+		stop()
+		workers.Wait()
+		os.Exit(1)
+	}
+}
+
+// chainVerifyTimeout bounds the startup check of each chain's upstreams
+const chainVerifyTimeout = 10 * time.Second
 
-	if envRPC := os.Getenv("BLOCKCHAIN_RPC_URL"); envRPC != "" {
-		*rpcURL = envRPC
+// startChain connects to a hosted chain's upstreams, verifies they serve the
+// configured chain, and starts its head follower and indexer on workers. It
+// returns the chain's block store, if any, and the server options for the
+// chain. Head and coalescing metrics are recorded for the default chain only.
+func startChain(ctx context.Context, cfg *config.Config, chain config.Chain, m *metrics.Metrics, isDefault bool, workers *sync.WaitGroup) (*store.Store, []api.Option) {
+	log := slog.With("chain", chain.Name)
+	if chain.Name == "" {
+		log = slog.Default()
 	}
 
-	if envPort := os.Getenv("API_PORT"); envPort != "" {
-		*port = envPort
+	clientOpts := []blockchain.ClientOption{blockchain.WithTimeout(time.Duration(cfg.Upstream.Timeout))}
+	if cfg.Upstream.VerifyBlocks {
+		clientOpts = append(clientOpts, blockchain.WithBlockVerification())
+	}
+	upstreams := blockchain.NewUpstreamSet(chain.Upstreams, clientOpts...)
+	if len(cfg.Upstream.Quorum) > 0 {
+		quorums := make(map[string]blockchain.Quorum, len(cfg.Upstream.Quorum))
+		for method, value := range cfg.Upstream.Quorum {
+			// Validated with the rest of the config
+			quorums[method], _ = blockchain.ParseQuorum(value)
+		}
+		upstreams.SetQuorum(quorums)
+	}
+	if chain.ChainID != 0 {
+		verifyCtx, cancel := context.WithTimeout(ctx, chainVerifyTimeout)
+		err := upstreams.VerifyChainID(verifyCtx, chain.ChainID)
+		cancel()
+		if err != nil {
+			fatal("upstream chain ID mismatch", "chain", chain.Name, "error", err)
+		}
+	}
+
+	stats := blockchain.NewStatsRecorder()
+	upstreams.AddObserver(stats)
+
+	follower := blockchain.NewHeadFollower(upstreams, blockchain.DefaultFollowInterval)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		follower.Run(ctx)
+	}()
+
+	if m != nil {
+		upstreams.AddObserver(m)
+		if isDefault {
+			m.RegisterCoalesceStats(upstreams.CoalesceStats)
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				m.TrackHead(ctx, follower, upstreams)
+			}()
+		}
 	}
 
-	server := api.NewServer(*rpcURL)
+	opts := []api.Option{
+		api.WithChain(chain.Name, chain.ChainID),
+		api.WithClient(upstreams),
+		api.WithUpstreamStats(stats),
+		api.WithHeadFollower(follower),
+		api.WithHealth(api.HealthConfig{ExpectedChainID: chain.ChainID, MaxHeadAge: time.Duration(cfg.Upstream.MaxHeadAge)}),
+	}
+
+	var st *store.Store
+	if chain.IndexDB != "" {
+		var err error
+		st, err = store.Open(chain.IndexDB)
+		if err != nil {
+			fatal("failed to open block store", "chain", chain.Name, "error", err)
+		}
+
+		ix := indexer.New(upstreams, st, indexer.Config{
+			StartHeight:  chain.IndexStart,
+			Workers:      cfg.Index.Workers,
+			SkipReceipts: !cfg.Index.Receipts,
+		})
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := ix.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Error("indexer stopped", "error", err)
+			}
+		}()
+
+		opts = append(opts, api.WithStore(st))
+		log.Info("indexing blocks", "from", chain.IndexStart, "db", chain.IndexDB)
+	}
+
+	upstreamURLs := make([]string, len(chain.Upstreams))
+	for i, u := range chain.Upstreams {
+		upstreamURLs[i] = blockchain.RedactURL(u)
+	}
+	log.Info("blockchain client connecting", "chain_id", chain.ChainID, "rpc", upstreamURLs)
+	return st, opts
+}
+
+// reloadOnHangup reloads the configuration on SIGHUP until ctx is cancelled,
+// applying the settings that are safe to change while serving
+func reloadOnHangup(ctx context.Context, initial *config.Config, servers []*api.Server, logLevel *slog.LevelVar) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		cfg, err := loadConfig(fs, os.Args[1:])
+		if err != nil {
+			slog.Error("failed to reload configuration, keeping current settings", "error", err)
+			continue
+		}
 
-	log.Printf("Blockchain client connecting to %s", *rpcURL)
-	log.Printf("Starting API server on %s", *port)
+		level, _ := logging.ParseLevel(cfg.Logging.Level)
+		logLevel.Set(level)
+		for _, server := range servers {
+			server.SetMethods(cfg.Methods)
+			server.SetComputeUnits(cfg.RateLimit.ComputeUnits)
+			if cfg.RateLimit.Rate > 0 {
+				server.SetRateLimit(ratelimit.Config{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst})
+			}
+		}
 
-	if err := server.Start(*port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		if restart := config.RestartRequired(initial, cfg); len(restart) > 0 {
+			slog.Warn("configuration reloaded; some changes take effect only after a restart", "sections", restart)
+			continue
+		}
+		slog.Info("configuration reloaded")
 	}
 }
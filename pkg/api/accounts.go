@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// addressPattern matches a hex-encoded account address
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// StorageValue represents the value of one storage slot
+type StorageValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// AccountResponse represents the response for the account endpoint
+type AccountResponse struct {
+	Address     string         `json:"address"`
+	Balance     string         `json:"balance"`
+	Nonce       string         `json:"nonce"`
+	CodeHash    string         `json:"codeHash"`
+	StorageHash string         `json:"storageHash"`
+	Storage     []StorageValue `json:"storage,omitempty"`
+	// The block the values were proven against, set when verified
+	BlockNumber string `json:"blockNumber,omitempty"`
+	BlockHash   string `json:"blockHash,omitempty"`
+	StateRoot   string `json:"stateRoot,omitempty"`
+	Verified    bool   `json:"verified,omitempty"`
+}
+
+// HandleGetAccount handles the /api/v1/accounts/{address} endpoint, returning
+// the account's balance, nonce and the storage slots listed in the storage
+// parameter at the block given by the block parameter (latest by default).
+// With verified=true, the values are checked against the block's state root
+// using the proofs returned by eth_getProof, and only served if they match.
+func (s *Server) HandleGetAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	address := r.PathValue("address")
+	if !addressPattern.MatchString(address) {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid address"})
+		return
+	}
+
+	query := r.URL.Query()
+	blockNumber := query.Get("block")
+	if blockNumber == "" {
+		blockNumber = "latest"
+	}
+	var storageKeys []string
+	if v := query.Get("storage"); v != "" {
+		storageKeys = strings.Split(v, ",")
+	}
+
+	if query.Get("verified") != "true" {
+		proof, err := s.client.GetProof(r.Context(), address, storageKeys, blockNumber)
+		if err != nil {
+			upstreamFailed(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, accountResponse(proof))
+		return
+	}
+
+	// Resolve the block first so the proof is requested at the exact block
+	// whose state root it is checked against
+	header, err := s.client.GetBlockByNumber(r.Context(), blockNumber, false)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if header == nil || header.Hash == "" {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "block not found"})
+		return
+	}
+	if err := header.VerifyHash(); err != nil {
+		s.verificationFailed(w, r, "block", err)
+		return
+	}
+
+	proof, err := s.client.GetProof(r.Context(), address, storageKeys, header.Number)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if err := proof.Verify(header.StateRoot); err != nil {
+		s.verificationFailed(w, r, "account proof", err)
+		return
+	}
+	if err := proofCovers(proof, address, storageKeys); err != nil {
+		s.verificationFailed(w, r, "account proof", err)
+		return
+	}
+
+	resp := accountResponse(proof)
+	resp.BlockNumber = header.Number
+	resp.BlockHash = header.Hash
+	resp.StateRoot = header.StateRoot
+	resp.Verified = true
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// proofCovers checks that a proof is for address and proves exactly the
+// requested storage slots, so that a valid proof of another account or slot
+// is not served as verified
+func proofCovers(proof *blockchain.AccountProof, address string, storageKeys []string) error {
+	if !strings.EqualFold(proof.Address, address) {
+		return fmt.Errorf("proof is for account %s, not %s", proof.Address, address)
+	}
+
+	requested := make(map[string]bool, len(storageKeys))
+	for _, key := range storageKeys {
+		requested[normalizeSlot(key)] = true
+	}
+	proven := make(map[string]bool, len(proof.StorageProof))
+	for _, sp := range proof.StorageProof {
+		slot := normalizeSlot(sp.Key)
+		if !requested[slot] {
+			return fmt.Errorf("proof includes unrequested slot %s", sp.Key)
+		}
+		proven[slot] = true
+	}
+	for _, key := range storageKeys {
+		if !proven[normalizeSlot(key)] {
+			return fmt.Errorf("proof is missing slot %s", key)
+		}
+	}
+	return nil
+}
+
+// normalizeSlot returns a storage slot in a canonical form, as the upstream
+// may return a requested slot zero-padded or in another case
+func normalizeSlot(key string) string {
+	key = strings.TrimLeft(strings.TrimPrefix(strings.ToLower(key), "0x"), "0")
+	return "0x" + key
+}
+
+// accountResponse converts an eth_getProof result into the account response
+func accountResponse(proof *blockchain.AccountProof) AccountResponse {
+	resp := AccountResponse{
+		Address:     proof.Address,
+		Balance:     proof.Balance,
+		Nonce:       proof.Nonce,
+		CodeHash:    proof.CodeHash,
+		StorageHash: proof.StorageHash,
+	}
+	for _, sp := range proof.StorageProof {
+		resp.Storage = append(resp.Storage, StorageValue{Key: sp.Key, Value: sp.Value})
+	}
+	return resp
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/rlp"
+	"blockchain-client/pkg/trie"
+)
+
+const testAccount = "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+
+// newAccountTestServer serves a header whose state holds only testAccount,
+// with proofs that report balance
+func newAccountTestServer(t *testing.T, balance string) *testServer {
+	t.Helper()
+	address, _ := hex.DecodeString(testAccount[2:])
+	codeHash := blockchain.Keccak256(nil)
+	account := rlp.EncodeList(rlp.EncodeUint(1), rlp.EncodeUint(1000), rlp.EncodeBytes(trie.EmptyRoot), rlp.EncodeBytes(codeHash))
+	// The only node of a single-entry trie is a leaf holding the whole key
+	leaf := rlp.EncodeList(rlp.EncodeBytes(append([]byte{0x20}, blockchain.Keccak256(address)...)), rlp.EncodeBytes(account))
+
+	header := &blockchain.Block{
+		ParentHash:       "0x" + strings.Repeat("a1", 32),
+		Sha3Uncles:       "0x" + strings.Repeat("00", 32),
+		Miner:            "0x" + strings.Repeat("00", 20),
+		StateRoot:        "0x" + hex.EncodeToString(blockchain.Keccak256(leaf)),
+		TransactionsRoot: "0x" + hex.EncodeToString(trie.EmptyRoot),
+		ReceiptsRoot:     "0x" + hex.EncodeToString(trie.EmptyRoot),
+		LogsBloom:        "0x" + strings.Repeat("00", 256),
+		Difficulty:       "0x0",
+		Number:           "0x10",
+		GasLimit:         "0x1c9c380",
+		GasUsed:          "0x0",
+		Timestamp:        "0x65a7751f",
+		ExtraData:        "0x",
+		MixHash:          "0x" + strings.Repeat("00", 32),
+		Nonce:            "0x0000000000000000",
+	}
+	header.Hash, _ = header.ComputeHash()
+
+	ts := newTestServer()
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		return header, nil
+	}
+	ts.mock.getProofFunc = func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+		if blockNumber != "latest" && blockNumber != header.Number {
+			t.Errorf("expected the proof to be requested at the resolved block, got %s", blockNumber)
+		}
+		proof := &blockchain.AccountProof{
+			Address:      address,
+			AccountProof: []string{"0x" + hex.EncodeToString(leaf)},
+			Balance:      balance,
+			Nonce:        "0x1",
+			CodeHash:     "0x" + hex.EncodeToString(codeHash),
+			StorageHash:  "0x" + hex.EncodeToString(trie.EmptyRoot),
+		}
+		for _, key := range storageKeys {
+			proof.StorageProof = append(proof.StorageProof, blockchain.StorageProof{Key: key, Value: "0x0", Proof: []string{}})
+		}
+		return proof, nil
+	}
+	return ts
+}
+
+func TestHandleGetAccountVerified(t *testing.T) {
+	ts := newAccountTestServer(t, "0x3e8")
+	rec := httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts/"+testAccount+"?verified=true&storage=0x0", nil))
+
+	var resp AccountResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || !resp.Verified || resp.Balance != "0x3e8" || resp.BlockNumber != "0x10" {
+		t.Fatalf("expected a verified account, got %d %+v", rec.Code, resp)
+	}
+	if len(resp.Storage) != 1 || resp.Storage[0].Value != "0x0" {
+		t.Errorf("expected the requested storage slot, got %+v", resp.Storage)
+	}
+}
+
+func TestHandleGetAccountRejectsBadProof(t *testing.T) {
+	ts := newAccountTestServer(t, "0x3e9")
+
+	rec := httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts/"+testAccount+"?verified=true", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected a balance that does not match the proof to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Without verification the upstream's values are passed through
+	rec = httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts/"+testAccount, nil))
+	var resp AccountResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || resp.Verified || resp.Balance != "0x3e9" {
+		t.Errorf("expected unverified values, got %d %+v", rec.Code, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts/0x1234", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid address to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestHandleGetAccountRejectsProofOfOtherState(t *testing.T) {
+	other := "0x" + strings.Repeat("11", 20)
+
+	tests := []struct {
+		name   string
+		target string
+		alter  func(*blockchain.AccountProof)
+	}{
+		{
+			// A valid proof of testAccount, served for another address
+			name:   "wrong account",
+			target: "/api/v1/accounts/" + other + "?verified=true",
+			alter:  func(p *blockchain.AccountProof) { p.Address = testAccount },
+		},
+		{
+			name:   "missing slot",
+			target: "/api/v1/accounts/" + testAccount + "?verified=true&storage=0x0,0x1",
+			alter:  func(p *blockchain.AccountProof) { p.StorageProof = p.StorageProof[:1] },
+		},
+		{
+			name:   "other slot",
+			target: "/api/v1/accounts/" + testAccount + "?verified=true&storage=0x1",
+			alter:  func(p *blockchain.AccountProof) { p.StorageProof[0].Key = "0x2" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newAccountTestServer(t, "0x3e8")
+			getProof := ts.mock.getProofFunc
+			ts.mock.getProofFunc = func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+				proof, err := getProof(testAccount, storageKeys, blockNumber)
+				if err == nil {
+					tt.alter(proof)
+				}
+				return proof, err
+			}
+
+			rec := httptest.NewRecorder()
+			ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != http.StatusBadGateway {
+				t.Errorf("expected the proof to be rejected, got %d %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Slots are matched regardless of zero padding and case
+	ts := newAccountTestServer(t, "0x3e8")
+	getProof := ts.mock.getProofFunc
+	ts.mock.getProofFunc = func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+		proof, err := getProof(address, storageKeys, blockNumber)
+		proof.Address = "0x" + strings.ToUpper(proof.Address[2:])
+		proof.StorageProof[0].Key = "0x" + strings.Repeat("0", 63) + "a"
+		return proof, err
+	}
+	rec := httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts/"+testAccount+"?verified=true&storage=0xA", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected an equivalent slot to be accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blockchain-client/pkg/auth"
+)
+
+// APIKeyHeader carries the caller's API key
+const APIKeyHeader = "X-API-Key"
+
+// keyPathPrefix is the JSON-RPC endpoint that takes the API key in its path,
+// e.g. POST /rpc/{key}
+const keyPathPrefix = "/rpc/"
+
+// JSON-RPC error codes for rejected requests
+const (
+	rpcCodeUnauthorized  = -32001
+	rpcCodeMethodDenied  = -32004
+	rpcCodeLimitExceeded = -32005
+)
+
+// WithAPIKeys requires every API request to carry one of keys, either in the
+// X-API-Key header, as a bearer token, or in the /rpc/{key} path. Health,
+// status and metrics endpoints stay open.
+func WithAPIKeys(keys *auth.Keyring) Option {
+	return func(s *Server) {
+		s.keys = keys
+	}
+}
+
+// apiKey returns the API key that authenticated the current request, if any
+func apiKey(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.apiKey
+	}
+	return ""
+}
+
+// openPath reports whether path is served without an API key
+func openPath(path string) bool {
+	switch path {
+	case "/healthz", "/readyz", "/status", "/metrics", "/openapi.json", "/docs":
+		return true
+	}
+	return false
+}
+
+// isRPCPath reports whether path is served by the JSON-RPC endpoint
+func isRPCPath(path string) bool {
+	return path == "/" || strings.HasPrefix(path, keyPathPrefix)
+}
+
+// redactPath hides an API key given in the request path, including after a
+// chain prefix, so it never reaches logs or traces
+func redactPath(path string) string {
+	if i := strings.Index(path, keyPathPrefix); i >= 0 && len(path) > i+len(keyPathPrefix) {
+		return path[:i+len(keyPathPrefix)] + "REDACTED"
+	}
+	return path
+}
+
+// requestKey extracts the caller's API key from the request
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key, ok := strings.CutPrefix(r.URL.Path, keyPathPrefix); ok {
+		return key
+	}
+	return ""
+}
+
+// restOperation returns the route that REST requests to path are authorized
+// and charged as. Versioned routes share the route of the unversioned route
+// they replace, so key permissions and costs apply to both.
+func restOperation(path string) string {
+	if rest, ok := strings.CutPrefix(path, apiPrefix+"/"); ok {
+		path = "/api/" + rest
+		// The versioned /blocks/latest is the latest block, not its number
+		if path == "/api/blocks/latest" {
+			return "/api/blocks"
+		}
+	}
+	for _, route := range []string{"/api/blocks/latest", "/api/blocks", "/api/tx", "/api/accounts/", "/api/exports"} {
+		if path == route || strings.HasPrefix(path, strings.TrimSuffix(route, "/")+"/") {
+			return route
+		}
+	}
+	return path
+}
+
+// authenticate rejects requests without a valid API key. JSON-RPC calls are
+// authorized per method by HandleJSONRPC; REST calls are authorized and
+// charged here by route.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if openPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestKey(r)
+		entry, ok := s.keys.Lookup(key)
+		if !ok {
+			message := "invalid API key"
+			if key == "" {
+				message = "API key required"
+			}
+			if isRPCPath(r.URL.Path) {
+				writeJSONResponse(w, http.StatusUnauthorized, RPCResponse{
+					JSONRPC: "2.0",
+					Error:   &RPCError{Code: rpcCodeUnauthorized, Message: message},
+				})
+				return
+			}
+			writeJSONResponse(w, http.StatusUnauthorized, ErrorResponse{Error: message})
+			return
+		}
+		// Recorded on the request info rather than a new request so that
+		// instrument still sees the route ServeMux matches
+		setAPIKey(r.Context(), key, entry.Name)
+
+		if !isRPCPath(r.URL.Path) {
+			if err := s.keys.Authorize(key, restOperation(r.URL.Path), s.restCost(r)); err != nil {
+				status, _ := authFailure(w, err)
+				writeJSONResponse(w, status, ErrorResponse{Error: err.Error()})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorizeRPC checks that the request's API key may call method, charging
+// its rate limit and quota. It returns the HTTP status and JSON-RPC error to
+// respond with when the call is rejected.
+func (s *Server) authorizeRPC(w http.ResponseWriter, r *http.Request, method string) (int, *RPCError) {
+	if s.keys == nil {
+		return http.StatusOK, nil
+	}
+	err := s.keys.Authorize(apiKey(r.Context()), method, s.requestCost(r, method))
+	if err == nil {
+		return http.StatusOK, nil
+	}
+	status, code := authFailure(w, err)
+	return status, &RPCError{Code: code, Message: err.Error()}
+}
+
+// authFailure maps an authorization error to its HTTP status and JSON-RPC
+// error code, setting Retry-After for exceeded limits
+func authFailure(w http.ResponseWriter, err error) (int, int) {
+	var limitErr *auth.LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		setRetryAfter(w, limitErr.RetryAfter)
+		return http.StatusTooManyRequests, rpcCodeLimitExceeded
+	case errors.Is(err, auth.ErrMethodNotAllowed):
+		return http.StatusForbidden, rpcCodeMethodDenied
+	case errors.Is(err, auth.ErrUnknownKey):
+		return http.StatusUnauthorized, rpcCodeUnauthorized
+	}
+	return http.StatusInternalServerError, -32603
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounding up
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
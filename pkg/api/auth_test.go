@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/auth"
+	"blockchain-client/pkg/logging"
+)
+
+func newAuthTestServer(t *testing.T, keyFile string) (*testServer, http.Handler) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(keyFile), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	keys, err := auth.Load(path)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+
+	ts := newTestServer()
+	ts.server.keys = keys
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+	return ts, ts.server.SetupRoutes()
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	_, handler := newAuthTestServer(t, `{"keys":[{"name":"team","key":"secret"}]}`)
+	rpc := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"header", "/", APIKeyHeader, "secret", http.StatusOK},
+		{"bearer", "/", "Authorization", "Bearer secret", http.StatusOK},
+		{"path", "/rpc/secret", "", "", http.StatusOK},
+		{"missing", "/", "", "", http.StatusUnauthorized},
+		{"wrong header", "/", APIKeyHeader, "guess", http.StatusUnauthorized},
+		{"wrong path", "/rpc/guess", "", "", http.StatusUnauthorized},
+		{"rest missing", "/api/blocks/latest", "", "", http.StatusUnauthorized},
+		{"rest header", "/api/blocks/latest", APIKeyHeader, "secret", http.StatusOK},
+		{"unknown path", "/eth", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := "POST"
+			if strings.HasPrefix(tt.path, "/api/") {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, tt.path, strings.NewReader(rpc))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && isRPCPath(tt.path) {
+				var resp RPCResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if resp.Error == nil || resp.Error.Code != rpcCodeUnauthorized {
+					t.Errorf("expected JSON-RPC error %d, got %+v", rpcCodeUnauthorized, resp.Error)
+				}
+			}
+		})
+	}
+
+	for _, path := range []string{"/healthz", "/status"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected %s to be open, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAPIKeyLimits(t *testing.T) {
+	_, handler := newAuthTestServer(t, `{"keys":[
+		{"name":"blocks-only","key":"k1","methods":["eth_blockNumber"],"rateLimit":1,"burst":1},
+		{"name":"quota","key":"k2","dailyQuota":10}
+	]}`)
+
+	call := func(key, method string) (*httptest.ResponseRecorder, RPCResponse) {
+		body := `{"jsonrpc":"2.0","method":"` + method + `","params":["0x1",false],"id":7}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/rpc/"+key, strings.NewReader(body)))
+		var resp RPCResponse
+		json.NewDecoder(bytes.NewReader(rec.Body.Bytes())).Decode(&resp)
+		return rec, resp
+	}
+
+	rec, resp := call("k1", "eth_getBlockByNumber")
+	if rec.Code != http.StatusForbidden || resp.Error == nil || resp.Error.Code != rpcCodeMethodDenied || string(resp.ID) != "7" {
+		t.Errorf("expected method denied error, got %d %+v", rec.Code, resp)
+	}
+
+	if rec, _ := call("k1", "eth_blockNumber"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first call to succeed, got %d", rec.Code)
+	}
+	rec, resp = call("k1", "eth_blockNumber")
+	if rec.Code != http.StatusTooManyRequests || resp.Error == nil || resp.Error.Code != rpcCodeLimitExceeded {
+		t.Errorf("expected rate limit error, got %d %+v", rec.Code, resp)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After of 1 second, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// eth_blockNumber costs 10 compute units, exhausting the quota
+	if rec, _ := call("k2", "eth_blockNumber"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first call to succeed, got %d", rec.Code)
+	}
+	rec, resp = call("k2", "eth_blockNumber")
+	if rec.Code != http.StatusTooManyRequests || resp.Error == nil || !strings.Contains(resp.Error.Message, "quota") {
+		t.Errorf("expected quota error, got %d %+v", rec.Code, resp)
+	}
+
+	req := httptest.NewRequest("GET", "/api/blocks/latest", nil)
+	req.Header.Set(APIKeyHeader, "k2")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected REST quota error with Retry-After, got %d", rec.Code)
+	}
+}
+
+func TestAPIKeyRedactedFromLogs(t *testing.T) {
+	_, handler := newAuthTestServer(t, `{"keys":[{"name":"team","key":"supersecret"}]}`)
+
+	var logs bytes.Buffer
+	logger, _ := logging.New(&logs, logging.Config{Format: logging.FormatJSON})
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	body := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/rpc/supersecret", strings.NewReader(body)))
+
+	if strings.Contains(logs.String(), "supersecret") {
+		t.Fatalf("API key leaked into logs: %s", logs.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse access log: %v", err)
+	}
+	if entry["path"] != "/rpc/REDACTED" || entry["route"] != "/rpc/{key}" || entry["api_key"] != "team" {
+		t.Errorf("unexpected access log entry %v", entry)
+	}
+}
+
+func TestRESTOperation(t *testing.T) {
+	tests := map[string]string{
+		"/api/blocks/latest":                "/api/blocks/latest",
+		"/api/blocks":                       "/api/blocks",
+		"/api/v1/blocks":                    "/api/blocks",
+		"/api/v1/blocks/latest":             "/api/blocks",
+		"/api/v1/blocks/0x10/transactions":  "/api/blocks",
+		"/api/v1/tx/0x1234":                 "/api/tx",
+		"/api/accounts/0x1234/transactions": "/api/accounts/",
+		"/api/v1/accounts/0x1234":           "/api/accounts/",
+		"/api/v1/exports/job":               "/api/exports",
+		"/api/block":                        "/api/block",
+	}
+	for path, want := range tests {
+		if got := restOperation(path); got != want {
+			t.Errorf("restOperation(%q) = %q; want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/store"
+)
+
+const (
+	// defaultRangeWorkers is the number of upstream batches fetched concurrently
+	defaultRangeWorkers = 4
+	// defaultRangeBatchSize is the number of blocks requested per upstream batch
+	defaultRangeBatchSize = 25
+	// defaultRangeLimit is the page size for JSON range responses
+	defaultRangeLimit = 100
+	// maxRangeLimit is the largest page size for JSON range responses
+	maxRangeLimit = 1000
+	// maxStreamBlocks is the largest range that can be streamed as NDJSON
+	maxStreamBlocks = 100000
+
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// BlockRangeResponse represents a page of blocks from the range endpoint
+type BlockRangeResponse struct {
+	Blocks     []*blockchain.Block `json:"blocks"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+// WithRangeConcurrency sets how many upstream batches the range endpoint
+// fetches concurrently and how many blocks each batch requests
+func WithRangeConcurrency(workers, batchSize int) Option {
+	return func(s *Server) {
+		s.rangeWorkers = workers
+		s.rangeBatchSize = batchSize
+	}
+}
+
+// getBlocks returns the blocks at numbers, serving ingested blocks from the
+// store and fetching the rest in one upstream batch
+func (s *Server) getBlocks(ctx context.Context, numbers []uint64, fullTransactions bool) ([]*blockchain.Block, error) {
+	blocks := make([]*blockchain.Block, len(numbers))
+
+	var missing []string
+	var missingIdx []int
+	for i, number := range numbers {
+		if s.store != nil {
+			block, err := s.store.GetBlock(number, fullTransactions)
+			s.observeCache(err == nil)
+			if err == nil {
+				blocks[i] = block
+				continue
+			}
+			if !errors.Is(err, store.ErrNotFound) {
+				slog.ErrorContext(ctx, "failed to read block from store", "block", number, "error", err)
+			}
+		}
+		missing = append(missing, blockchain.EncodeQuantity(number))
+		missingIdx = append(missingIdx, i)
+	}
+
+	if len(missing) > 0 {
+		fetched, err := s.client.GetBlocksByNumber(ctx, missing, fullTransactions)
+		if err != nil {
+			return nil, err
+		}
+		for j, block := range fetched {
+			if block == nil {
+				return nil, fmt.Errorf("block %s not found", missing[j])
+			}
+			blocks[missingIdx[j]] = block
+		}
+	}
+
+	return blocks, nil
+}
+
+// batchResult represents the outcome of fetching one batch of a range
+type batchResult struct {
+	blocks []*blockchain.Block
+	err    error
+}
+
+// fetchBlockRange fetches blocks [from, to] in concurrent batches and calls
+// emit for each block in order. At most rangeWorkers batches are held in
+// memory at once, so slow consumers apply backpressure to the upstream.
+func (s *Server) fetchBlockRange(ctx context.Context, from, to uint64, fullTransactions bool, emit func(*blockchain.Block) error) error {
+	workers := s.rangeWorkers
+	if workers <= 0 {
+		workers = defaultRangeWorkers
+	}
+	batchSize := uint64(s.rangeBatchSize)
+	if batchSize == 0 {
+		batchSize = defaultRangeBatchSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batchCount := int((to-from)/batchSize + 1)
+	results := make([]chan batchResult, batchCount)
+	for i := range results {
+		results[i] = make(chan batchResult, 1)
+	}
+
+	slots := make(chan struct{}, workers)
+	go func() {
+		for i := 0; i < batchCount; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			start := from + uint64(i)*batchSize
+			end := start + batchSize - 1
+			if end > to {
+				end = to
+			}
+			numbers := make([]uint64, 0, end-start+1)
+			for n := start; n <= end; n++ {
+				numbers = append(numbers, n)
+			}
+
+			go func(i int) {
+				blocks, err := s.getBlocks(ctx, numbers, fullTransactions)
+				results[i] <- batchResult{blocks: blocks, err: err}
+			}(i)
+		}
+	}()
+
+	for i := 0; i < batchCount; i++ {
+		var res batchResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// Release the slot only once the batch has been consumed
+		<-slots
+
+		if res.err != nil {
+			return res.err
+		}
+		for _, block := range res.blocks {
+			if err := emit(block); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// wantsNDJSON reports whether the client asked for a streamed NDJSON response
+func wantsNDJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// blockRange is the part of a range request served by one response
+type blockRange struct {
+	from, to uint64
+	// stream is set when the range is written as NDJSON rather than a page
+	stream bool
+	// nextCursor resumes the range after a page, if there are more blocks
+	nextCursor string
+}
+
+// size returns the number of blocks in the range
+func (br blockRange) size() uint64 {
+	return br.to - br.from + 1
+}
+
+// isBlockRange reports whether r asks the blocks route for a range
+func isBlockRange(r *http.Request) bool {
+	switch r.URL.Path {
+	case apiPrefix + "/blocks":
+		return true
+	case "/api/blocks":
+		return r.URL.Query().Has("from") || r.URL.Query().Has("to")
+	}
+	return false
+}
+
+// parseBlockRange parses the from, to, cursor and limit of a range request
+// into the blocks its response holds
+func parseBlockRange(r *http.Request) (blockRange, error) {
+	query := r.URL.Query()
+	from, err := parseBlockParam(query.Get("from"))
+	if err != nil {
+		return blockRange{}, errors.New("invalid from block")
+	}
+	to, err := parseBlockParam(query.Get("to"))
+	if err != nil {
+		return blockRange{}, errors.New("invalid to block")
+	}
+	if to < from {
+		return blockRange{}, errors.New("to block must not be below from block")
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		next, err := blockchain.ParseQuantity(cursor)
+		if err != nil || next < from || next > to {
+			return blockRange{}, errors.New("invalid cursor")
+		}
+		from = next
+	}
+
+	if wantsNDJSON(r) {
+		if to-from >= maxStreamBlocks {
+			return blockRange{}, fmt.Errorf("range exceeds %d blocks", maxStreamBlocks)
+		}
+		return blockRange{from: from, to: to, stream: true}, nil
+	}
+
+	limit := defaultRangeLimit
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxRangeLimit {
+			return blockRange{}, fmt.Errorf("limit must be between 1 and %d", maxRangeLimit)
+		}
+	}
+
+	br := blockRange{from: from, to: to}
+	if to-from >= uint64(limit) {
+		br.to = from + uint64(limit) - 1
+		br.nextCursor = blockchain.EncodeQuantity(br.to + 1)
+	}
+	return br, nil
+}
+
+// HandleGetBlockRange handles the /api/v1/blocks?from=&to= endpoint
+func (s *Server) HandleGetBlockRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	br, err := parseBlockRange(r)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	fullTx := r.URL.Query().Get("full") == "true"
+
+	if br.stream {
+		s.streamBlockRange(w, r, br.from, br.to, fullTx)
+		return
+	}
+
+	resp := BlockRangeResponse{Blocks: []*blockchain.Block{}, NextCursor: br.nextCursor}
+	err = s.fetchBlockRange(r.Context(), br.from, br.to, fullTx, func(block *blockchain.Block) error {
+		resp.Blocks = append(resp.Blocks, block)
+		return nil
+	})
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, resp)
+}
+
+// streamBlockRange writes blocks [from, to] as newline-delimited JSON as they
+// arrive. Errors after the first block are reported as a final error line
+// since the status code has already been sent.
+func (s *Server) streamBlockRange(w http.ResponseWriter, r *http.Request, from, to uint64, fullTransactions bool) {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	// Large ranges can take longer than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	started := false
+
+	err := s.fetchBlockRange(r.Context(), from, to, fullTransactions, func(block *blockchain.Block) error {
+		if !started {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := enc.Encode(block); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	if !started {
+		upstreamFailed(w, err)
+		return
+	}
+	if r.Context().Err() == nil {
+		enc.Encode(ErrorResponse{Error: err.Error()})
+	}
+}
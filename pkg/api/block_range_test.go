@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// newRangeTestServer creates a test server whose mock client serves any block
+// and records the size of each upstream batch
+func newRangeTestServer() (*testServer, *[]int, *sync.Mutex) {
+	ts := newTestServer()
+	ts.server.rangeWorkers = 3
+	ts.server.rangeBatchSize = 4
+
+	var mu sync.Mutex
+	var batches []int
+	ts.mock.getBlocksByNumberFunc = func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+		mu.Lock()
+		batches = append(batches, len(blockNumbers))
+		mu.Unlock()
+
+		blocks := make([]*blockchain.Block, len(blockNumbers))
+		for i, n := range blockNumbers {
+			blocks[i] = &blockchain.Block{Number: n, Hash: "0xhash" + n}
+		}
+		return blocks, nil
+	}
+	return ts, &batches, &mu
+}
+
+func TestHandleGetBlockRange(t *testing.T) {
+	t.Run("returns blocks in order with a next cursor", func(t *testing.T) {
+		ts, batches, _ := newRangeTestServer()
+
+		req := httptest.NewRequest("GET", "/api/blocks?from=10&to=0x30&limit=15", nil)
+		rec := httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status OK; got %v: %s", rec.Code, rec.Body)
+		}
+
+		var resp BlockRangeResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if len(resp.Blocks) != 15 {
+			t.Fatalf("expected 15 blocks; got %d", len(resp.Blocks))
+		}
+		for i, block := range resp.Blocks {
+			if want := blockchain.EncodeQuantity(uint64(10 + i)); block.Number != want {
+				t.Errorf("expected block %s at position %d; got %s", want, i, block.Number)
+			}
+		}
+		if resp.NextCursor != "0x19" {
+			t.Errorf("expected next cursor 0x19; got %s", resp.NextCursor)
+		}
+		if len(*batches) != 4 {
+			t.Errorf("expected 4 upstream batches; got %d", len(*batches))
+		}
+	})
+
+	t.Run("cursor continues the range", func(t *testing.T) {
+		ts, _, _ := newRangeTestServer()
+
+		req := httptest.NewRequest("GET", "/api/blocks?from=10&to=30&limit=15&cursor=0x19", nil)
+		rec := httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, req)
+
+		var resp BlockRangeResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if len(resp.Blocks) != 6 || resp.Blocks[0].Number != "0x19" {
+			t.Errorf("expected 6 blocks starting at 0x19; got %d", len(resp.Blocks))
+		}
+		if resp.NextCursor != "" {
+			t.Errorf("expected no next cursor; got %s", resp.NextCursor)
+		}
+	})
+
+	t.Run("streams NDJSON", func(t *testing.T) {
+		ts, batches, _ := newRangeTestServer()
+
+		req := httptest.NewRequest("GET", "/api/blocks?from=0&to=2999", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("expected NDJSON content type; got %s", ct)
+		}
+
+		scanner := bufio.NewScanner(rec.Body)
+		count := 0
+		for scanner.Scan() {
+			var block blockchain.Block
+			if err := json.Unmarshal(scanner.Bytes(), &block); err != nil {
+				t.Fatalf("line %d: could not decode block: %v", count, err)
+			}
+			if want := blockchain.EncodeQuantity(uint64(count)); block.Number != want {
+				t.Fatalf("expected block %s on line %d; got %s", want, count, block.Number)
+			}
+			count++
+		}
+		if count != 3000 {
+			t.Errorf("expected 3000 blocks; got %d", count)
+		}
+		if len(*batches) != 750 {
+			t.Errorf("expected 750 upstream batches; got %d", len(*batches))
+		}
+	})
+
+	t.Run("upstream errors", func(t *testing.T) {
+		ts, _, _ := newRangeTestServer()
+		ts.mock.getBlocksByNumberFunc = func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+			if blockNumbers[0] == "0x8" {
+				return nil, errors.New("upstream unavailable")
+			}
+			return make([]*blockchain.Block, len(blockNumbers)), nil
+		}
+
+		rec := httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, httptest.NewRequest("GET", "/api/blocks?from=0&to=3", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500 for missing blocks; got %d", rec.Code)
+		}
+
+		ts.mock.getBlocksByNumberFunc = func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+			if blockNumbers[0] == "0x8" {
+				return nil, errors.New("upstream unavailable")
+			}
+			blocks := make([]*blockchain.Block, len(blockNumbers))
+			for i, n := range blockNumbers {
+				blocks[i] = &blockchain.Block{Number: n}
+			}
+			return blocks, nil
+		}
+
+		req := httptest.NewRequest("GET", "/api/blocks?from=0&to=20&format=ndjson", nil)
+		rec = httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, req)
+
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			json.Unmarshal(scanner.Bytes(), &line)
+			lines = append(lines, line)
+		}
+		if len(lines) != 9 {
+			t.Fatalf("expected 8 blocks and an error line; got %d lines", len(lines))
+		}
+		if lines[8]["error"] != "upstream unavailable" {
+			t.Errorf("expected trailing error line; got %v", lines[8])
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		ts, _, _ := newRangeTestServer()
+
+		for _, query := range []string{
+			"from=abc&to=10",
+			"from=10",
+			"from=10&to=5",
+			"from=10&to=20&limit=5000",
+			"from=10&to=20&cursor=0x1",
+			"from=0&to=200000&format=ndjson",
+		} {
+			rec := httptest.NewRecorder()
+			ts.server.HandleGetBlockByNumber(rec, httptest.NewRequest("GET", "/api/blocks?"+query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400; got %d", query, rec.Code)
+			}
+		}
+	})
+}
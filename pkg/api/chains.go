@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ChainHeader selects the chain a request is served by, by name or decimal
+// chain ID, when the path carries no chain prefix
+const ChainHeader = "X-Chain-ID"
+
+// WithChain names the chain the server serves and its chain ID, which select
+// it in request paths and the X-Chain-ID header
+func WithChain(name string, chainID uint64) Option {
+	return func(s *Server) {
+		s.chainName = name
+		s.chainID = chainID
+	}
+}
+
+// WithChains hosts additional chains on the server. Requests whose path
+// starts with a chain's name or ID, e.g. /polygon/api/blocks/latest or
+// /137/api/blocks/latest, or that carry the chain in the X-Chain-ID header,
+// are served by that chain's server; every other request is served by s.
+func WithChains(servers ...*Server) Option {
+	return func(s *Server) {
+		s.chains = append(s.chains, servers...)
+	}
+}
+
+// setChain records the chain serving the current request and the route its
+// ServeMux matched
+func setChain(ctx context.Context, chain, route string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.chain = chain
+	}
+	setRoute(ctx, route)
+}
+
+// matchesChain reports whether id names s by chain name or decimal chain ID
+func (s *Server) matchesChain(id string) bool {
+	if id == "" {
+		return false
+	}
+	if id == s.chainName {
+		return true
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	return err == nil && s.chainID != 0 && n == s.chainID
+}
+
+// chainFor returns the hosted chain identified by id, including s itself
+func (s *Server) chainFor(id string) *Server {
+	if s.matchesChain(id) {
+		return s
+	}
+	for _, chain := range s.chains {
+		if chain.matchesChain(id) {
+			return chain
+		}
+	}
+	return nil
+}
+
+// routeChains dispatches each request to the chain selected by its path
+// prefix or X-Chain-ID header. own serves requests for s and those that name
+// no chain.
+func (s *Server) routeChains(own http.Handler) http.Handler {
+	handlers := make(map[*Server]http.Handler, len(s.chains)+1)
+	handlers[s] = own
+	for _, chain := range s.chains {
+		handlers[chain] = chain.routes()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segment, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if chain := s.chainFor(segment); chain != nil {
+			// Route a shallow copy so the chain's ServeMux sees the path
+			// without the prefix and records its pattern where we can read it
+			routed := *r
+			routed.URL = new(url.URL)
+			*routed.URL = *r.URL
+			routed.URL.Path = "/" + rest
+			routed.URL.RawPath = ""
+			handlers[chain].ServeHTTP(w, &routed)
+			setChain(r.Context(), chain.chainName, routed.Pattern)
+			return
+		}
+
+		if id := r.Header.Get(ChainHeader); id != "" {
+			chain := s.chainFor(id)
+			if chain == nil {
+				writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "unknown chain " + strconv.Quote(id)})
+				return
+			}
+			handlers[chain].ServeHTTP(w, r)
+			setChain(r.Context(), chain.chainName, r.Pattern)
+			return
+		}
+
+		own.ServeHTTP(w, r)
+		setChain(r.Context(), s.chainName, r.Pattern)
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/logging"
+	"blockchain-client/pkg/ratelimit"
+)
+
+// newChainTestServer hosts polygon (137) as the default chain and ethereum
+// (1), each answering eth_blockNumber with its own head
+func newChainTestServer() http.Handler {
+	ethereum := newTestServer()
+	ethereum.server.chainName, ethereum.server.chainID = "ethereum", 1
+	ethereum.mock.getBlockNumberFunc = func() (string, error) { return "0x1", nil }
+
+	polygon := newTestServer()
+	polygon.server.chainName, polygon.server.chainID = "polygon", 137
+	polygon.server.chains = []*Server{ethereum.server}
+	polygon.mock.getBlockNumberFunc = func() (string, error) { return "0x89", nil }
+
+	return polygon.server.SetupRoutes()
+}
+
+func TestChainRouting(t *testing.T) {
+	handler := newChainTestServer()
+
+	tests := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+		wantBlock  string
+	}{
+		{"default", "/api/blocks/latest", "", http.StatusOK, "0x89"},
+		{"name prefix", "/ethereum/api/blocks/latest", "", http.StatusOK, "0x1"},
+		{"chain ID prefix", "/1/api/blocks/latest", "", http.StatusOK, "0x1"},
+		{"default chain prefix", "/137/api/blocks/latest", "", http.StatusOK, "0x89"},
+		{"header name", "/api/blocks/latest", "ethereum", http.StatusOK, "0x1"},
+		{"header chain ID", "/api/blocks/latest", "1", http.StatusOK, "0x1"},
+		{"prefix wins over header", "/polygon/api/blocks/latest", "1", http.StatusOK, "0x89"},
+		{"unknown header", "/api/blocks/latest", "base", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(ChainHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBlock == "" {
+				return
+			}
+			var resp BlockNumberResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.BlockNumber != tt.wantBlock {
+				t.Errorf("expected block %s, got %s", tt.wantBlock, resp.BlockNumber)
+			}
+		})
+	}
+}
+
+func TestChainRoutingJSONRPC(t *testing.T) {
+	handler := newChainTestServer()
+
+	body := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/ethereum", strings.NewReader(body)))
+
+	var resp RPCResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if string(resp.Result) != `"0x1"` {
+		t.Errorf("expected the ethereum head, got %+v", resp)
+	}
+}
+
+func TestChainRoutingLogsRoute(t *testing.T) {
+	handler := newChainTestServer()
+
+	var logs bytes.Buffer
+	logger, _ := logging.New(&logs, logging.Config{Format: logging.FormatJSON})
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/1/api/blocks/latest", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse access log: %v", err)
+	}
+	if entry["path"] != "/1/api/blocks/latest" || entry["route"] != "/api/blocks/latest" || entry["chain"] != "ethereum" {
+		t.Errorf("unexpected access log entry %v", entry)
+	}
+}
+
+func TestChainRoutingSharedRateLimit(t *testing.T) {
+	ethereum := newTestServer()
+	ethereum.server.chainName, ethereum.server.chainID = "ethereum", 1
+	ethereum.mock.getBlockNumberFunc = func() (string, error) { return "0x1", nil }
+
+	polygon := newTestServer()
+	polygon.server.chainName, polygon.server.chainID = "polygon", 137
+	polygon.server.chains = []*Server{ethereum.server}
+	polygon.mock.getBlockNumberFunc = func() (string, error) { return "0x89", nil }
+
+	limiter := ratelimit.NewLimiter(ratelimit.Config{Rate: 1, Burst: 20})
+	WithLimiter(limiter, false)(ethereum.server)
+	WithLimiter(limiter, false)(polygon.server)
+	handler := polygon.server.SetupRoutes()
+
+	get := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/api/blocks/latest"); code != http.StatusOK {
+		t.Fatalf("expected the first call to succeed, got %d", code)
+	}
+	for code := http.StatusOK; code == http.StatusOK; {
+		code = get("/polygon/api/blocks/latest")
+	}
+	if code := get("/ethereum/api/blocks/latest"); code != http.StatusTooManyRequests {
+		t.Errorf("expected the budget spent on polygon to apply to ethereum, got %d", code)
+	}
+}
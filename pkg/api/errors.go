@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// rpcErrorFor converts an error from the blockchain client into the JSON-RPC
+// error returned to the caller. Upstream JSON-RPC errors are passed through
+// with their code and data, so callers see e.g. the revert data of a failed
+// call; any other failure is an internal error. Errors that already are
+// JSON-RPC errors of the server, such as those of method handlers rejecting
+// their params, are returned as is.
+func rpcErrorFor(err error) *RPCError {
+	var callErr *RPCError
+	if errors.As(err, &callErr) {
+		return callErr
+	}
+	var rpcErr *blockchain.RPCError
+	if errors.As(err, &rpcErr) {
+		return &RPCError{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}
+	}
+	return &RPCError{Code: -32603, Message: err.Error()}
+}
+
+// upstreamStatus maps an error from the blockchain client to the HTTP status
+// of a REST response. Requests the upstream rejects as invalid are the
+// caller's fault; other upstream failures are reported as a bad gateway, or a
+// gateway timeout when the upstream did not answer in time.
+func upstreamStatus(err error) int {
+	var (
+		rpcErr       *blockchain.RPCError
+		statusErr    *blockchain.HTTPStatusError
+		transportErr *blockchain.TransportError
+		decodeErr    *blockchain.DecodeError
+		idErr        *blockchain.IDMismatchError
+		disagreement *blockchain.DisagreementError
+		hashErr      *blockchain.HashMismatchError
+		rootErr      *blockchain.RootMismatchError
+		netErr       net.Error
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout
+	case errors.As(err, &rpcErr):
+		switch rpcErr.Code {
+		case -32600, -32602:
+			return http.StatusBadRequest
+		case rpcCodeLimitExceeded:
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusBadGateway
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusTooManyRequests {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusBadGateway
+	case errors.As(err, &transportErr), errors.As(err, &decodeErr), errors.As(err, &idErr), errors.As(err, &disagreement),
+		errors.As(err, &hashErr), errors.As(err, &rootErr):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// upstreamFailed responds to a REST request whose upstream read failed,
+// including the upstream's JSON-RPC error code and data when it returned one
+func upstreamFailed(w http.ResponseWriter, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	var rpcErr *blockchain.RPCError
+	if errors.As(err, &rpcErr) {
+		resp.Code = rpcErr.Code
+		resp.Data = rpcErr.Data
+	}
+	writeJSONResponse(w, upstreamStatus(err), resp)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+func TestUpstreamErrorPassthrough(t *testing.T) {
+	ts := newTestServer()
+	upstreamErr := &blockchain.RPCError{Code: 3, Message: "execution reverted", Data: json.RawMessage(`"0x08c379a0"`)}
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "", fmt.Errorf("block 0x1: %w", upstreamErr)
+	}
+	handler := ts.server.SetupRoutes()
+
+	body := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	var resp RPCResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error == nil || resp.Error.Code != 3 || resp.Error.Message != "execution reverted" || string(resp.Error.Data) != `"0x08c379a0"` {
+		t.Errorf("expected the upstream error to be passed through, got %+v", resp.Error)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/blocks/latest", nil))
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if w.Code != http.StatusBadGateway || errResp.Code != 3 || string(errResp.Data) != `"0x08c379a0"` {
+		t.Errorf("expected 502 with the upstream code and data, got %d: %+v", w.Code, errResp)
+	}
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&blockchain.RPCError{Code: -32602, Message: "invalid argument"}, http.StatusBadRequest},
+		{&blockchain.RPCError{Code: -32005, Message: "limit exceeded"}, http.StatusServiceUnavailable},
+		{&blockchain.RPCError{Code: -32000, Message: "header not found"}, http.StatusBadGateway},
+		{&blockchain.HTTPStatusError{StatusCode: http.StatusTooManyRequests}, http.StatusServiceUnavailable},
+		{&blockchain.HTTPStatusError{StatusCode: http.StatusInternalServerError}, http.StatusBadGateway},
+		{&blockchain.TransportError{Err: errors.New("connection refused")}, http.StatusBadGateway},
+		{&blockchain.TransportError{Err: context.DeadlineExceeded}, http.StatusGatewayTimeout},
+		{&blockchain.DecodeError{Err: errors.New("invalid character")}, http.StatusBadGateway},
+		{&blockchain.DisagreementError{Method: "eth_blockNumber"}, http.StatusBadGateway},
+		{&blockchain.HashMismatchError{Number: "0x1"}, http.StatusBadGateway},
+		{errors.New("store closed"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := upstreamStatus(tt.err); got != tt.want {
+			t.Errorf("upstreamStatus(%T %v) = %d, want %d", tt.err, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"blockchain-client/pkg/export"
+)
+
+// Export job statuses
+const (
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportCancelled = "cancelled"
+)
+
+const (
+	// defaultMaxExportJobs is the number of export jobs that may run at once
+	defaultMaxExportJobs = 4
+	// defaultMaxExportBlocks is the largest range a single job may export
+	defaultMaxExportBlocks = 1000000
+	// defaultExportRetention is how long finished jobs stay listed
+	defaultExportRetention = 24 * time.Hour
+)
+
+// ExportRequest represents the body of a request to start an export job
+type ExportRequest struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Format       string `json:"format"`
+	RotateBlocks uint64 `json:"rotateBlocks,omitempty"`
+	SkipReceipts bool   `json:"skipReceipts,omitempty"`
+}
+
+// ExportJob represents the state of an export job
+type ExportJob struct {
+	ID        string        `json:"id"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Format    export.Format `json:"format"`
+	OutDir    string        `json:"outDir"`
+	StartedAt time.Time     `json:"startedAt"`
+	// FinishedAt is set once the job is no longer running
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	Progress   export.Progress `json:"progress"`
+}
+
+// ExportLimits bounds the export jobs run through the API. Zero values use
+// the defaults.
+type ExportLimits struct {
+	// MaxJobs is the number of jobs that may run at once
+	MaxJobs int
+	// MaxBlocks is the largest block range a job may export
+	MaxBlocks uint64
+	// Retention is how long finished jobs are kept in the job list
+	Retention time.Duration
+}
+
+// exportJob tracks a running export
+type exportJob struct {
+	exporter *export.Exporter
+	cancel   context.CancelFunc
+
+	mu  sync.Mutex
+	job ExportJob
+}
+
+// snapshot returns the current job state
+func (j *exportJob) snapshot() ExportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job := j.job
+	job.Progress = j.exporter.Progress()
+	return job
+}
+
+// exportJobs is the registry of export jobs started through the API
+type exportJobs struct {
+	limits ExportLimits
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*exportJob
+	active  int
+	running sync.WaitGroup
+}
+
+// maxJobs returns the number of jobs that may run at once
+func (e *exportJobs) maxJobs() int {
+	if e.limits.MaxJobs > 0 {
+		return e.limits.MaxJobs
+	}
+	return defaultMaxExportJobs
+}
+
+// maxBlocks returns the largest block range a job may export
+func (e *exportJobs) maxBlocks() uint64 {
+	if e.limits.MaxBlocks > 0 {
+		return e.limits.MaxBlocks
+	}
+	return defaultMaxExportBlocks
+}
+
+// clock returns the current time
+func (e *exportJobs) clock() time.Time {
+	if e.now != nil {
+		return e.now()
+	}
+	return time.Now()
+}
+
+// prune removes jobs that finished longer than the retention period ago. The
+// caller must hold e.mu.
+func (e *exportJobs) prune() {
+	retention := e.limits.Retention
+	if retention <= 0 {
+		retention = defaultExportRetention
+	}
+	cutoff := e.clock().Add(-retention)
+	for id, job := range e.jobs {
+		job.mu.Lock()
+		finished := job.job.FinishedAt
+		job.mu.Unlock()
+		if finished != nil && finished.Before(cutoff) {
+			delete(e.jobs, id)
+		}
+	}
+}
+
+// shutdown cancels every running job and waits until they have stopped or
+// ctx is done
+func (e *exportJobs) shutdown(ctx context.Context) {
+	e.mu.Lock()
+	for _, job := range e.jobs {
+		job.cancel()
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// WithExportDir enables the export job endpoints, writing each job's files to
+// a subdirectory of dir
+func WithExportDir(dir string) Option {
+	return func(s *Server) {
+		s.exportDir = dir
+	}
+}
+
+// WithExportLimits bounds the number of running export jobs and the range
+// each may export, and sets how long finished jobs are kept
+func WithExportLimits(limits ExportLimits) Option {
+	return func(s *Server) {
+		s.exports.limits = limits
+	}
+}
+
+// newJobID generates a random export job identifier
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// exportsEnabled responds with 503 and reports false when the export job
+// endpoints are not enabled
+func (s *Server) exportsEnabled(w http.ResponseWriter) bool {
+	if s.exportDir == "" {
+		writeJSONResponse(w, http.StatusServiceUnavailable, ErrorResponse{Error: "exports are not enabled"})
+		return false
+	}
+	return true
+}
+
+// HandleStartExport handles POST /api/v1/exports
+func (s *Server) HandleStartExport(w http.ResponseWriter, r *http.Request) {
+	if s.exportsEnabled(w) {
+		s.startExport(w, r)
+	}
+}
+
+// HandleListExports handles GET /api/v1/exports
+func (s *Server) HandleListExports(w http.ResponseWriter, r *http.Request) {
+	if s.exportsEnabled(w) {
+		s.listExports(w)
+	}
+}
+
+// HandleGetExport handles GET /api/v1/exports/{id}
+func (s *Server) HandleGetExport(w http.ResponseWriter, r *http.Request) {
+	if s.exportsEnabled(w) {
+		s.getExport(w, r.PathValue("id"), false)
+	}
+}
+
+// HandleCancelExport handles DELETE /api/v1/exports/{id}
+func (s *Server) HandleCancelExport(w http.ResponseWriter, r *http.Request) {
+	if s.exportsEnabled(w) {
+		s.getExport(w, r.PathValue("id"), true)
+	}
+}
+
+// startExport starts a new export job
+func (s *Server) startExport(w http.ResponseWriter, r *http.Request) {
+	source, ok := s.client.(export.Source)
+	if !ok {
+		writeJSONResponse(w, http.StatusServiceUnavailable, ErrorResponse{Error: "client does not support exports"})
+		return
+	}
+
+	var req ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if isBodyTooLarge(err) {
+			writeJSONResponse(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+			return
+		}
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid export request"})
+		return
+	}
+
+	from, err := parseBlockParam(req.From)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid from block"})
+		return
+	}
+	to, err := parseBlockParam(req.To)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid to block"})
+		return
+	}
+	if to >= from && to-from >= s.exports.maxBlocks() {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("export exceeds %d blocks", s.exports.maxBlocks())})
+		return
+	}
+	format, err := export.ParseFormat(req.Format)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	id := newJobID()
+	outDir := filepath.Join(s.exportDir, id)
+	exporter, err := export.New(source, export.Config{
+		From:         from,
+		To:           to,
+		Format:       format,
+		OutDir:       outDir,
+		RotateBlocks: req.RotateBlocks,
+		SkipReceipts: req.SkipReceipts,
+	})
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &exportJob{
+		exporter: exporter,
+		cancel:   cancel,
+		job: ExportJob{
+			ID:        id,
+			Status:    ExportRunning,
+			Format:    format,
+			OutDir:    outDir,
+			StartedAt: s.exports.clock().UTC(),
+		},
+	}
+
+	s.exports.mu.Lock()
+	if s.exports.active >= s.exports.maxJobs() {
+		s.exports.mu.Unlock()
+		cancel()
+		writeJSONResponse(w, http.StatusTooManyRequests, ErrorResponse{Error: fmt.Sprintf("%d export jobs are already running", s.exports.maxJobs())})
+		return
+	}
+	if s.exports.jobs == nil {
+		s.exports.jobs = make(map[string]*exportJob)
+	}
+	s.exports.prune()
+	s.exports.jobs[id] = job
+	s.exports.active++
+	s.exports.running.Add(1)
+	s.exports.mu.Unlock()
+
+	go func() {
+		defer s.exports.running.Done()
+		err := exporter.Run(ctx)
+
+		s.exports.mu.Lock()
+		s.exports.active--
+		s.exports.mu.Unlock()
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		finished := s.exports.clock().UTC()
+		job.job.FinishedAt = &finished
+		switch {
+		case err == nil:
+			job.job.Status = ExportCompleted
+		case errors.Is(err, context.Canceled):
+			job.job.Status = ExportCancelled
+		default:
+			job.job.Status = ExportFailed
+			job.job.Error = err.Error()
+			slog.Error("export job failed", "job", id, "error", err)
+		}
+	}()
+
+	writeJSONResponse(w, http.StatusAccepted, job.snapshot())
+}
+
+// listExports lists all export jobs, newest first
+func (s *Server) listExports(w http.ResponseWriter) {
+	s.exports.mu.Lock()
+	s.exports.prune()
+	jobs := make([]ExportJob, 0, len(s.exports.jobs))
+	for _, job := range s.exports.jobs {
+		jobs = append(jobs, job.snapshot())
+	}
+	s.exports.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	writeJSONResponse(w, http.StatusOK, jobs)
+}
+
+// getExport returns an export job's status, cancelling it first if cancel
+// is set
+func (s *Server) getExport(w http.ResponseWriter, id string, cancel bool) {
+	s.exports.mu.Lock()
+	s.exports.prune()
+	job, ok := s.exports.jobs[id]
+	s.exports.mu.Unlock()
+
+	if !ok {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "export job not found"})
+		return
+	}
+
+	if cancel {
+		job.cancel()
+	}
+
+	writeJSONResponse(w, http.StatusOK, job.snapshot())
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// waitForExport polls an export job until it leaves the running state
+func waitForExport(t *testing.T, handler http.Handler, id string) ExportJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/exports/"+id, nil))
+
+		var job ExportJob
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if job.Status != ExportRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for export job %s", id)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandleExports(t *testing.T) {
+	ts := newTestServer()
+	ts.server.exportDir = t.TempDir()
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		return &blockchain.Block{
+			Number:       blockNumber,
+			Hash:         "0xhash" + blockNumber,
+			Transactions: json.RawMessage(`[{"hash": "0xtx", "from": "0xa", "to": "0xb"}]`),
+		}, nil
+	}
+	ts.mock.getBlockReceiptsFunc = func(blockNumber string) ([]*blockchain.Receipt, error) {
+		return []*blockchain.Receipt{{TransactionHash: "0xtx", Status: "0x1"}}, nil
+	}
+	handler := ts.server.SetupRoutes()
+
+	t.Run("runs an export job", func(t *testing.T) {
+		body := `{"from": "0x10", "to": "25", "format": "ndjson", "rotateBlocks": 5}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/exports", bytes.NewBufferString(body)))
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202; got %d: %s", rec.Code, rec.Body)
+		}
+		var job ExportJob
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+
+		job = waitForExport(t, handler, job.ID)
+		if job.Status != ExportCompleted {
+			t.Fatalf("expected completed job; got %s (%s)", job.Status, job.Error)
+		}
+		if len(job.Progress.Files) != 8 {
+			t.Errorf("expected 8 files; got %d", len(job.Progress.Files))
+		}
+		if _, err := os.Stat(filepath.Join(job.OutDir, "blocks", "blocks_000000000016_000000000020.ndjson")); err != nil {
+			t.Errorf("expected blocks file: %v", err)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/exports", nil))
+		var jobs []ExportJob
+		json.NewDecoder(rec.Body).Decode(&jobs)
+		if len(jobs) != 1 || jobs[0].ID != job.ID {
+			t.Errorf("expected job in listing; got %+v", jobs)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		for _, body := range []string{
+			`not json`,
+			`{"from": "abc", "to": "10", "format": "csv"}`,
+			`{"from": "1", "to": "10", "format": "xlsx"}`,
+			`{"from": "10", "to": "1", "format": "csv"}`,
+		} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/exports", bytes.NewBufferString(body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400; got %d", body, rec.Code)
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/exports/unknown", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 for unknown job; got %d", rec.Code)
+		}
+	})
+
+	t.Run("disabled without an export directory", func(t *testing.T) {
+		ts := newTestServer()
+		rec := httptest.NewRecorder()
+		ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/exports", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503; got %d", rec.Code)
+		}
+	})
+}
+
+func TestExportLimits(t *testing.T) {
+	release := make(chan struct{})
+	ts := newTestServer()
+	ts.server.exportDir = t.TempDir()
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		<-release
+		return &blockchain.Block{Number: blockNumber, Hash: "0xhash" + blockNumber, Transactions: json.RawMessage(`[]`)}, nil
+	}
+	WithExportLimits(ExportLimits{MaxJobs: 1, MaxBlocks: 10, Retention: time.Hour})(ts.server)
+	now := time.Now()
+	ts.server.exports.now = func() time.Time { return now }
+	handler := ts.server.SetupRoutes()
+
+	start := func(from, to string) *httptest.ResponseRecorder {
+		body := `{"from": "` + from + `", "to": "` + to + `", "format": "ndjson", "skipReceipts": true}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/exports", bytes.NewBufferString(body)))
+		return rec
+	}
+
+	if rec := start("0", "10"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a range over the limit; got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := start("0", "9")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202; got %d: %s", rec.Code, rec.Body)
+	}
+	var job ExportJob
+	json.NewDecoder(rec.Body).Decode(&job)
+
+	if rec := start("0", "1"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 while a job is running; got %d: %s", rec.Code, rec.Body)
+	}
+
+	close(release)
+	if job = waitForExport(t, handler, job.ID); job.Status != ExportCompleted || job.FinishedAt == nil {
+		t.Fatalf("expected a completed job with its finish time; got %+v", job)
+	}
+	rec = start("0", "1")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected a job to start once the running one finished; got %d: %s", rec.Code, rec.Body)
+	}
+	var next ExportJob
+	json.NewDecoder(rec.Body).Decode(&next)
+	waitForExport(t, handler, next.ID)
+
+	// Finished jobs are evicted once the retention period has passed
+	now = now.Add(2 * time.Hour)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/exports/"+job.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an evicted job; got %d", rec.Code)
+	}
+}
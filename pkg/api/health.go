@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/version"
+)
+
+const (
+	// defaultMaxHeadAge is how old the latest block may be before the
+	// upstream is considered stale
+	defaultMaxHeadAge = time.Minute
+	// defaultCheckTimeout bounds the upstream calls made by /readyz
+	defaultCheckTimeout = 5 * time.Second
+)
+
+// Readiness check names
+const (
+	CheckUpstream = "upstream"
+	CheckHead     = "head"
+	CheckChainID  = "chainId"
+	CheckShutdown = "shutdown"
+)
+
+// HealthConfig represents the readiness thresholds
+type HealthConfig struct {
+	// ExpectedChainID is the chain the upstream must serve; 0 skips the check
+	ExpectedChainID uint64
+	// MaxHeadAge is how old the latest block may be
+	MaxHeadAge time.Duration
+	// CheckTimeout bounds the upstream calls made by each readiness probe
+	CheckTimeout time.Duration
+	// CacheTTL is how long a readiness result answers later probes before
+	// the upstream is checked again; 0 uses the head follow interval
+	CacheTTL time.Duration
+}
+
+// readinessCache holds the last readiness result so that frequent probes do
+// not each call the upstream
+type readinessCache struct {
+	mu      sync.Mutex
+	resp    ReadinessResponse
+	checked time.Time
+}
+
+// WithHealth sets the readiness thresholds used by /readyz
+func WithHealth(cfg HealthConfig) Option {
+	return func(s *Server) {
+		s.health = cfg
+	}
+}
+
+// WithUpstreamStats reports per-upstream call statistics on /status
+func WithUpstreamStats(stats *blockchain.StatsRecorder) Option {
+	return func(s *Server) {
+		s.upstreamStats = stats
+	}
+}
+
+// WithHeadFollower reports the followed chain head on /status
+func WithHeadFollower(follower *blockchain.HeadFollower) Option {
+	return func(s *Server) {
+		s.follower = follower
+	}
+}
+
+// HealthResponse represents the response for the liveness endpoint
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// CheckResult represents the outcome of one readiness check
+type CheckResult struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReadinessResponse represents the response for the readiness endpoint
+type ReadinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// HeadStatus represents the chain head seen by the head follower
+type HeadStatus struct {
+	Block     string    `json:"block"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// StatusResponse represents the response for the status endpoint
+type StatusResponse struct {
+	Version       version.Info               `json:"version"`
+	UptimeSeconds float64                    `json:"uptimeSeconds"`
+	Head          *HeadStatus                `json:"head,omitempty"`
+	Upstreams     []blockchain.UpstreamStats `json:"upstreams"`
+}
+
+// HandleHealthz handles the /healthz liveness endpoint. It succeeds whenever
+// the process can serve requests and never calls the upstream.
+func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// HandleReadyz handles the /readyz readiness endpoint
+func (s *Server) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := s.checkReadiness(r.Context())
+
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSONResponse(w, status, resp)
+}
+
+// checkReadiness verifies that the upstream is reachable, its head is
+// recent, and it serves the expected chain. Results are reused for CacheTTL,
+// and concurrent probes wait for a single check instead of starting their own.
+func (s *Server) checkReadiness(ctx context.Context) ReadinessResponse {
+	if s.Draining() {
+		return ReadinessResponse{
+			Status: "not ready",
+			Checks: map[string]CheckResult{CheckShutdown: {Message: "server is shutting down"}},
+		}
+	}
+
+	ttl := s.health.CacheTTL
+	if ttl <= 0 {
+		ttl = blockchain.DefaultFollowInterval
+	}
+
+	s.readiness.mu.Lock()
+	defer s.readiness.mu.Unlock()
+	if !s.readiness.checked.IsZero() && time.Since(s.readiness.checked) < ttl {
+		return s.readiness.resp
+	}
+
+	// The result is shared with later probes, so it must not depend on
+	// whether this probe's client stays connected
+	s.readiness.resp = s.runReadinessChecks(context.WithoutCancel(ctx))
+	s.readiness.checked = time.Now()
+	return s.readiness.resp
+}
+
+// runReadinessChecks calls the upstream for each readiness check
+func (s *Server) runReadinessChecks(ctx context.Context) ReadinessResponse {
+	timeout := s.health.CheckTimeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	maxHeadAge := s.health.MaxHeadAge
+	if maxHeadAge <= 0 {
+		maxHeadAge = defaultMaxHeadAge
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checks := make(map[string]CheckResult)
+
+	block, err := s.client.GetBlockByNumber(ctx, "latest", false)
+	if err != nil {
+		checks[CheckUpstream] = CheckResult{Message: err.Error()}
+		checks[CheckHead] = CheckResult{Message: "upstream unreachable"}
+	} else {
+		checks[CheckUpstream] = CheckResult{OK: true}
+		checks[CheckHead] = headCheck(block, maxHeadAge)
+	}
+
+	if s.health.ExpectedChainID != 0 {
+		checks[CheckChainID] = s.chainIDCheck(ctx)
+	}
+
+	resp := ReadinessResponse{Status: "ready", Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			resp.Status = "not ready"
+		}
+	}
+	return resp
+}
+
+// headCheck verifies that block was produced within maxAge
+func headCheck(block *blockchain.Block, maxAge time.Duration) CheckResult {
+	ts, err := blockchain.ParseQuantity(block.Timestamp)
+	if err != nil {
+		return CheckResult{Message: "invalid head block timestamp"}
+	}
+
+	age := time.Since(time.Unix(int64(ts), 0)).Truncate(time.Second)
+	if age > maxAge {
+		return CheckResult{Message: fmt.Sprintf("head block %s is %s old", block.Number, age)}
+	}
+	return CheckResult{OK: true, Message: fmt.Sprintf("head block %s is %s old", block.Number, age)}
+}
+
+// chainIDCheck verifies that the upstream serves the expected chain
+func (s *Server) chainIDCheck(ctx context.Context) CheckResult {
+	chainID, err := s.client.GetChainID(ctx)
+	if err != nil {
+		return CheckResult{Message: err.Error()}
+	}
+	id, err := blockchain.ParseQuantity(chainID)
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("invalid chain ID %q", chainID)}
+	}
+	if id != s.health.ExpectedChainID {
+		return CheckResult{Message: fmt.Sprintf("upstream serves chain %d, expected %d", id, s.health.ExpectedChainID)}
+	}
+	return CheckResult{OK: true}
+}
+
+// HandleStatus handles the /status endpoint
+func (s *Server) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := StatusResponse{
+		Version:       version.Get(),
+		UptimeSeconds: time.Since(s.started).Seconds(),
+		Upstreams:     []blockchain.UpstreamStats{},
+	}
+
+	if s.follower != nil {
+		if head, updated := s.follower.Head(); head > 0 {
+			resp.Head = &HeadStatus{Block: blockchain.EncodeQuantity(head), UpdatedAt: updated}
+		}
+	}
+	if s.upstreamStats != nil {
+		resp.Upstreams = s.upstreamStats.Snapshot()
+	}
+
+	writeJSONResponse(w, http.StatusOK, resp)
+}
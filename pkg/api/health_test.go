@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+func TestHandleHealthz(t *testing.T) {
+	ts := newTestServer()
+	rec := httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200; got %d", rec.Code)
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	headAt := func(age time.Duration) func(string, bool) (*blockchain.Block, error) {
+		return func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+			ts := time.Now().Add(-age).Unix()
+			return &blockchain.Block{Number: "0x10", Timestamp: fmt.Sprintf("0x%x", ts)}, nil
+		}
+	}
+
+	tests := []struct {
+		name       string
+		block      func(string, bool) (*blockchain.Block, error)
+		chainID    string
+		wantStatus int
+		failed     []string
+	}{
+		{"ready", headAt(5 * time.Second), "0x89", http.StatusOK, nil},
+		{"stale head", headAt(5 * time.Minute), "0x89", http.StatusServiceUnavailable, []string{CheckHead}},
+		{"wrong chain", headAt(5 * time.Second), "0x1", http.StatusServiceUnavailable, []string{CheckChainID}},
+		{"upstream down", func(string, bool) (*blockchain.Block, error) {
+			return nil, errors.New("connection refused")
+		}, "0x89", http.StatusServiceUnavailable, []string{CheckUpstream, CheckHead}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer()
+			ts.server.health = HealthConfig{ExpectedChainID: 137, MaxHeadAge: time.Minute}
+			ts.mock.getBlockByNumberFunc = tt.block
+			ts.mock.getChainIDFunc = func() (string, error) { return tt.chainID, nil }
+
+			rec := httptest.NewRecorder()
+			ts.server.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d; got %d", tt.wantStatus, rec.Code)
+			}
+
+			var resp ReadinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			for name, check := range resp.Checks {
+				if check.OK == slices.Contains(tt.failed, name) {
+					t.Errorf("unexpected %s check result %+v", name, check)
+				}
+			}
+			if len(resp.Checks) != 3 {
+				t.Errorf("expected 3 checks; got %v", resp.Checks)
+			}
+		})
+	}
+
+	t.Run("chain ID check is optional", func(t *testing.T) {
+		ts := newTestServer()
+		ts.mock.getBlockByNumberFunc = headAt(time.Second)
+
+		rec := httptest.NewRecorder()
+		ts.server.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), CheckChainID) {
+			t.Errorf("expected ready without chain ID check; got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestHandleReadyzHungUpstream(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	s := &Server{client: blockchain.NewClient(upstream.URL)}
+	WithHealth(HealthConfig{CheckTimeout: 100 * time.Millisecond})(s)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	s.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503; got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the probe to give up after its check timeout, took %s", elapsed)
+	}
+}
+
+func TestHandleReadyzCache(t *testing.T) {
+	ts := newTestServer()
+	ts.server.health = HealthConfig{ExpectedChainID: 137, CacheTTL: 100 * time.Millisecond}
+	var blockCalls, chainIDCalls atomic.Int32
+	ts.mock.getBlockByNumberFunc = func(string, bool) (*blockchain.Block, error) {
+		blockCalls.Add(1)
+		return &blockchain.Block{Number: "0x10", Timestamp: fmt.Sprintf("0x%x", time.Now().Unix())}, nil
+	}
+	ts.mock.getChainIDFunc = func() (string, error) {
+		chainIDCalls.Add(1)
+		return "0x89", nil
+	}
+
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		ts.server.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+		return rec.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		if code := probe(); code != http.StatusOK {
+			t.Fatalf("expected status 200; got %d", code)
+		}
+	}
+	if blockCalls.Load() != 1 || chainIDCalls.Load() != 1 {
+		t.Errorf("expected repeated probes to reuse one check, got %d block and %d chain ID calls", blockCalls.Load(), chainIDCalls.Load())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	probe()
+	if blockCalls.Load() != 2 {
+		t.Errorf("expected the upstream to be checked again once the result expired, got %d calls", blockCalls.Load())
+	}
+
+	ts.server.draining.Store(true)
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected a draining server to be not ready despite the cached result; got %d", code)
+	}
+}
+
+// headSource reports a fixed block number
+type headSource string
+
+func (h headSource) GetBlockNumber(ctx context.Context) (string, error) {
+	return string(h), nil
+}
+
+func TestHandleStatus(t *testing.T) {
+	stats := blockchain.NewStatsRecorder()
+	stats.ObserveCall(blockchain.CallInfo{Endpoint: "https://rpc.example.com/v2/key", Method: "eth_blockNumber", Duration: 10 * time.Millisecond})
+	stats.ObserveCall(blockchain.CallInfo{Endpoint: "https://rpc.example.com/v2/key", Method: "eth_blockNumber", ErrorCode: "http_503", Err: errors.New("unexpected status code: 503")})
+
+	follower := blockchain.NewHeadFollower(headSource("0x20"), time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go follower.Run(ctx)
+	heads, unsubscribe := follower.Subscribe()
+	<-heads
+	unsubscribe()
+
+	ts := newTestServer()
+	ts.server.upstreamStats = stats
+	ts.server.follower = follower
+
+	rec := httptest.NewRecorder()
+	ts.server.HandleStatus(rec, httptest.NewRequest("GET", "/status", nil))
+
+	var resp StatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if resp.Version.Version == "" || resp.Version.GoVersion == "" {
+		t.Errorf("expected version info; got %+v", resp.Version)
+	}
+	if resp.Head == nil || resp.Head.Block != "0x20" {
+		t.Errorf("expected head 0x20; got %+v", resp.Head)
+	}
+	if len(resp.Upstreams) != 1 {
+		t.Fatalf("expected 1 upstream; got %d", len(resp.Upstreams))
+	}
+	upstream := resp.Upstreams[0]
+	if upstream.Endpoint != "https://rpc.example.com/REDACTED" || upstream.Requests != 2 || upstream.ErrorRate != 0.5 {
+		t.Errorf("unexpected upstream stats %+v", upstream)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// parseRPCRequest decodes and validates one JSON-RPC 2.0 request. Invalid
+// requests are reported as a -32600 error; the returned request still carries
+// the ID when it is valid, so the error can be correlated.
+func parseRPCRequest(raw json.RawMessage) (RPCRequest, *RPCError) {
+	var request RPCRequest
+	var fields struct {
+		JSONRPC json.RawMessage `json:"jsonrpc"`
+		Method  json.RawMessage `json:"method"`
+		Params  json.RawMessage `json:"params"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if len(raw) == 0 || raw[0] != '{' || json.Unmarshal(raw, &fields) != nil {
+		return request, invalidRequest("request must be an object")
+	}
+
+	if fields.ID != nil && !validID(fields.ID) {
+		return request, invalidRequest("id must be a string, number or null")
+	}
+	request.ID = fields.ID
+
+	if json.Unmarshal(fields.Method, &request.Method) != nil || request.Method == "" {
+		request.Method = ""
+		return request, invalidRequest("method must be a non-empty string")
+	}
+	if json.Unmarshal(fields.JSONRPC, &request.JSONRPC) != nil || request.JSONRPC != "2.0" {
+		return request, invalidRequest("invalid JSON-RPC version, expected 2.0")
+	}
+	if len(fields.Params) > 0 && !bytes.Equal(fields.Params, []byte("null")) {
+		if fields.Params[0] != '[' && fields.Params[0] != '{' {
+			return request, invalidRequest("params must be an array or object")
+		}
+		request.Params = fields.Params
+	}
+	return request, nil
+}
+
+// validID reports whether id is a string, number or null, the ID types
+// JSON-RPC 2.0 allows
+func validID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return false
+	}
+	switch c := id[0]; {
+	case c == '"', c == '-', c >= '0' && c <= '9':
+		return true
+	}
+	return bytes.Equal(id, []byte("null"))
+}
+
+func invalidRequest(message string) *RPCError {
+	return &RPCError{Code: -32600, Message: message}
+}
+
+// isNotification reports whether the request expects no response
+func (r RPCRequest) isNotification() bool {
+	return r.ID == nil
+}
+
+// rpcErrorResponse returns a response carrying a JSON-RPC error
+func rpcErrorResponse(id json.RawMessage, code int, message string) RPCResponse {
+	return RPCResponse{JSONRPC: "2.0", Error: &RPCError{Code: code, Message: message}, ID: id}
+}
+
+// writeRPCResponse writes the response to request, or only the status for
+// notifications, which must not be answered
+func writeRPCResponse(w http.ResponseWriter, status int, request RPCRequest, response RPCResponse) {
+	if !request.isNotification() {
+		writeJSONResponse(w, status, response)
+		return
+	}
+	if status == http.StatusOK {
+		status = http.StatusNoContent
+	}
+	w.WriteHeader(status)
+}
+
+// writeBatchResponse writes the responses to a batch, or no body when every
+// request in it was a notification
+func writeBatchResponse(w http.ResponseWriter, status int, responses []RPCResponse) {
+	if len(responses) > 0 {
+		writeJSONResponse(w, status, responses)
+		return
+	}
+	if status == http.StatusOK {
+		status = http.StatusNoContent
+	}
+	w.WriteHeader(status)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// decodeJSON decodes s keeping numbers verbatim, so that ids can be compared
+// exactly
+func decodeJSON(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", s, err)
+	}
+	return v
+}
+
+// TestJSONRPCConformance runs the examples of the JSON-RPC 2.0 specification,
+// with eth_blockNumber and eth_getBlockByNumber standing in for its methods
+func TestJSONRPCConformance(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    string
+		wantStatus int
+		// want is the expected response body; empty for no body
+		want string
+	}{
+		{
+			name:       "positional params",
+			request:    `{"jsonrpc": "2.0", "method": "eth_getBlockByNumber", "params": ["0x1", false], "id": 1}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "result": null, "id": 1}`,
+		},
+		{
+			name:       "named params",
+			request:    `{"jsonrpc": "2.0", "method": "eth_getBlockByNumber", "params": {"fullTransactions": false, "blockNumber": "0x1"}, "id": 3}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "result": null, "id": 3}`,
+		},
+		{
+			name:       "unknown named param",
+			request:    `{"jsonrpc": "2.0", "method": "eth_getBlockByNumber", "params": {"block": "0x1", "fullTransactions": false}, "id": 4}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "invalid params: unknown param \"block\""}, "id": 4}`,
+		},
+		{
+			name:       "string id",
+			request:    `{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": "abc-1"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "result": "0x10", "id": "abc-1"}`,
+		},
+		{
+			name:       "large and fractional ids echoed verbatim",
+			request:    `[{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": 12345678901234567890}, {"jsonrpc": "2.0", "method": "eth_blockNumber", "id": 1.5}]`,
+			wantStatus: http.StatusOK,
+			want:       `[{"jsonrpc": "2.0", "result": "0x10", "id": 12345678901234567890}, {"jsonrpc": "2.0", "result": "0x10", "id": 1.5}]`,
+		},
+		{
+			name:       "null id",
+			request:    `{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": null}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "result": "0x10", "id": null}`,
+		},
+		{
+			name:       "notification",
+			request:    `{"jsonrpc": "2.0", "method": "eth_blockNumber"}`,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "notification of a missing method",
+			request:    `{"jsonrpc": "2.0", "method": "foobar"}`,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "non-existent method",
+			request:    `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "method not found"}, "id": "1"}`,
+		},
+		{
+			name:       "invalid JSON",
+			request:    `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "parse error"}, "id": null}`,
+		},
+		{
+			name:       "invalid request object",
+			request:    `{"jsonrpc": "2.0", "method": 1, "params": "bar"}`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "method must be a non-empty string"}, "id": null}`,
+		},
+		{
+			name:       "invalid id",
+			request:    `{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": {"n": 1}}`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "id must be a string, number or null"}, "id": null}`,
+		},
+		{
+			name:       "invalid params",
+			request:    `{"jsonrpc": "2.0", "method": "eth_getBlockByNumber", "params": "0x1", "id": 6}`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "params must be an array or object"}, "id": 6}`,
+		},
+		{
+			name:       "batch with invalid JSON",
+			request:    `[{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": "1"}, {"jsonrpc": "2.0", "method"]`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "parse error"}, "id": null}`,
+		},
+		{
+			name:       "empty batch",
+			request:    `[]`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid JSON-RPC request: empty batch"}, "id": null}`,
+		},
+		{
+			name:       "invalid batch",
+			request:    `[1]`,
+			wantStatus: http.StatusOK,
+			want:       `[{"jsonrpc": "2.0", "error": {"code": -32600, "message": "request must be an object"}, "id": null}]`,
+		},
+		{
+			name:       "invalid batch entries",
+			request:    `[1, 2, 3]`,
+			wantStatus: http.StatusOK,
+			want: `[
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "request must be an object"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "request must be an object"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "request must be an object"}, "id": null}
+			]`,
+		},
+		{
+			name: "mixed batch",
+			request: `[
+				{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": "1"},
+				{"jsonrpc": "2.0", "method": "eth_blockNumber"},
+				{"jsonrpc": "2.0", "method": "eth_getBlockByNumber", "params": ["0x1", false], "id": "2"},
+				{"foo": "boo"},
+				{"jsonrpc": "2.0", "method": "foo.get", "params": {"name": "myself"}, "id": "5"},
+				{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": "9"}
+			]`,
+			wantStatus: http.StatusOK,
+			want: `[
+				{"jsonrpc": "2.0", "result": "0x10", "id": "1"},
+				{"jsonrpc": "2.0", "result": null, "id": "2"},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "method must be a non-empty string"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32601, "message": "method not found"}, "id": "5"},
+				{"jsonrpc": "2.0", "result": "0x10", "id": "9"}
+			]`,
+		},
+		{
+			name: "batch of notifications",
+			request: `[
+				{"jsonrpc": "2.0", "method": "eth_blockNumber"},
+				{"jsonrpc": "2.0", "method": "foobar"}
+			]`,
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer()
+			ts.mock.getBlockNumberFunc = func() (string, error) { return "0x10", nil }
+			ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+				if blockNumber != "0x1" || fullTransactions {
+					t.Errorf("unexpected params %s, %v", blockNumber, fullTransactions)
+				}
+				return nil, nil
+			}
+
+			rec := httptest.NewRecorder()
+			ts.server.HandleJSONRPC(rec, httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.request)))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d; got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.want == "" {
+				if rec.Body.Len() != 0 {
+					t.Errorf("expected no response body; got %s", rec.Body)
+				}
+				return
+			}
+			if got, want := decodeJSON(t, rec.Body.String()), decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected response\n got: %s\nwant: %s", rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRPCNotificationsAreExecuted(t *testing.T) {
+	ts := newTestServer()
+	calls := 0
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		calls++
+		return "0x10", nil
+	}
+
+	body := `[{"jsonrpc": "2.0", "method": "eth_blockNumber"}, {"jsonrpc": "2.0", "method": "eth_blockNumber"}]`
+	ts.server.HandleJSONRPC(httptest.NewRecorder(), httptest.NewRequest("POST", "/", bytes.NewBufferString(body)))
+	if calls != 2 {
+		t.Errorf("expected both notifications to be executed; got %d calls", calls)
+	}
+}
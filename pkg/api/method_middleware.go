@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"blockchain-client/pkg/metrics"
+)
+
+// observeRPC records the outcome and latency of each call when metrics are
+// enabled
+func (s *Server) observeRPC(next RPCHandler) RPCHandler {
+	return func(ctx context.Context, call *RPCCall) (any, error) {
+		if s.metrics == nil {
+			return next(ctx, call)
+		}
+		start := time.Now()
+		result, err := next(ctx, call)
+		s.metrics.ObserveRPCCall(s.rpcMethodLabel(call.Method), rpcCodeLabel(err), time.Since(start))
+		return result, err
+	}
+}
+
+// rpcCodeLabel is the JSON-RPC error code of err, or "ok" on success
+func rpcCodeLabel(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return strconv.Itoa(rpcErrorFor(err).Code)
+}
+
+// logRPC logs each call at debug level, since batches hold several calls
+// under one access log entry
+func (s *Server) logRPC(next RPCHandler) RPCHandler {
+	return func(ctx context.Context, call *RPCCall) (any, error) {
+		start := time.Now()
+		result, err := next(ctx, call)
+		attrs := []any{"method", call.Method, "batch", call.Batch, "latency", time.Since(start)}
+		if err != nil {
+			attrs = append(attrs, "code", rpcErrorFor(err).Code, "error", err)
+		}
+		slog.DebugContext(ctx, "rpc call", attrs...)
+		return result, err
+	}
+}
+
+// authorizeCall checks that the request's API key may call the method
+func (s *Server) authorizeCall(next RPCHandler) RPCHandler {
+	return func(ctx context.Context, call *RPCCall) (any, error) {
+		if status, rpcError := s.authorizeRPC(call.w, call.Request, call.Method); rpcError != nil {
+			rpcError.status = status
+			return nil, rpcError
+		}
+		return next(ctx, call)
+	}
+}
+
+// limitCall charges the client the compute units of the method. Batches are
+// charged as a whole before their calls run.
+func (s *Server) limitCall(next RPCHandler) RPCHandler {
+	return func(ctx context.Context, call *RPCCall) (any, error) {
+		if !call.Batch {
+			if status, rpcError := s.limitRPC(call.w, call.Request, s.requestCost(call.Request, call.Method)); rpcError != nil {
+				rpcError.status = status
+				return nil, rpcError
+			}
+		}
+		return next(ctx, call)
+	}
+}
+
+// validateParams decodes the call's params into the method's param struct,
+// rejecting params that do not match it
+func validateParams(method RPCMethod) RPCMiddleware {
+	return func(next RPCHandler) RPCHandler {
+		return func(ctx context.Context, call *RPCCall) (any, error) {
+			params := method.newParams()
+			if err := decodeParams(call.RawParams, params); err != nil {
+				return nil, invalidParams("%v", err)
+			}
+			if v, ok := params.(paramValidator); ok {
+				if err := v.Validate(); err != nil {
+					return nil, invalidParams("%v", err)
+				}
+			}
+			call.Params = params
+			return next(ctx, call)
+		}
+	}
+}
+
+// cacheResults serves repeated calls with the same params from the server's
+// result cache for ttl; a ttl of 0 disables caching
+func (s *Server) cacheResults(ttl time.Duration) RPCMiddleware {
+	return func(next RPCHandler) RPCHandler {
+		if ttl <= 0 {
+			return next
+		}
+		return func(ctx context.Context, call *RPCCall) (any, error) {
+			var params bytes.Buffer
+			if err := json.Compact(&params, call.RawParams); err != nil {
+				return next(ctx, call)
+			}
+			key := call.Method + "\x00" + params.String()
+
+			result, ok := s.rpcCache.get(key)
+			if s.metrics != nil {
+				s.metrics.ObserveCache(metrics.CacheRPC, ok)
+			}
+			if ok {
+				return result, nil
+			}
+
+			value, err := next(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return value, nil
+			}
+			s.rpcCache.put(key, encoded, ttl)
+			return json.RawMessage(encoded), nil
+		}
+	}
+}
+
+// resultCacheSize bounds the number of results cached per server
+const resultCacheSize = 4096
+
+// resultCache holds encoded method results until they expire
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	value   json.RawMessage
+	expires time.Time
+}
+
+func newResultCache(size int) *resultCache {
+	return &resultCache{size: size, entries: make(map[string]cachedResult)}
+}
+
+func (c *resultCache) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// put caches value under key. A full cache first drops expired entries, then
+// arbitrary ones.
+func (c *resultCache) put(key string, value json.RawMessage, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedResult{value: value, expires: now.Add(ttl)}
+}
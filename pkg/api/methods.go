@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RPCCall is a JSON-RPC call as it passes through the method middleware
+type RPCCall struct {
+	Method string
+	// RawParams are the params as sent, an array or an object. Params holds
+	// them decoded into a pointer to the method's param struct once they are
+	// validated.
+	RawParams json.RawMessage
+	Params    any
+	// Request is the HTTP request that carried the call
+	Request *http.Request
+	// Batch is set for calls made in a batch, which is rate limited as a
+	// whole before its calls run
+	Batch bool
+
+	w http.ResponseWriter
+}
+
+// RPCHandler serves a JSON-RPC call. An *RPCError is returned to the caller
+// as is and upstream JSON-RPC errors are passed through; any other error is
+// reported as an internal error.
+type RPCHandler func(ctx context.Context, call *RPCCall) (any, error)
+
+// RPCMiddleware wraps the handler of every JSON-RPC method. The method being
+// called is in RPCCall.Method.
+type RPCMiddleware func(next RPCHandler) RPCHandler
+
+// RPCMethod is a JSON-RPC method served by the server
+type RPCMethod struct {
+	// CacheTTL caches results by params for the given time; 0 disables
+	// caching
+	CacheTTL time.Duration
+
+	newParams func() any
+	call      func(ctx context.Context, params any) (any, error)
+}
+
+// paramValidator is implemented by param structs that check their values
+// once decoded
+type paramValidator interface {
+	Validate() error
+}
+
+// NewRPCMethod creates a method that calls fn with its params decoded into
+// P, a struct. Params given by position fill the exported fields of P in
+// order, and params given by name the fields with the matching json name.
+// Every field is required unless tagged `rpc:"optional"`. If P has a
+// Validate() error method it is called after decoding, and an error rejects
+// the call with invalid params.
+func NewRPCMethod[P, R any](fn func(ctx context.Context, params P) (R, error)) RPCMethod {
+	return RPCMethod{
+		newParams: func() any { return new(P) },
+		call: func(ctx context.Context, params any) (any, error) {
+			return fn(ctx, *params.(*P))
+		},
+	}
+}
+
+// WithRPCMethod serves a JSON-RPC method in addition to the built-in ones,
+// or replaces the built-in method of the same name. It is subject to
+// WithMethods like any other method.
+func WithRPCMethod(name string, method RPCMethod) Option {
+	return func(s *Server) {
+		if s.customMethods == nil {
+			s.customMethods = make(map[string]RPCMethod)
+		}
+		s.customMethods[name] = method
+	}
+}
+
+// WithRPCMiddleware wraps every JSON-RPC method in mw, in order, with the
+// first outermost. They run after the built-in authorization, rate limiting,
+// param validation and caching, so RPCCall.Params is already decoded.
+func WithRPCMiddleware(mw ...RPCMiddleware) Option {
+	return func(s *Server) {
+		s.rpcMiddleware = append(s.rpcMiddleware, mw...)
+	}
+}
+
+// blockParams are the params of eth_getBlockByNumber
+type blockParams struct {
+	BlockNumber      string `json:"blockNumber"`
+	FullTransactions bool   `json:"fullTransactions"`
+}
+
+// builtinMethods returns the JSON-RPC methods served by every server
+func (s *Server) builtinMethods() map[string]RPCMethod {
+	return map[string]RPCMethod{
+		"eth_blockNumber": NewRPCMethod(func(ctx context.Context, _ struct{}) (string, error) {
+			return s.client.GetBlockNumber(ctx)
+		}),
+		"eth_getBlockByNumber": NewRPCMethod(func(ctx context.Context, p blockParams) (any, error) {
+			return s.getBlock(ctx, p.BlockNumber, p.FullTransactions)
+		}),
+	}
+}
+
+// rpcHandlers returns the handler of every method, wrapped in the method
+// middleware, building them on first use
+func (s *Server) rpcHandlers() map[string]RPCHandler {
+	s.rpcOnce.Do(func() {
+		methods := s.builtinMethods()
+		for name, method := range s.customMethods {
+			methods[name] = method
+		}
+		s.rpcCache = newResultCache(resultCacheSize)
+		s.rpcMethods = make(map[string]RPCHandler, len(methods))
+		for name, method := range methods {
+			s.rpcMethods[name] = s.chain(method)
+		}
+		// Calls of unknown methods are still authorized and charged
+		s.rpcNotFound = s.observeRPC(s.logRPC(s.authorizeCall(s.limitCall(methodNotFound))))
+	})
+	return s.rpcMethods
+}
+
+// chain wraps method in the built-in middleware and those added with
+// WithRPCMiddleware, outermost first
+func (s *Server) chain(method RPCMethod) RPCHandler {
+	handler := func(ctx context.Context, call *RPCCall) (any, error) {
+		return method.call(ctx, call.Params)
+	}
+	for i := len(s.rpcMiddleware) - 1; i >= 0; i-- {
+		handler = s.rpcMiddleware[i](handler)
+	}
+	middleware := []RPCMiddleware{
+		s.observeRPC,
+		s.logRPC,
+		s.authorizeCall,
+		s.limitCall,
+		validateParams(method),
+		s.cacheResults(method.CacheTTL),
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// callRPC executes a single JSON-RPC call through its method's middleware.
+// It returns the response and the HTTP status to answer the call with when
+// it is the whole request.
+func (s *Server) callRPC(w http.ResponseWriter, r *http.Request, request RPCRequest, batch bool) (RPCResponse, int) {
+	response := RPCResponse{JSONRPC: "2.0", ID: request.ID}
+
+	// Disabled methods are reported as not found
+	handler, ok := s.rpcHandlers()[request.Method]
+	if !ok || !s.methodEnabled(request.Method) {
+		handler = s.rpcNotFound
+	}
+
+	call := &RPCCall{Method: request.Method, RawParams: request.Params, Request: r, Batch: batch, w: w}
+	result, err := handler(r.Context(), call)
+	if err != nil {
+		response.Error = rpcErrorFor(err)
+		if response.Error.status != 0 {
+			return response, response.Error.status
+		}
+		return response, http.StatusOK
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		response.Error = &RPCError{Code: -32603, Message: "failed to marshal result"}
+		return response, http.StatusOK
+	}
+	response.Result = resultBytes
+	return response, http.StatusOK
+}
+
+// methodNotFound answers calls of methods the server does not serve
+func methodNotFound(context.Context, *RPCCall) (any, error) {
+	return nil, &RPCError{Code: -32601, Message: "method not found"}
+}
+
+// invalidParams rejects a call whose params do not match its method
+func invalidParams(format string, args ...any) *RPCError {
+	return &RPCError{Code: -32602, Message: "invalid params: " + fmt.Sprintf(format, args...)}
+}
+
+// paramField describes a field of a param struct
+type paramField struct {
+	index    int
+	name     string
+	optional bool
+}
+
+// paramFields lists the exported fields of a param struct in order
+func paramFields(t reflect.Type) []paramField {
+	var fields []paramField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, paramField{index: i, name: name, optional: f.Tag.Get("rpc") == "optional"})
+	}
+	return fields
+}
+
+// decodeParams decodes params given by position or by name into dst, a
+// pointer to a param struct
+func decodeParams(params json.RawMessage, dst any) error {
+	v := reflect.ValueOf(dst).Elem()
+	if v.Kind() != reflect.Struct {
+		if len(params) == 0 {
+			return errors.New("missing params")
+		}
+		return json.Unmarshal(params, dst)
+	}
+
+	fields := paramFields(v.Type())
+	given := make([]bool, len(fields))
+	decode := func(i int, value json.RawMessage) error {
+		given[i] = true
+		if err := json.Unmarshal(value, v.Field(fields[i].index).Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %w", fields[i].name, err)
+		}
+		return nil
+	}
+
+	switch {
+	case len(params) == 0:
+	case params[0] == '[':
+		var list []json.RawMessage
+		if err := json.Unmarshal(params, &list); err != nil {
+			return err
+		}
+		if len(list) > len(fields) {
+			return fmt.Errorf("expected at most %d params, got %d", len(fields), len(list))
+		}
+		for i, value := range list {
+			if err := decode(i, value); err != nil {
+				return err
+			}
+		}
+	default:
+		var named map[string]json.RawMessage
+		if err := json.Unmarshal(params, &named); err != nil {
+			return err
+		}
+		for name, value := range named {
+			i := fieldIndex(fields, name)
+			if i < 0 {
+				return fmt.Errorf("unknown param %q", name)
+			}
+			if err := decode(i, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, f := range fields {
+		if !given[i] && !f.optional {
+			return fmt.Errorf("missing param %s", f.name)
+		}
+	}
+	return nil
+}
+
+func fieldIndex(fields []paramField, name string) int {
+	for i, f := range fields {
+		if f.name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+type accountSummaryParams struct {
+	Address string `json:"address"`
+	Block   string `json:"block" rpc:"optional"`
+}
+
+func (p accountSummaryParams) Validate() error {
+	if !strings.HasPrefix(p.Address, "0x") || len(p.Address) != 42 {
+		return errors.New("address must be a 20 byte hex string")
+	}
+	return nil
+}
+
+type accountSummary struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Block   string `json:"block"`
+}
+
+// accountSummaryMethod is a custom method as a team would add it, reading
+// through the server's upstream client
+func accountSummaryMethod(client BlockchainClient) RPCMethod {
+	return NewRPCMethod(func(ctx context.Context, p accountSummaryParams) (*accountSummary, error) {
+		if p.Block == "" {
+			p.Block = "latest"
+		}
+		proof, err := client.GetProof(ctx, p.Address, nil, p.Block)
+		if err != nil {
+			return nil, err
+		}
+		return &accountSummary{Address: p.Address, Balance: proof.Balance, Block: p.Block}, nil
+	})
+}
+
+const testAddress = "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+
+func callJSONRPC(t *testing.T, s *Server, body string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.HandleJSONRPC(rec, httptest.NewRequest("POST", "/", bytes.NewBufferString(body)))
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+func TestCustomRPCMethod(t *testing.T) {
+	mock := &mockBlockchainClient{
+		getProofFunc: func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+			if blockNumber == "0xdead" {
+				return nil, &blockchain.RPCError{Code: -32000, Message: "header not found"}
+			}
+			return &blockchain.AccountProof{Address: address, Balance: "0x64"}, nil
+		},
+	}
+	s := NewServer("", WithClient(mock), WithRPCMethod("our_getAccountSummary", accountSummaryMethod(mock)))
+
+	tests := []struct {
+		name   string
+		params string
+		want   string
+	}{
+		{
+			name:   "positional params",
+			params: `["` + testAddress + `", "0x10"]`,
+			want:   `{"jsonrpc":"2.0","result":{"address":"` + testAddress + `","balance":"0x64","block":"0x10"},"id":1}`,
+		},
+		{
+			name:   "named params with optional param omitted",
+			params: `{"address": "` + testAddress + `"}`,
+			want:   `{"jsonrpc":"2.0","result":{"address":"` + testAddress + `","balance":"0x64","block":"latest"},"id":1}`,
+		},
+		{
+			name:   "missing param",
+			params: `[]`,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: missing param address"},"id":1}`,
+		},
+		{
+			name:   "too many params",
+			params: `["` + testAddress + `", "latest", true]`,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: expected at most 2 params, got 3"},"id":1}`,
+		},
+		{
+			name:   "wrong type",
+			params: `[1]`,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: address: json: cannot unmarshal number into Go value of type string"},"id":1}`,
+		},
+		{
+			name:   "failed validation",
+			params: `["0x1234"]`,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid params: address must be a 20 byte hex string"},"id":1}`,
+		},
+		{
+			name:   "upstream error passed through",
+			params: `["` + testAddress + `", "0xdead"]`,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32000,"message":"header not found"},"id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"our_getAccountSummary","params":`+tt.params+`,"id":1}`)
+			if status != http.StatusOK {
+				t.Errorf("expected status 200; got %d", status)
+			}
+			if body != tt.want {
+				t.Errorf("unexpected response\n got: %s\nwant: %s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomRPCMethodCanBeDisabled(t *testing.T) {
+	mock := &mockBlockchainClient{}
+	s := NewServer("", WithClient(mock), WithMethods([]string{"eth_blockNumber"}),
+		WithRPCMethod("our_getAccountSummary", accountSummaryMethod(mock)))
+
+	_, body := callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"our_getAccountSummary","params":["`+testAddress+`"],"id":1}`)
+	if want := `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":1}`; body != want {
+		t.Errorf("unexpected response\n got: %s\nwant: %s", body, want)
+	}
+}
+
+func TestRPCMiddleware(t *testing.T) {
+	mock := &mockBlockchainClient{
+		getBlockByNumberFunc: func(string, bool) (*blockchain.Block, error) { return nil, nil },
+	}
+
+	var calls []string
+	record := func(name string) RPCMiddleware {
+		return func(next RPCHandler) RPCHandler {
+			return func(ctx context.Context, call *RPCCall) (any, error) {
+				calls = append(calls, name+" "+call.Method+" "+reflect.TypeOf(call.Params).String())
+				return next(ctx, call)
+			}
+		}
+	}
+	s := NewServer("", WithClient(mock), WithRPCMiddleware(record("outer"), record("inner")))
+
+	callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["0x1",false],"id":1}`)
+	callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":[],"id":2}`)
+
+	// The call with invalid params is rejected before reaching custom middleware
+	want := []string{
+		"outer eth_getBlockByNumber *api.blockParams",
+		"inner eth_getBlockByNumber *api.blockParams",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected middleware calls %q; got %q", want, calls)
+	}
+}
+
+func TestRPCMethodCache(t *testing.T) {
+	upstreamCalls := 0
+	mock := &mockBlockchainClient{
+		getProofFunc: func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+			upstreamCalls++
+			return &blockchain.AccountProof{Address: address, Balance: "0x64"}, nil
+		},
+	}
+	method := accountSummaryMethod(mock)
+	method.CacheTTL = time.Minute
+	s := NewServer("", WithClient(mock), WithRPCMethod("our_getAccountSummary", method))
+
+	first := `{"jsonrpc":"2.0","method":"our_getAccountSummary","params":["` + testAddress + `"],"id":1}`
+	_, want := callJSONRPC(t, s, first)
+	// Whitespace in params does not change the cache key
+	_, got := callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"our_getAccountSummary","params":[ "`+testAddress+`" ],"id":1}`)
+	if got != want {
+		t.Errorf("expected cached response %s; got %s", want, got)
+	}
+	if upstreamCalls != 1 {
+		t.Errorf("expected 1 upstream call; got %d", upstreamCalls)
+	}
+
+	callJSONRPC(t, s, `{"jsonrpc":"2.0","method":"our_getAccountSummary","params":["`+testAddress+`","0x1"],"id":1}`)
+	if upstreamCalls != 2 {
+		t.Errorf("expected other params to miss the cache; got %d upstream calls", upstreamCalls)
+	}
+}
+
+func TestResultCacheBound(t *testing.T) {
+	c := newResultCache(2)
+	c.put("a", []byte("1"), time.Minute)
+	c.put("b", []byte("2"), -time.Second)
+	c.put("c", []byte("3"), time.Minute)
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf("expected expired entry to be evicted")
+	}
+	if v, ok := c.get("c"); !ok || string(v) != "3" {
+		t.Errorf("expected newest entry to be cached; got %s, %v", v, ok)
+	}
+	if len(c.entries) > 2 {
+		t.Errorf("expected at most 2 entries; got %d", len(c.entries))
+	}
+}
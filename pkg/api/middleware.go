@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"blockchain-client/pkg/logging"
+)
+
+// requestInfo collects details about a request as it is handled, for use by
+// middleware once the handler returns
+type requestInfo struct {
+	rpcMethod  string
+	apiKey     string
+	apiKeyName string
+	// chain and route are recorded when a request is routed to a chain, as
+	// the chain's ServeMux sees a request with the chain prefix removed, or
+	// when middleware hands the ServeMux a request with a new context
+	chain string
+	route string
+}
+
+type requestInfoKey struct{}
+
+// setRoute records the route matched by a ServeMux that was handed a copy of
+// the current request. An empty route leaves one recorded nearer the ServeMux.
+func setRoute(ctx context.Context, route string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok && route != "" {
+		info.route = route
+	}
+}
+
+// setRPCMethod records the JSON-RPC method handled by the current request
+func setRPCMethod(ctx context.Context, method string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.rpcMethod = method
+	}
+}
+
+// setAPIKey records the API key that authenticated the current request and
+// its name, which is logged in place of the key
+func setAPIKey(ctx context.Context, key, name string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.apiKey = key
+		info.apiKeyName = name
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming handlers
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// routePath returns the path of a ServeMux pattern, without the method and
+// the end-of-path wildcard, as requests are labelled by route
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+	return strings.TrimSuffix(pattern, "{$}")
+}
+
+// rpcMethodLabel bounds the cardinality of the JSON-RPC method label to the
+// methods the server serves
+func (s *Server) rpcMethodLabel(method string) string {
+	if method == "" || method == batchMethod {
+		return method
+	}
+	if _, ok := s.rpcHandlers()[method]; ok {
+		return method
+	}
+	return "other"
+}
+
+// tracerName identifies spans created by the API server
+const tracerName = "blockchain-client/pkg/api"
+
+// maxRequestIDLength bounds the length of caller-supplied request IDs
+const maxRequestIDLength = 128
+
+// validRequestID reports whether a caller-supplied request ID is safe to log
+// and propagate
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID tags every request with the caller's X-Request-ID, or a new one
+// when it is missing or malformed, and echoes it in the response
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// instrument starts a server span for every request handled by next,
+// continuing any trace the caller propagated in a traceparent header, writes
+// an access log entry and records request metrics when they are enabled
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{}
+		rec := &statusRecorder{ResponseWriter: w}
+
+		path := redactPath(r.URL.Path)
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", path),
+			),
+		)
+		defer span.End()
+
+		r = r.WithContext(context.WithValue(ctx, requestInfoKey{}, info))
+		next.ServeHTTP(rec, r)
+
+		// ServeMux records the matched pattern on the request it routes
+		route := r.Pattern
+		if route == "" {
+			route = info.route
+		}
+		route = routePath(route)
+		if route == "" {
+			route = "unmatched"
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if info.chain != "" {
+			span.SetAttributes(attribute.String("chain", info.chain))
+		}
+		if info.rpcMethod != "" {
+			span.SetAttributes(attribute.String("rpc.method", info.rpcMethod))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		latency := time.Since(start)
+		if s.metrics != nil {
+			s.metrics.ObserveRequest(route, s.rpcMethodLabel(info.rpcMethod), status, latency)
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", path),
+			slog.String("route", route),
+			slog.String("rpc_method", info.rpcMethod),
+			slog.String("api_key", info.apiKeyName),
+			slog.Int("status", status),
+			slog.Duration("latency", latency),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if info.chain != "" {
+			attrs = append(attrs, slog.String("chain", info.chain))
+		}
+		slog.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
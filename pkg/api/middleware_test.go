@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"blockchain-client/pkg/logging"
+	"blockchain-client/pkg/metrics"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	ts := newTestServer()
+	ts.server.metrics = metrics.New()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+
+	handler := ts.server.SetupRoutes()
+
+	rpc := []byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", bytes.NewReader(rpc)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/blocks/latest", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/blocks", nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	expected := []string{
+		`blockchain_client_http_requests_total{route="/",rpc_method="eth_blockNumber",status="200"} 1`,
+		`blockchain_client_http_requests_total{route="/api/blocks/latest",rpc_method="",status="200"} 1`,
+		`blockchain_client_http_requests_total{route="/api/blocks",rpc_method="",status="400"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestRequestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ts := newTestServer()
+	var upstreamSpan trace.SpanContext
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+	ts.server.client = &contextCapture{BlockchainClient: ts.mock, capture: func(ctx context.Context) {
+		upstreamSpan = trace.SpanContextFromContext(ctx)
+	}}
+	handler := ts.server.SetupRoutes()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`)))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "POST /" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != traceID || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected span to continue the propagated trace, got %v", span.SpanContext())
+	}
+	if upstreamSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected upstream calls to receive the request span context")
+	}
+
+	found := false
+	for _, kv := range span.Attributes() {
+		if kv.Key == "rpc.method" && kv.Value.AsString() == "eth_blockNumber" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected rpc.method attribute, got %v", span.Attributes())
+	}
+}
+
+// contextCapture reports the context passed to GetBlockNumber
+type contextCapture struct {
+	BlockchainClient
+	capture func(ctx context.Context)
+}
+
+func (c *contextCapture) GetBlockNumber(ctx context.Context) (string, error) {
+	c.capture(ctx)
+	return c.BlockchainClient.GetBlockNumber(ctx)
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger, _ := logging.New(&logs, logging.Config{Format: logging.FormatJSON})
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+	handler := ts.server.SetupRoutes()
+
+	t.Run("propagates caller ID", func(t *testing.T) {
+		logs.Reset()
+		req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`)))
+		req.Header.Set("X-Request-ID", "abc-123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("X-Request-ID"); got != "abc-123" {
+			t.Errorf("expected request ID to be echoed, got %q", got)
+		}
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid access log %q: %v", logs.String(), err)
+		}
+		want := map[string]interface{}{
+			"msg":        "request",
+			"request_id": "abc-123",
+			"method":     "POST",
+			"path":       "/",
+			"rpc_method": "eth_blockNumber",
+			"status":     float64(200),
+		}
+		for key, value := range want {
+			if entry[key] != value {
+				t.Errorf("expected %s=%v in access log, got %v", key, value, entry[key])
+			}
+		}
+		if _, ok := entry["latency"]; !ok {
+			t.Errorf("expected latency in access log")
+		}
+	})
+
+	t.Run("generates missing or invalid IDs", func(t *testing.T) {
+		for _, id := range []string{"", "bad id\n"} {
+			req := httptest.NewRequest("GET", "/api/blocks/latest", nil)
+			req.Header.Set("X-Request-ID", id)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get("X-Request-ID")
+			if len(got) != 32 || got == id {
+				t.Errorf("expected generated request ID for %q, got %q", id, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,620 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/export"
+	"blockchain-client/pkg/store"
+	"blockchain-client/pkg/version"
+)
+
+// openAPIVersion is the OpenAPI version of the document served at
+// /openapi.json
+const openAPIVersion = "3.0.3"
+
+// Patterns of the values accepted by REST parameters
+const (
+	// blockTagPattern matches a hex block number or a block tag, as passed
+	// to the upstream
+	blockTagPattern = `^(0x[0-9a-fA-F]+|latest|earliest|pending|safe|finalized)$`
+	// blockHeightPattern matches a block height in decimal or hex, as parsed
+	// by parseBlockParam
+	blockHeightPattern = `^(0x[0-9a-fA-F]+|[0-9]+)$`
+	// blockIDPattern matches the {id} of a block route, as parsed by
+	// parseBlockID
+	blockIDPattern     = `^(0x[0-9a-fA-F]+|[0-9]+|latest|earliest|pending|safe|finalized)$`
+	quantityPattern    = `^0x[0-9a-fA-F]+$`
+	storageKeysPattern = `^0x[0-9a-fA-F]+(,0x[0-9a-fA-F]+)*$`
+	directionPattern   = `^(all|(from|to|log|topic)(,(from|to|log|topic))*)$`
+)
+
+// openAPIDocument is an OpenAPI 3.0 document, covering the parts the server
+// uses
+type openAPIDocument struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       openAPIInfo           `json:"info"`
+	Paths      map[string]pathItem   `json:"paths"`
+	Components openAPIComponents     `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	Name   string `json:"name,omitempty"`
+	In     string `json:"in,omitempty"`
+}
+
+// pathItem holds the operations of a path by lower case HTTP method
+type pathItem map[string]*operation
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []parameter          `json:"parameters,omitempty"`
+	RequestBody *requestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+// schema is the subset of the OpenAPI schema object the server uses. A
+// schema without a type accepts any value.
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	AllOf                []*schema          `json:"allOf,omitempty"`
+	OneOf                []*schema          `json:"oneOf,omitempty"`
+}
+
+// schemaRef refers to a schema in the document's components
+func schemaRef(name string) *schema {
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+// schemaGenerator derives schemas from the Go types the handlers encode and
+// decode, registering named structs as components
+type schemaGenerator struct {
+	schemas map[string]*schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{schemas: make(map[string]*schema), names: make(map[reflect.Type]string)}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaFor returns the schema of values of type t as encoding/json encodes
+// them. Pointers are nullable; struct fields without omitempty are required.
+func (g *schemaGenerator) schemaFor(t reflect.Type) *schema {
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schemaFor(t.Elem())
+		if s.Ref != "" {
+			// A $ref cannot carry siblings in OpenAPI 3.0
+			return &schema{AllOf: []*schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	case reflect.Struct:
+		return g.structSchema(t)
+	case reflect.Slice, reflect.Array:
+		return &schema{Type: "array", Items: g.schemaFor(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem()), Nullable: true}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer", Minimum: bound(0)}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	}
+	return &schema{}
+}
+
+// structSchema registers the object schema of a struct type as a component,
+// named after the type, and refers to it
+func (g *schemaGenerator) structSchema(t reflect.Type) *schema {
+	if name, ok := g.names[t]; ok {
+		return schemaRef(name)
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken || name == "" {
+		name = strings.ReplaceAll(t.String(), ".", "")
+	}
+	g.names[t] = name
+
+	// Register before generating the fields, so recursive types terminate
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+	g.schemas[name] = s
+	g.addFields(s, t)
+	return schemaRef(name)
+}
+
+// addFields adds the JSON fields of struct type t to s, including those of
+// embedded structs
+func (g *schemaGenerator) addFields(s *schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.addFields(s, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schemaFor(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// openAPIRoute describes a REST operation for the document
+type openAPIRoute struct {
+	method string
+	path   string
+	op     operation
+	// body is the type of the JSON request body, if any
+	body reflect.Type
+	// responses maps status codes to the type of their JSON body
+	responses map[int]reflect.Type
+	// oneOf lists the types the JSON body of a response listed with a nil
+	// type may have
+	oneOf []reflect.Type
+	// ndjson is the type of each line of an NDJSON response to 200 OK
+	ndjson reflect.Type
+}
+
+// Tags grouping the REST operations in the document
+const (
+	tagBlocks       = "blocks"
+	tagTransactions = "transactions"
+	tagAccounts     = "accounts"
+	tagExports      = "exports"
+	tagHealth       = "health"
+)
+
+func queryParam(name, description string, s *schema) parameter {
+	return parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+func pathParam(name, description string, s *schema) parameter {
+	return parameter{Name: name, In: "path", Description: description, Required: true, Schema: s}
+}
+
+// openAPIRoutes lists the REST operations served by routes. Every route
+// registered there must be listed here; TestOpenAPIDocumentsRoutes checks
+// that they agree.
+func openAPIRoutes() []openAPIRoute {
+	var (
+		jobType  = reflect.TypeFor[ExportJob]()
+		block    = &schema{Type: "string", Pattern: blockTagPattern}
+		height   = &schema{Type: "string", Pattern: blockHeightPattern}
+		full     = queryParam("full", "Include full transaction objects instead of hashes", &schema{Type: "boolean"})
+		verify   = queryParam("verify", "Fetch the block from the upstream and check its hash against its header", &schema{Type: "boolean"})
+		blockID  = pathParam("id", "Block number in decimal or hex, or a block tag", &schema{Type: "string", Pattern: blockIDPattern})
+		address  = pathParam("address", "Account address", &schema{Type: "string", Pattern: addressPattern.String()})
+		exportID = pathParam("id", "Export job ID", &schema{Type: "string"})
+		cursor   = queryParam("cursor", "nextCursor of the previous page", &schema{Type: "string", Pattern: quantityPattern})
+		limit    = queryParam("limit", "Blocks per page", &schema{Type: "integer", Minimum: bound(1), Maximum: bound(maxRangeLimit)})
+		format   = queryParam("format", "Stream the range as NDJSON", &schema{Type: "string", Enum: []string{"ndjson"}})
+	)
+
+	versioned := []openAPIRoute{
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/blocks",
+			op: operation{
+				OperationID: "getBlockRange",
+				Summary:     "Get a range of blocks",
+				Description: "Returns a page of the blocks in the range, or streams all of them as NDJSON with " +
+					"format=ndjson or Accept: application/x-ndjson.",
+				Tags: []string{tagBlocks},
+				Parameters: []parameter{
+					{Name: "from", In: "query", Description: "First block of the range, in decimal or hex", Required: true, Schema: height},
+					{Name: "to", In: "query", Description: "Last block of the range, in decimal or hex", Required: true, Schema: height},
+					full, cursor, limit, format,
+				},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[BlockRangeResponse]()},
+			ndjson:    reflect.TypeFor[blockchain.Block](),
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/blocks/{id}",
+			op: operation{
+				OperationID: "getBlock",
+				Summary:     "Get a block",
+				Description: "Served from the indexer store when ingested.",
+				Tags:        []string{tagBlocks},
+				Parameters:  []parameter{blockID, full, verify},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[BlockResponse]()},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/blocks/{id}/transactions",
+			op: operation{
+				OperationID: "getBlockTransactions",
+				Summary:     "Get the transactions of a block",
+				Tags:        []string{tagBlocks},
+				Parameters:  []parameter{blockID},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[BlockTransactionsResponse]()},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/tx/{hash}",
+			op: operation{
+				OperationID: "getTransaction",
+				Summary:     "Get a transaction by hash",
+				Description: "Served from the indexer store when its block is ingested.",
+				Tags:        []string{tagTransactions},
+				Parameters: []parameter{
+					pathParam("hash", "Transaction hash", &schema{Type: "string", Pattern: txHashPattern.String()}),
+				},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[TransactionResponse]()},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/accounts/{address}",
+			op: operation{
+				OperationID: "getAccount",
+				Summary:     "Get an account's balance, nonce and storage",
+				Tags:        []string{tagAccounts},
+				Parameters: []parameter{
+					address,
+					queryParam("block", "Block number in hex, or a block tag; latest by default", block),
+					queryParam("storage", "Comma-separated storage slots to read", &schema{Type: "string", Pattern: storageKeysPattern}),
+					queryParam("verified", "Check the values against the block's state root", &schema{Type: "boolean"}),
+				},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[AccountResponse]()},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/accounts/{address}/transactions",
+			op: operation{
+				OperationID: "getAccountTransactions",
+				Summary:     "List the indexed activity of an address",
+				Tags:        []string{tagAccounts},
+				Parameters: []parameter{
+					address,
+					queryParam("direction", "Comma-separated roles of the address: from, to, log or topic; all by default", &schema{Type: "string", Pattern: directionPattern}),
+					queryParam("fromBlock", "First block, in decimal or hex", height),
+					queryParam("toBlock", "Last block, in decimal or hex", height),
+					queryParam("cursor", "nextCursor of the previous page", &schema{Type: "string"}),
+					queryParam("limit", "Items per page", &schema{Type: "integer", Minimum: bound(1)}),
+				},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[store.ActivityPage]()},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/exports",
+			op: operation{
+				OperationID: "listExports",
+				Summary:     "List export jobs",
+				Tags:        []string{tagExports},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[[]ExportJob]()},
+		},
+		{
+			method: http.MethodPost,
+			path:   apiPrefix + "/exports",
+			op: operation{
+				OperationID: "startExport",
+				Summary:     "Start an export job",
+				Tags:        []string{tagExports},
+			},
+			body:      reflect.TypeFor[ExportRequest](),
+			responses: map[int]reflect.Type{http.StatusAccepted: jobType},
+		},
+		{
+			method: http.MethodGet,
+			path:   apiPrefix + "/exports/{id}",
+			op: operation{
+				OperationID: "getExport",
+				Summary:     "Get an export job's status and written files",
+				Tags:        []string{tagExports},
+				Parameters:  []parameter{exportID},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: jobType},
+		},
+		{
+			method: http.MethodDelete,
+			path:   apiPrefix + "/exports/{id}",
+			op: operation{
+				OperationID: "cancelExport",
+				Summary:     "Cancel an export job",
+				Tags:        []string{tagExports},
+				Parameters:  []parameter{exportID},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: jobType},
+		},
+	}
+
+	// The unversioned block routes differ from their replacements; the
+	// others are aliases of the versioned routes
+	routes := append(versioned,
+		openAPIRoute{
+			method: http.MethodGet,
+			path:   "/api/blocks/latest",
+			op: operation{
+				OperationID: "getLatestBlockNumber",
+				Summary:     "Get the latest block number",
+				Description: "Deprecated: use " + apiPrefix + "/blocks/latest, which returns the latest block.",
+				Tags:        []string{tagBlocks},
+				Deprecated:  true,
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[BlockNumberResponse]()},
+		},
+		openAPIRoute{
+			method: http.MethodGet,
+			path:   "/api/blocks",
+			op: operation{
+				OperationID: "getBlocks",
+				Summary:     "Get a block by number, or a range of blocks",
+				Description: "Deprecated: use " + apiPrefix + "/blocks/{id} and " + apiPrefix + "/blocks. " +
+					"With number, returns that block; with from and to, a range of blocks.",
+				Tags:       []string{tagBlocks},
+				Deprecated: true,
+				Parameters: []parameter{
+					queryParam("number", "Block number in hex, or a block tag", block),
+					full,
+					verify,
+					queryParam("from", "First block of the range, in decimal or hex", height),
+					queryParam("to", "Last block of the range, in decimal or hex", height),
+					cursor, limit, format,
+				},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: nil},
+			oneOf:     []reflect.Type{reflect.TypeFor[BlockResponse](), reflect.TypeFor[BlockRangeResponse]()},
+			ndjson:    reflect.TypeFor[blockchain.Block](),
+		},
+	)
+	for _, route := range versioned {
+		if strings.HasPrefix(route.path, apiPrefix+"/accounts") || strings.HasPrefix(route.path, apiPrefix+"/exports") {
+			routes = append(routes, unversioned(route))
+		}
+	}
+
+	return append(routes,
+		openAPIRoute{
+			method: http.MethodGet,
+			path:   "/healthz",
+			op: operation{
+				OperationID: "getHealth",
+				Summary:     "Liveness probe",
+				Tags:        []string{tagHealth},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[HealthResponse]()},
+		},
+		openAPIRoute{
+			method: http.MethodGet,
+			path:   "/readyz",
+			op: operation{
+				OperationID: "getReadiness",
+				Summary:     "Readiness probe, checking the upstream and chain head",
+				Tags:        []string{tagHealth},
+			},
+			responses: map[int]reflect.Type{
+				http.StatusOK:                 reflect.TypeFor[ReadinessResponse](),
+				http.StatusServiceUnavailable: reflect.TypeFor[ReadinessResponse](),
+			},
+		},
+		openAPIRoute{
+			method: http.MethodGet,
+			path:   "/status",
+			op: operation{
+				OperationID: "getStatus",
+				Summary:     "Version, uptime, chain head and upstream statistics",
+				Tags:        []string{tagHealth},
+			},
+			responses: map[int]reflect.Type{http.StatusOK: reflect.TypeFor[StatusResponse]()},
+		},
+	)
+}
+
+// unversioned returns the deprecated unversioned alias of a versioned route
+func unversioned(route openAPIRoute) openAPIRoute {
+	route.path = "/api" + strings.TrimPrefix(route.path, apiPrefix)
+	route.op.OperationID += "Unversioned"
+	route.op.Description = "Deprecated: use " + apiPrefix + strings.TrimPrefix(route.path, "/api") + "."
+	route.op.Deprecated = true
+	return route
+}
+
+// newOpenAPIDocument builds the document describing the REST routes, with
+// schemas generated from the types the handlers encode and decode
+func newOpenAPIDocument(authenticated bool) *openAPIDocument {
+	g := newSchemaGenerator()
+	errorSchema := g.schemaFor(reflect.TypeFor[ErrorResponse]())
+
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       "Blockchain Client API",
+			Description: "REST API of the blockchain client. The JSON-RPC endpoint is served at / and documented separately.",
+			Version:     version.Get().Version,
+		},
+		Paths: make(map[string]pathItem),
+	}
+
+	for _, route := range openAPIRoutes() {
+		op := route.op
+		op.Responses = make(map[string]*response)
+		for status, typ := range route.responses {
+			resp := &response{Description: http.StatusText(status), Content: make(map[string]mediaType)}
+			switch {
+			case typ != nil:
+				resp.Content["application/json"] = mediaType{Schema: g.schemaFor(typ)}
+			case route.oneOf != nil:
+				body := &schema{}
+				for _, alt := range route.oneOf {
+					body.OneOf = append(body.OneOf, g.schemaFor(alt))
+				}
+				resp.Content["application/json"] = mediaType{Schema: body}
+			}
+			if status == http.StatusOK && route.ndjson != nil {
+				// A stream that fails after its first block ends with an
+				// error line
+				resp.Content[ndjsonContentType] = mediaType{Schema: &schema{OneOf: []*schema{g.schemaFor(route.ndjson), errorSchema}}}
+			}
+			op.Responses[statusKey(status)] = resp
+		}
+		op.Responses["default"] = &response{
+			Description: "Error",
+			Content:     map[string]mediaType{"application/json": {Schema: errorSchema}},
+		}
+		if route.body != nil {
+			op.RequestBody = &requestBody{
+				Required: true,
+				Content:  map[string]mediaType{"application/json": {Schema: g.schemaFor(route.body)}},
+			}
+		}
+
+		item, ok := doc.Paths[route.path]
+		if !ok {
+			item = make(pathItem)
+			doc.Paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = &op
+	}
+
+	// Refine the generated schemas with the values the handlers accept
+	if req := g.schemas["ExportRequest"]; req != nil {
+		req.Properties["from"].Pattern = blockHeightPattern
+		req.Properties["to"].Pattern = blockHeightPattern
+		req.Properties["format"].Enum = []string{string(export.FormatCSV), string(export.FormatNDJSON), string(export.FormatParquet)}
+	}
+	doc.Components.Schemas = g.schemas
+
+	if authenticated {
+		doc.Components.SecuritySchemes = map[string]securityScheme{
+			"apiKeyHeader": {Type: "apiKey", Name: APIKeyHeader, In: "header"},
+			"bearer":       {Type: "http", Scheme: "bearer"},
+		}
+		doc.Security = []map[string][]string{{"apiKeyHeader": {}}, {"bearer": {}}}
+	}
+	return doc
+}
+
+// statusKey formats a status code as a key of an operation's responses
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+// openAPI returns the document describing the server's REST routes,
+// building it on first use
+func (s *Server) openAPI() *openAPIDocument {
+	s.openAPIOnce.Do(func() {
+		s.openAPIDoc = newOpenAPIDocument(s.keys != nil)
+	})
+	return s.openAPIDoc
+}
+
+// HandleOpenAPI handles the /openapi.json endpoint, serving the OpenAPI
+// document of the REST routes
+func (s *Server) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, s.openAPI())
+}
+
+//go:embed docs.html
+var docsPage []byte
+
+// HandleDocs handles the /docs endpoint, serving an interactive page that
+// renders /openapi.json and sends requests to the documented routes
+func (s *Server) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
@@ -0,0 +1,368 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// testBlock returns a block with the fields upstreams return
+func testBlock(number string) *blockchain.Block {
+	return &blockchain.Block{
+		Number:           number,
+		Hash:             "0x" + strings.Repeat("ab", 32),
+		ParentHash:       "0x" + strings.Repeat("cd", 32),
+		Nonce:            "0x0000000000000000",
+		Timestamp:        blockchain.EncodeQuantity(uint64(time.Now().Unix())),
+		Transactions:     json.RawMessage(`[]`),
+		TransactionCount: 0,
+	}
+}
+
+// newValidatingServer returns a test server whose REST requests and
+// responses are checked against the OpenAPI document, failing the test on
+// responses that do not match it
+func newValidatingServer(t *testing.T) (*testServer, http.Handler) {
+	t.Helper()
+	ts := newTestServer()
+	ts.server.validation = ValidationConfig{
+		Requests:  true,
+		Responses: true,
+		OnInvalidResponse: func(r *http.Request, err error) {
+			t.Errorf("invalid response: %v", err)
+		},
+	}
+	ts.server.exportDir = t.TempDir()
+
+	ts.mock.getBlockNumberFunc = func() (string, error) { return "0x10", nil }
+	ts.mock.getChainIDFunc = func() (string, error) { return "0x89", nil }
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		if blockNumber == "latest" {
+			return testBlock("0x10"), nil
+		}
+		if blockNumber == "0xdead" {
+			return nil, &blockchain.RPCError{Code: -32000, Message: "header not found"}
+		}
+		if blockNumber == "0xffff" {
+			return nil, nil
+		}
+		return testBlock(blockNumber), nil
+	}
+	ts.mock.getBlocksByNumberFunc = func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+		blocks := make([]*blockchain.Block, len(blockNumbers))
+		for i, n := range blockNumbers {
+			blocks[i] = testBlock(n)
+		}
+		return blocks, nil
+	}
+	ts.mock.getTransactionFunc = func(hash string) (json.RawMessage, error) {
+		if hash != testTxHash {
+			return nil, nil
+		}
+		return json.RawMessage(`{"hash":"` + hash + `","blockNumber":"0x10"}`), nil
+	}
+	ts.mock.getProofFunc = func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+		return &blockchain.AccountProof{
+			Address:     address,
+			Balance:     "0x64",
+			Nonce:       "0x1",
+			CodeHash:    "0x" + strings.Repeat("00", 32),
+			StorageHash: "0x" + strings.Repeat("00", 32),
+		}, nil
+	}
+	return ts, ts.server.SetupRoutes()
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	_, handler := newAuthTestServer(t, `{"keys":[{"name":"team","key":"secret"}]}`)
+
+	// The document and docs page are served without an API key
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200; got %d", rec.Code)
+	}
+
+	var doc openAPIDocument
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.OpenAPI != openAPIVersion {
+		t.Errorf("expected openapi %s; got %s", openAPIVersion, doc.OpenAPI)
+	}
+	if op := doc.Paths["/api/blocks/latest"]["get"]; op == nil || op.Responses["200"] == nil {
+		t.Errorf("expected /api/blocks/latest to be documented")
+	}
+	if doc.Components.SecuritySchemes["apiKeyHeader"].Name != APIKeyHeader {
+		t.Errorf("expected the API key header to be documented")
+	}
+	for name, s := range doc.Components.Schemas {
+		for prop, ps := range s.Properties {
+			if ps.Ref != "" && doc.Components.Schemas[strings.TrimPrefix(ps.Ref, "#/components/schemas/")] == nil {
+				t.Errorf("%s.%s refers to missing schema %s", name, prop, ps.Ref)
+			}
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/docs", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected docs page; got status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// TestOpenAPIDocumentsRoutes checks that every documented operation is
+// served by a REST handler rather than falling through to JSON-RPC
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	ts := newTestServer()
+	handler := ts.server.SetupRoutes()
+	samples := strings.NewReplacer("{address}", testAddress, "{id}", "job", "{hash}", testTxHash)
+
+	for path, item := range newOpenAPIDocument(false).Paths {
+		for method := range item {
+			method = strings.ToUpper(method)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(method, samples.Replace(path), strings.NewReader("{}"))
+			ts.mock.getBlockNumberFunc = func() (string, error) { return "0x10", nil }
+			ts.mock.getBlockByNumberFunc = func(n string, _ bool) (*blockchain.Block, error) { return testBlock(n), nil }
+			ts.mock.getProofFunc = func(string, []string, string) (*blockchain.AccountProof, error) {
+				return &blockchain.AccountProof{}, nil
+			}
+			ts.mock.getTransactionFunc = func(string) (json.RawMessage, error) { return nil, nil }
+			handler.ServeHTTP(rec, req)
+
+			unrouted := rec.Code == http.StatusNotFound && strings.TrimSpace(rec.Body.String()) == `{"error":"not found"}`
+			if unrouted || rec.Code == http.StatusMethodNotAllowed || strings.Contains(rec.Body.String(), `"jsonrpc"`) {
+				t.Errorf("%s %s is documented but not routed to a REST handler: %d %s", method, path, rec.Code, rec.Body)
+			}
+		}
+	}
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	requests := []struct {
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{method: "GET", target: "/api/v1/blocks?from=1&to=5&limit=2", want: http.StatusOK},
+		{method: "GET", target: "/api/v1/blocks?from=1&to=5&format=ndjson", want: http.StatusOK},
+		{method: "GET", target: "/api/v1/blocks/latest", want: http.StatusOK},
+		{method: "GET", target: "/api/v1/blocks/1?verify=true", want: http.StatusBadGateway},
+		{method: "GET", target: "/api/v1/blocks/0xffff", want: http.StatusNotFound},
+		{method: "GET", target: "/api/v1/blocks/16/transactions", want: http.StatusOK},
+		{method: "GET", target: "/api/v1/tx/" + testTxHash, want: http.StatusOK},
+		{method: "GET", target: "/api/v1/tx/0x" + strings.Repeat("00", 32), want: http.StatusNotFound},
+		{method: "GET", target: "/api/v1/accounts/" + testAddress, want: http.StatusOK},
+		{method: "GET", target: "/api/v1/exports", want: http.StatusOK},
+		{method: "DELETE", target: "/api/v1/exports/missing", want: http.StatusNotFound},
+		{method: "GET", target: "/api/blocks/latest", want: http.StatusOK},
+		{method: "GET", target: "/api/blocks?number=0x1", want: http.StatusOK},
+		{method: "GET", target: "/api/blocks?number=0xffff", want: http.StatusOK},
+		{method: "GET", target: "/api/blocks?number=0xdead", want: http.StatusBadGateway},
+		{method: "GET", target: "/api/blocks?from=1&to=5&limit=2", want: http.StatusOK},
+		{method: "GET", target: "/api/blocks?from=1&to=5&format=ndjson", want: http.StatusOK},
+		{method: "GET", target: "/api/accounts/" + testAddress + "?storage=0x0,0x1", want: http.StatusOK},
+		{method: "GET", target: "/api/accounts/" + testAddress + "/transactions", want: http.StatusServiceUnavailable},
+		{method: "GET", target: "/api/exports", want: http.StatusOK},
+		{method: "GET", target: "/api/exports/missing", want: http.StatusNotFound},
+		{method: "POST", target: "/api/exports", body: `{"from":"5","to":"2","format":"csv"}`, want: http.StatusBadRequest},
+		{method: "GET", target: "/healthz", want: http.StatusOK},
+		{method: "GET", target: "/readyz", want: http.StatusOK},
+		{method: "GET", target: "/status", want: http.StatusOK},
+	}
+
+	for _, tt := range requests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			_, handler := newValidatingServer(t)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d; got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "invalid block number",
+			target:  "/api/blocks?number=abc",
+			wantErr: `invalid query parameter "number": "abc" does not match ` + blockTagPattern,
+		},
+		{
+			name:    "invalid boolean",
+			target:  "/api/blocks?number=0x1&full=yes",
+			wantErr: `invalid query parameter "full": "yes" is not true or false`,
+		},
+		{
+			name:    "limit out of range",
+			target:  "/api/blocks?from=1&to=2&limit=5000",
+			wantErr: `invalid query parameter "limit": must be at most 1000`,
+		},
+		{
+			name:    "limit not a number",
+			target:  "/api/blocks?from=1&to=2&limit=ten",
+			wantErr: `invalid query parameter "limit": "ten" is not a number`,
+		},
+		{
+			name:    "invalid block id",
+			target:  "/api/v1/blocks/abc",
+			wantErr: `invalid path parameter "id": "abc" does not match ` + blockIDPattern,
+		},
+		{
+			name:    "missing range bound",
+			target:  "/api/v1/blocks?to=2",
+			wantErr: `query parameter "from" is required`,
+		},
+		{
+			name:    "invalid transaction hash",
+			target:  "/api/v1/tx/0x1234",
+			wantErr: `invalid path parameter "hash": "0x1234" does not match ` + txHashPattern.String(),
+		},
+		{
+			name:    "invalid address",
+			target:  "/api/accounts/0x1234",
+			wantErr: `invalid path parameter "address": "0x1234" does not match ` + addressPattern.String(),
+		},
+		{
+			name:    "missing body field",
+			method:  "POST",
+			target:  "/api/exports",
+			body:    `{"from":"1","format":"csv"}`,
+			wantErr: "invalid request body: to is required",
+		},
+		{
+			name:    "unsupported format",
+			method:  "POST",
+			target:  "/api/exports",
+			body:    `{"from":"1","to":"2","format":"xml"}`,
+			wantErr: "invalid request body: format: must be one of csv, ndjson, parquet",
+		},
+		{
+			name:    "wrong body field type",
+			method:  "POST",
+			target:  "/api/exports",
+			body:    `{"from":"1","to":"2","format":"csv","rotateBlocks":-1}`,
+			wantErr: "invalid request body: rotateBlocks: must be at least 0",
+		},
+		{
+			name:    "body not JSON",
+			method:  "POST",
+			target:  "/api/exports",
+			body:    `from=1`,
+			wantErr: "request body is not valid JSON",
+		},
+		{
+			name:    "missing body",
+			method:  "POST",
+			target:  "/api/exports",
+			wantErr: "request body is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler := newValidatingServer(t)
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, tt.target, strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400; got %d: %s", rec.Code, rec.Body)
+			}
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Error != tt.wantErr {
+				t.Errorf("expected error %q; got %q", tt.wantErr, resp.Error)
+			}
+		})
+	}
+}
+
+func TestRequestValidationIsOptional(t *testing.T) {
+	ts := newTestServer()
+	called := false
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		called = true
+		return nil, &blockchain.RPCError{Code: -32602, Message: "invalid block number"}
+	}
+
+	rec := httptest.NewRecorder()
+	ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/blocks?number=abc", nil))
+	if !called || rec.Code != http.StatusBadRequest {
+		t.Errorf("expected the request to reach the handler; got status %d", rec.Code)
+	}
+}
+
+func TestInvalidResponsesAreReported(t *testing.T) {
+	ts := newTestServer()
+	var reported error
+	ts.server.validation = ValidationConfig{
+		Responses:         true,
+		OnInvalidResponse: func(r *http.Request, err error) { reported = err },
+	}
+	// A handler encoding the block number as a number does not match the
+	// document
+	handler := ts.server.validateREST(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, http.StatusOK, map[string]any{"blockNumber": 16})
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/blocks/latest", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"blockNumber":16}` {
+		t.Errorf("expected the response to be sent unchanged; got %d %s", rec.Code, rec.Body)
+	}
+	want := "GET /api/blocks/latest: response body: blockNumber: must be a string"
+	if reported == nil || reported.Error() != want {
+		t.Errorf("expected invalid response to be reported as %q; got %v", want, reported)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	g := newSchemaGenerator()
+	doc := &openAPIDocument{}
+	blockSchema := g.schemaFor(reflect.TypeFor[BlockResponse]())
+	doc.Components.Schemas = g.schemas
+
+	tests := []struct {
+		value   string
+		wantErr error
+	}{
+		{value: `{"block": null}`},
+		{value: `{"block": {"number": "0x1", "hash": "0x2", "parentHash": "0x3", "nonce": "0x0", "timestamp": "0x4", "transactions": [], "transactionCount": 0}, "verified": true}`},
+		{value: `{}`, wantErr: errors.New("block is required")},
+		{value: `{"block": {"number": 1, "hash": "0x2", "parentHash": "0x3", "nonce": "0x0", "timestamp": "0x4", "transactions": [], "transactionCount": 0}}`, wantErr: errors.New("block.number: must be a string")},
+		{value: `{"block": {"number": "0x1"}}`, wantErr: errors.New("block: hash is required")},
+		{value: `{"block": null, "verified": "yes"}`, wantErr: errors.New("verified: must be a boolean")},
+		{value: `[]`, wantErr: errors.New("must be an object")},
+	}
+	for _, tt := range tests {
+		value, err := decodeJSONValue([]byte(tt.value))
+		if err != nil {
+			t.Fatalf("invalid test value %s: %v", tt.value, err)
+		}
+		err = doc.validate(blockSchema, value, "")
+		if (err == nil) != (tt.wantErr == nil) || (err != nil && !strings.HasPrefix(err.Error(), tt.wantErr.Error())) {
+			t.Errorf("%s: expected error %v; got %v", tt.value, tt.wantErr, err)
+		}
+	}
+}
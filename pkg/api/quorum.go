@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// QuorumHeader requests that the upstream reads made for a request be served
+// by a quorum of upstreams, written as "M" for M agreeing upstreams or "M/N"
+// for M of the first N. It overrides the quorum configured for each method.
+const QuorumHeader = "X-Quorum"
+
+// upstreamCount returns the number of upstreams the server's client reads from
+func (s *Server) upstreamCount() int {
+	if set, ok := s.client.(interface{ Clients() []*blockchain.Client }); ok {
+		return len(set.Clients())
+	}
+	return 1
+}
+
+// parseRequestQuorum parses the X-Quorum header of r, rejecting quorums that
+// need more upstreams than are configured
+func (s *Server) parseRequestQuorum(r *http.Request) (blockchain.Quorum, error) {
+	q, err := blockchain.ParseQuorum(r.Header.Get(QuorumHeader))
+	if err != nil {
+		return blockchain.Quorum{}, err
+	}
+	if n := s.upstreamCount(); q.Agree > n || q.Size > n {
+		return blockchain.Quorum{}, fmt.Errorf("invalid quorum %q: only %d upstreams are configured", q, n)
+	}
+	return q, nil
+}
+
+// quorumFanout returns the number of upstreams each read made for r is sent
+// to, which multiplies the compute units it is charged
+func (s *Server) quorumFanout(r *http.Request) int {
+	if r.Header.Get(QuorumHeader) == "" {
+		return 1
+	}
+	q, err := s.parseRequestQuorum(r)
+	if err != nil || q.Agree <= 1 {
+		// Invalid quorums are rejected by requestQuorum, and a quorum of
+		// one is served by plain failover
+		return 1
+	}
+	if q.Size > 0 {
+		return q.Size
+	}
+	return s.upstreamCount()
+}
+
+// requestQuorum applies the quorum in the X-Quorum header to the upstream
+// reads made while handling the request
+func (s *Server) requestQuorum(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(QuorumHeader) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q, err := s.parseRequestQuorum(r)
+		if err != nil {
+			if isRPCPath(r.URL.Path) {
+				writeJSONResponse(w, http.StatusBadRequest, RPCResponse{
+					JSONRPC: "2.0",
+					Error:   &RPCError{Code: -32600, Message: err.Error()},
+				})
+				return
+			}
+			writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		routed := r.WithContext(blockchain.WithQuorum(r.Context(), q))
+		next.ServeHTTP(w, routed)
+		setRoute(r.Context(), routed.Pattern)
+	})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/logging"
+	"blockchain-client/pkg/ratelimit"
+)
+
+// newHeadServer is an upstream answering every call with head
+func newHeadServer(t *testing.T, head string) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req blockchain.RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(blockchain.RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"` + head + `"`)})
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+func TestQuorumHeader(t *testing.T) {
+	upstreams := blockchain.NewUpstreamSet([]string{newHeadServer(t, "0x10"), newHeadServer(t, "0xf"), newHeadServer(t, "0x10")})
+	handler := (&Server{client: upstreams}).SetupRoutes()
+
+	var logs bytes.Buffer
+	logger, _ := logging.New(&logs, logging.Config{Format: logging.FormatJSON})
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	tests := []struct {
+		name       string
+		quorum     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"failover", "", http.StatusOK, `"0x10"`},
+		{"quorum reached", "2", http.StatusOK, `"0x10"`},
+		{"quorum of first upstreams", "2/2", http.StatusBadGateway, "no quorum for eth_blockNumber"},
+		{"invalid", "most", http.StatusBadRequest, "invalid quorum"},
+		{"more than configured", "4", http.StatusBadRequest, "only 3 upstreams are configured"},
+		{"size more than configured", "2/4", http.StatusBadRequest, "only 3 upstreams are configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest("GET", "/api/blocks/latest", nil)
+			if tt.quorum != "" {
+				req.Header.Set(QuorumHeader, tt.quorum)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("expected status %d with %s, got %d: %s", tt.wantStatus, tt.wantBody, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				return
+			}
+			var entry map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to parse access log: %v", err)
+			}
+			if entry["route"] != "/api/blocks/latest" {
+				t.Errorf("expected the matched route to be logged, got %v", entry)
+			}
+		})
+	}
+
+	body := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set(QuorumHeader, "0")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var resp RPCResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != -32600 {
+		t.Errorf("expected an invalid request error, got %d: %+v", w.Code, resp)
+	}
+}
+
+func TestQuorumCharged(t *testing.T) {
+	upstreams := blockchain.NewUpstreamSet([]string{newHeadServer(t, "0x10"), newHeadServer(t, "0x10"), newHeadServer(t, "0x10")})
+	s := &Server{client: upstreams}
+
+	tests := []struct {
+		quorum string
+		want   int
+	}{
+		{"", 10},
+		{"1", 10},
+		{"2", 30},
+		{"2/2", 20},
+		{"4", 10},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set(QuorumHeader, tt.quorum)
+		if got := s.requestCost(req, "eth_blockNumber"); got != tt.want {
+			t.Errorf("quorum %q: expected %d compute units, got %d", tt.quorum, tt.want, got)
+		}
+	}
+
+	WithRateLimit(RateLimitConfig{Config: ratelimit.Config{Rate: 1, Burst: 40}})(s)
+	handler := s.SetupRoutes()
+	post := func(quorum string) int {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`))
+		req.Header.Set(QuorumHeader, quorum)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post("2"); code != http.StatusOK {
+		t.Fatalf("expected the first quorum read to succeed, got %d", code)
+	}
+	if code := post("2/2"); code != http.StatusTooManyRequests {
+		t.Errorf("expected a second quorum read to exceed the budget, got %d", code)
+	}
+	if code := post("1"); code != http.StatusOK {
+		t.Errorf("expected a single read to fit the remaining budget, got %d", code)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"blockchain-client/pkg/ratelimit"
+)
+
+// batchMethod is recorded as the JSON-RPC method of batch requests
+const batchMethod = "batch"
+
+// RateLimitConfig represents the compute unit limits applied to each client
+type RateLimitConfig struct {
+	ratelimit.Config
+	// TrustForwardedFor identifies clients by the address the load balancer
+	// appends to X-Forwarded-For instead of the connection's address
+	TrustForwardedFor bool
+}
+
+// WithRateLimit limits each client, identified by API key when
+// authenticated and by IP address otherwise, to a compute unit budget
+func WithRateLimit(cfg RateLimitConfig) Option {
+	return WithLimiter(ratelimit.NewLimiter(cfg.Config), cfg.TrustForwardedFor)
+}
+
+// WithLimiter charges clients against limiter, which may be shared by the
+// servers of several chains so that a client has one budget across them
+func WithLimiter(limiter *ratelimit.Limiter, trustForwardedFor bool) Option {
+	return func(s *Server) {
+		s.limiter = limiter
+		s.trustForwardedFor = trustForwardedFor
+	}
+}
+
+// WithComputeUnits overrides the compute units charged for JSON-RPC methods
+// and REST routes by the rate limit and API key quotas
+func WithComputeUnits(costs map[string]int) Option {
+	return func(s *Server) {
+		s.SetComputeUnits(costs)
+	}
+}
+
+// SetComputeUnits replaces the compute unit overrides while the server runs
+func (s *Server) SetComputeUnits(costs map[string]int) {
+	s.costs.Store(&costs)
+}
+
+// SetRateLimit changes the per-client limits while the server runs. It has no
+// effect unless the server was created with WithRateLimit or WithLimiter.
+func (s *Server) SetRateLimit(cfg ratelimit.Config) {
+	if s.limiter != nil {
+		s.limiter.SetConfig(cfg)
+	}
+}
+
+// cost returns the compute units charged for a JSON-RPC method or REST route
+func (s *Server) cost(operation string) int {
+	if costs := s.costs.Load(); costs != nil {
+		if cost, ok := (*costs)[operation]; ok {
+			return cost
+		}
+	}
+	return ratelimit.ComputeUnits(operation)
+}
+
+// requestCost returns the compute units charged for operation when made by r,
+// once for each upstream its X-Quorum header fans reads out to
+func (s *Server) requestCost(r *http.Request, operation string) int {
+	return s.cost(operation) * s.quorumFanout(r)
+}
+
+// restCost returns the compute units charged for a REST request. Block ranges
+// are charged per block in the page or stream they return.
+func (s *Server) restCost(r *http.Request) int {
+	cost := s.requestCost(r, restOperation(r.URL.Path))
+	if isBlockRange(r) {
+		if br, err := parseBlockRange(r); err == nil {
+			cost *= int(br.size())
+		}
+	}
+	return cost
+}
+
+// clientIP returns the address of the client that sent r
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustForwardedFor {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientKey identifies the client whose budget r is charged to
+func (s *Server) clientKey(r *http.Request) string {
+	if key := apiKey(r.Context()); key != "" {
+		return "key:" + key
+	}
+	return "ip:" + s.clientIP(r)
+}
+
+// limitRPC charges cost compute units to the client. It returns the HTTP
+// status and JSON-RPC error to respond with when the client is over its limit.
+func (s *Server) limitRPC(w http.ResponseWriter, r *http.Request, cost int) (int, *RPCError) {
+	if s.limiter == nil {
+		return http.StatusOK, nil
+	}
+	ok, wait := s.limiter.Allow(s.clientKey(r), cost)
+	if ok {
+		return http.StatusOK, nil
+	}
+	setRetryAfter(w, wait)
+	return http.StatusTooManyRequests, &RPCError{Code: rpcCodeLimitExceeded, Message: "compute unit rate limit exceeded"}
+}
+
+// limitREST charges REST requests the compute units of their route, per
+// block for block ranges. JSON-RPC requests are charged per method by
+// HandleJSONRPC.
+func (s *Server) limitREST(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if openPath(r.URL.Path) || isRPCPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, wait := s.limiter.Allow(s.clientKey(r), s.restCost(r))
+		if !ok {
+			setRetryAfter(w, wait)
+			writeJSONResponse(w, http.StatusTooManyRequests, ErrorResponse{Error: "compute unit rate limit exceeded"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/ratelimit"
+)
+
+func TestRateLimitRPC(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+	WithRateLimit(RateLimitConfig{Config: ratelimit.Config{Rate: 1, Burst: 40}})(ts.server)
+	WithComputeUnits(map[string]int{"eth_blockNumber": 15})(ts.server)
+	handler := ts.server.SetupRoutes()
+
+	post := func(body, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	single := `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`
+	if rec := post(single, "10.0.0.1:1000"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first call to succeed, got %d", rec.Code)
+	}
+
+	// The batch costs 30 units against the 25 left
+	batch := `[{"jsonrpc":"2.0","method":"eth_blockNumber","id":2},{"jsonrpc":"2.0","method":"eth_blockNumber","id":3}]`
+	rec := post(batch, "10.0.0.1:1001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "5" {
+		t.Fatalf("expected 429 with Retry-After 5, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var responses []RPCResponse
+	if err := json.NewDecoder(rec.Body).Decode(&responses); err != nil {
+		t.Fatalf("failed to decode batch response: %v", err)
+	}
+	if len(responses) != 2 || string(responses[1].ID) != "3" || responses[1].Error == nil || responses[1].Error.Code != rpcCodeLimitExceeded {
+		t.Errorf("expected -32005 for every call in the batch, got %+v", responses)
+	}
+
+	if rec := post(single, "10.0.0.1:1002"); rec.Code != http.StatusOK {
+		t.Errorf("expected a cheaper call to fit the remaining budget, got %d", rec.Code)
+	}
+	if rec := post(batch, "10.0.0.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("expected another client to have its own budget, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitREST(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+	WithRateLimit(RateLimitConfig{Config: ratelimit.Config{Rate: 10, Burst: 10}, TrustForwardedFor: true})(ts.server)
+	handler := ts.server.SetupRoutes()
+
+	get := func(forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/blocks/latest", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("203.0.113.9, 198.51.100.1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first call to succeed, got %d", rec.Code)
+	}
+	rec := get("203.0.113.7, 198.51.100.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected 429 with Retry-After 1, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := get("198.51.100.2"); rec.Code != http.StatusOK {
+		t.Errorf("expected a different client address to pass, got %d", rec.Code)
+	}
+
+	// Open endpoints are never limited
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected /healthz to pass, got %d", rec.Code)
+		}
+	}
+}
+
+func TestRESTCostPerBlock(t *testing.T) {
+	s := &Server{}
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/api/v1/blocks/1", 16},
+		{"/api/v1/blocks/1?from=0&to=999", 16},
+		{"/api/blocks?number=0x1", 16},
+		{"/api/v1/blocks?from=1&to=5", 5 * 16},
+		{"/api/blocks?from=1&to=5", 5 * 16},
+		{"/api/v1/blocks?from=0&to=5000", defaultRangeLimit * 16},
+		{"/api/v1/blocks?from=0&to=5000&limit=1000", 1000 * 16},
+		{"/api/v1/blocks?from=0&to=5000&cursor=0x1380", 9 * 16},
+		{"/api/v1/blocks?from=0&to=99999&format=ndjson", 100000 * 16},
+		{"/api/v1/blocks?from=0&to=100000&format=ndjson", 16},
+		{"/api/v1/blocks?from=5&to=1", 16},
+		{"/api/v1/tx/0x1", 15},
+	}
+	for _, tt := range tests {
+		if got := s.restCost(httptest.NewRequest("GET", tt.target, nil)); got != tt.want {
+			t.Errorf("%s: expected %d compute units, got %d", tt.target, tt.want, got)
+		}
+	}
+
+	WithComputeUnits(map[string]int{"/api/blocks": 2})(s)
+	if got := s.restCost(httptest.NewRequest("GET", "/api/v1/blocks?from=1&to=5", nil)); got != 10 {
+		t.Errorf("expected the overridden unit cost per block, got %d", got)
+	}
+}
+
+func TestRateLimitBlockRange(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlocksByNumberFunc = func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+		blocks := make([]*blockchain.Block, len(blockNumbers))
+		for i, number := range blockNumbers {
+			blocks[i] = &blockchain.Block{Number: number}
+		}
+		return blocks, nil
+	}
+	WithRateLimit(RateLimitConfig{Config: ratelimit.Config{Rate: 1, Burst: 100}})(ts.server)
+	handler := ts.server.SetupRoutes()
+
+	get := func(target string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec.Code
+	}
+
+	// Five blocks cost 80 units, leaving too few for another five
+	if code := get("/api/v1/blocks?from=1&to=5"); code != http.StatusOK {
+		t.Fatalf("expected first range to succeed, got %d", code)
+	}
+	if code := get("/api/v1/blocks?from=6&to=10&format=ndjson"); code != http.StatusTooManyRequests {
+		t.Errorf("expected second range to be limited, got %d", code)
+	}
+	if code := get("/api/v1/blocks?from=6&to=6"); code != http.StatusOK {
+		t.Errorf("expected a single block to fit the remaining budget, got %d", code)
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig represents the HTTP server limits and shutdown behaviour. Zero
+// values select the defaults below.
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout does not apply to NDJSON streams, which clear their write
+	// deadline
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// MaxBodyBytes limits request bodies; larger requests fail with 413
+	MaxBodyBytes int64
+	// DrainDelay is how long the server keeps serving after readiness turns
+	// to not ready, giving load balancers time to stop routing to it
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	ShutdownTimeout time.Duration
+}
+
+// Default HTTP server limits
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 1 << 20
+	DefaultMaxBodyBytes      = 1 << 20
+	DefaultShutdownTimeout   = 20 * time.Second
+)
+
+// WithHTTPConfig sets the HTTP server limits and shutdown behaviour
+func WithHTTPConfig(cfg HTTPConfig) Option {
+	return func(s *Server) {
+		s.httpConfig = cfg
+	}
+}
+
+// withDefaults returns cfg with zero values replaced by the defaults
+func (cfg HTTPConfig) withDefaults() HTTPConfig {
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = DefaultReadTimeout
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxHeaderBytes <= 0 {
+		cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	return cfg
+}
+
+// limitBody caps the size of request bodies read by next
+func limitBody(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isBodyTooLarge reports whether err came from exceeding the body limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// Draining reports whether the server has begun shutting down
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// Run serves the API on addr until ctx is cancelled, then shuts down
+// gracefully: readiness turns to not ready, the server keeps serving for the
+// drain delay, stops accepting connections, and waits up to the shutdown
+// timeout for in-flight requests and export jobs before closing the rest.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Run but accepts connections on ln, serving HTTPS when TLS
+// is configured
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	cfg := s.httpConfig.withDefaults()
+
+	srv := &http.Server{
+		Handler:           s.SetupRoutes(),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	if s.tlsConfig.CertFile != "" {
+		reloader, err := newCertReloader(s.tlsConfig)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		srv.TLSConfig = reloader.serverConfig()
+	}
+
+	var redirect *http.Server
+	if srv.TLSConfig != nil && s.tlsConfig.RedirectAddr != "" {
+		_, httpsPort, err := net.SplitHostPort(ln.Addr().String())
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		redirectLn, err := net.Listen("tcp", s.tlsConfig.RedirectAddr)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		redirect = &http.Server{
+			Handler:           redirectHandler(httpsPort),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			ErrorLog:          srv.ErrorLog,
+		}
+		go func() {
+			slog.Info("redirecting HTTP to HTTPS", "addr", redirectLn.Addr().String())
+			if err := redirect.Serve(redirectLn); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP redirect server failed", "error", err)
+			}
+		}()
+		defer redirect.Close()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			slog.Info("starting API server", "addr", ln.Addr().String(), "tls", true, "mtls", s.tlsConfig.ClientCAFile != "")
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		slog.Info("starting API server", "addr", ln.Addr().String())
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	for _, chain := range s.chains {
+		chain.draining.Store(true)
+	}
+	slog.Info("shutting down API server", "drain_delay", cfg.DrainDelay, "timeout", cfg.ShutdownTimeout)
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if redirect != nil {
+		redirect.Shutdown(shutdownCtx)
+	}
+	err := srv.Shutdown(shutdownCtx)
+	s.exports.shutdown(shutdownCtx)
+	for _, chain := range s.chains {
+		chain.exports.shutdown(shutdownCtx)
+	}
+	if err != nil {
+		slog.Warn("shutdown deadline exceeded, closing remaining connections", "error", err)
+		srv.Close()
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("API server stopped")
+	return nil
+}
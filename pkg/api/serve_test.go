@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves ts on a local listener until the returned cancel
+// function is called; the returned channel receives Serve's result
+func startServer(t *testing.T, ts *testServer) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ts.server.Serve(ctx, ln) }()
+
+	return "http://" + ln.Addr().String(), cancel, done
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	ts := newTestServer()
+	ts.server.httpConfig = HTTPConfig{DrainDelay: 100 * time.Millisecond, ShutdownTimeout: 5 * time.Second}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		close(started)
+		<-release
+		return "0x10", nil
+	}
+
+	url, cancel, done := startServer(t, ts)
+	defer cancel()
+
+	inflight := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/api/blocks/latest")
+		if err != nil {
+			t.Errorf("in-flight request failed: %v", err)
+			inflight <- nil
+			return
+		}
+		inflight <- resp
+	}()
+	<-started
+
+	cancel()
+
+	// Readiness fails during the drain delay while requests are still served
+	time.Sleep(20 * time.Millisecond)
+	if !ts.server.Draining() {
+		t.Errorf("expected server to be draining")
+	}
+	rec := httptest.NewRecorder()
+	ts.server.HandleReadyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), CheckShutdown) {
+		t.Errorf("expected not ready during shutdown; got %d %s", rec.Code, rec.Body.String())
+	}
+
+	close(release)
+	if resp := <-inflight; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("expected in-flight request to complete")
+	} else {
+		resp.Body.Close()
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not shut down")
+	}
+
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Errorf("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	ts := newTestServer()
+	ts.server.httpConfig = HTTPConfig{ShutdownTimeout: 50 * time.Millisecond}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		close(started)
+		<-release
+		return "0x10", nil
+	}
+
+	url, cancel, done := startServer(t, ts)
+	go http.Get(url + "/api/blocks/latest")
+	<-started
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not give up on the stuck request")
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	ts := newTestServer()
+	ts.server.httpConfig = HTTPConfig{MaxBodyBytes: 64}
+	ts.server.exportDir = t.TempDir()
+	handler := ts.server.SetupRoutes()
+
+	padding := strings.Repeat(" ", 100)
+	tests := map[string]string{
+		"/":            `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}` + padding,
+		"/api/exports": `{"from":"1",` + padding + `"to":"2","format":"csv"}`,
+	}
+	for path, body := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", path, bytes.NewReader([]byte(body))))
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413 for %s; got %d", path, rec.Code)
+		}
+		// JSON-RPC requests get a JSON-RPC error, REST requests an error body
+		var resp map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp["error"] == nil {
+			t.Errorf("expected JSON error for %s; got %v", path, err)
+		}
+	}
+
+	ts.mock.getBlockNumberFunc = func() (string, error) { return "0x1", nil }
+	rec := httptest.NewRecorder()
+	small := fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":%d}`, 1)
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader([]byte(small))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 for small body; got %d", rec.Code)
+	}
+}
@@ -1,30 +1,178 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blockchain-client/pkg/auth"
 	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/metrics"
+	"blockchain-client/pkg/ratelimit"
+	"blockchain-client/pkg/store"
 )
 
 // BlockchainClient interface for blockchain operations
 type BlockchainClient interface {
-	GetBlockNumber() (string, error)
-	GetBlockByNumber(blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	GetBlockNumber(ctx context.Context) (string, error)
+	GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	GetBlocksByNumber(ctx context.Context, blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error)
+	GetChainID(ctx context.Context) (string, error)
+	GetProof(ctx context.Context, address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error)
+	GetTransactionByHash(ctx context.Context, hash string) (json.RawMessage, error)
+}
+
+// BlockStore interface for reading ingested blocks, transactions and address
+// activity
+type BlockStore interface {
+	GetBlock(number uint64, fullTransactions bool) (*blockchain.Block, error)
+	GetTransaction(hash string) (json.RawMessage, error)
+	GetAddressActivity(q store.ActivityQuery) (*store.ActivityPage, error)
 }
 
 // Server represents the API server
 type Server struct {
 	client BlockchainClient
+	store  BlockStore
+
+	rangeWorkers   int
+	rangeBatchSize int
+
+	exportDir string
+	exports   exportJobs
+
+	metrics *metrics.Metrics
+	keys    *auth.Keyring
+
+	limiter           *ratelimit.Limiter
+	trustForwardedFor bool
+	costs             atomic.Pointer[map[string]int]
+
+	methods atomic.Pointer[map[string]bool]
+
+	health        HealthConfig
+	readiness     readinessCache
+	upstreamStats *blockchain.StatsRecorder
+	follower      *blockchain.HeadFollower
+	started       time.Time
+
+	chainName string
+	chainID   uint64
+	chains    []*Server
+
+	httpConfig HTTPConfig
+	tlsConfig  TLSConfig
+	draining   atomic.Bool
+
+	customMethods map[string]RPCMethod
+	rpcMiddleware []RPCMiddleware
+	rpcOnce       sync.Once
+	rpcMethods    map[string]RPCHandler
+	rpcNotFound   RPCHandler
+	rpcCache      *resultCache
+
+	validation  ValidationConfig
+	openAPIOnce sync.Once
+	openAPIDoc  *openAPIDocument
+}
+
+// Option configures optional Server behaviour
+type Option func(*Server)
+
+// WithClient makes the server use client instead of creating its own
+func WithClient(client BlockchainClient) Option {
+	return func(s *Server) {
+		s.client = client
+	}
+}
+
+// WithStore makes the server serve ingested blocks from store before falling
+// back to the upstream RPC endpoint
+func WithStore(store BlockStore) Option {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithMetrics enables request instrumentation and the /metrics endpoint
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithMethods limits the JSON-RPC endpoint to methods; an empty list enables
+// every supported method
+func WithMethods(methods []string) Option {
+	return func(s *Server) {
+		s.SetMethods(methods)
+	}
+}
+
+// SetMethods replaces the enabled JSON-RPC methods while the server runs
+func (s *Server) SetMethods(methods []string) {
+	var enabled map[string]bool
+	if len(methods) > 0 {
+		enabled = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			enabled[method] = true
+		}
+	}
+	s.methods.Store(&enabled)
+}
+
+// methodEnabled reports whether the JSON-RPC endpoint serves method
+func (s *Server) methodEnabled(method string) bool {
+	enabled := s.methods.Load()
+	return enabled == nil || *enabled == nil || (*enabled)[method]
+}
+
+// observeCache records a store lookup when metrics are enabled
+func (s *Server) observeCache(hit bool) {
+	if s.metrics != nil {
+		s.metrics.ObserveCache(metrics.CacheStore, hit)
+	}
 }
 
 // NewServer creates a new API server
-func NewServer(rpcURL string) *Server {
-	return &Server{
-		client: blockchain.NewClient(rpcURL),
+func NewServer(rpcURL string, opts ...Option) *Server {
+	s := &Server{started: time.Now()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.client == nil {
+		s.client = blockchain.NewClient(rpcURL)
+	}
+	return s
+}
+
+// getBlock returns a block from the store if it has been ingested, otherwise
+// from the upstream RPC endpoint
+func (s *Server) getBlock(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+	if s.store != nil {
+		if number, err := blockchain.ParseQuantity(blockNumber); err == nil {
+			block, err := s.store.GetBlock(number, fullTransactions)
+			s.observeCache(err == nil)
+			if err == nil {
+				return block, nil
+			}
+			if !errors.Is(err, store.ErrNotFound) {
+				slog.ErrorContext(ctx, "failed to read block from store", "block", number, "error", err)
+			}
+		}
 	}
+
+	return s.client.GetBlockByNumber(ctx, blockNumber, fullTransactions)
 }
 
 // BlockNumberResponse represents the response for block number endpoint
@@ -35,19 +183,38 @@ type BlockNumberResponse struct {
 // BlockResponse represents the response for block details endpoint
 type BlockResponse struct {
 	Block *blockchain.Block `json:"block"`
+	// Verified is set when the block hash was checked against its header
+	Verified bool `json:"verified,omitempty"`
+}
+
+// BlockTransactionsResponse represents the response for the block
+// transactions endpoint
+type BlockTransactionsResponse struct {
+	BlockNumber string `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	// Transactions are the block's full transaction objects
+	Transactions json.RawMessage `json:"transactions"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code and Data are the upstream's JSON-RPC error code and data, when
+	// the upstream rejected the request
+	Code int             `json:"code,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 // RPCRequest represents a JSON-RPC request
 type RPCRequest struct {
-	JSONRPC string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params,omitempty"`
-	ID      int           `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	// Params is either an array of positional params or an object of named
+	// params
+	Params json.RawMessage `json:"params,omitempty"`
+	// ID is a string, number or null and is echoed verbatim in the response.
+	// Requests without an ID are notifications.
+	ID json.RawMessage `json:"id,omitempty"`
 }
 
 // RPCResponse represents a JSON-RPC response
@@ -55,13 +222,24 @@ type RPCResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *RPCError       `json:"error,omitempty"`
-	ID      int             `json:"id"`
+	// ID is null when the request's ID could not be determined
+	ID json.RawMessage `json:"id"`
 }
 
 // RPCError represents a JSON-RPC error
 type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+
+	// status is the HTTP status of a single call rejected with the error
+	status int
+}
+
+// Error returns the error message, so that method handlers can return an
+// *RPCError to the caller
+func (e *RPCError) Error() string {
+	return e.Message
 }
 
 // writeJSONResponse writes a JSON response
@@ -70,33 +248,39 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	w.WriteHeader(statusCode)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("failed to encode response: %v", err)
+		slog.Error("failed to encode response", "error", err)
 	}
 }
 
-// HandleGetBlockNumber handles the /blocks/latest endpoint
+// HandleGetBlockNumber handles the deprecated /api/blocks/latest endpoint
 func (s *Server) HandleGetBlockNumber(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
 		return
 	}
 
-	blockNumber, err := s.client.GetBlockNumber()
+	blockNumber, err := s.client.GetBlockNumber(r.Context())
 	if err != nil {
-		writeJSONResponse(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		upstreamFailed(w, err)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, BlockNumberResponse{BlockNumber: blockNumber})
 }
 
-// HandleGetBlockByNumber handles the /blocks/{blockNumber} endpoint
+// HandleGetBlockByNumber handles the deprecated /api/blocks?number=
+// endpoint, and passes /api/blocks?from=&to= to HandleGetBlockRange
 func (s *Server) HandleGetBlockByNumber(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
 		return
 	}
 
+	if r.URL.Query().Has("from") || r.URL.Query().Has("to") {
+		s.HandleGetBlockRange(w, r)
+		return
+	}
+
 	blockNumber := r.URL.Query().Get("number")
 	if blockNumber == "" {
 		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "block number is required"})
@@ -105,16 +289,183 @@ func (s *Server) HandleGetBlockByNumber(w http.ResponseWriter, r *http.Request)
 
 	fullTx := r.URL.Query().Get("full") == "true"
 
-	block, err := s.client.GetBlockByNumber(blockNumber, fullTx)
+	if r.URL.Query().Get("verify") == "true" {
+		s.handleGetVerifiedBlock(w, r, blockNumber, fullTx)
+		return
+	}
+
+	block, err := s.getBlock(r.Context(), blockNumber, fullTx)
 	if err != nil {
-		writeJSONResponse(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		upstreamFailed(w, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, BlockResponse{Block: block})
+}
+
+// blockTags are the block tags accepted in place of a block number
+var blockTags = []string{"latest", "earliest", "pending", "safe", "finalized"}
+
+// parseBlockID parses the {id} of a block route, a block height in decimal or
+// hex or a block tag, into the block number passed to the upstream
+func parseBlockID(id string) (string, error) {
+	if slices.Contains(blockTags, id) {
+		return id, nil
+	}
+	number, err := parseBlockParam(id)
+	if err != nil {
+		return "", err
+	}
+	return blockchain.EncodeQuantity(number), nil
+}
+
+// HandleGetBlock handles the /api/v1/blocks/{id} endpoint
+func (s *Server) HandleGetBlock(w http.ResponseWriter, r *http.Request) {
+	blockNumber, err := parseBlockID(r.PathValue("id"))
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid block id"})
+		return
+	}
+
+	fullTx := r.URL.Query().Get("full") == "true"
+
+	if r.URL.Query().Get("verify") == "true" {
+		s.handleGetVerifiedBlock(w, r, blockNumber, fullTx)
+		return
+	}
+
+	block, err := s.getBlock(r.Context(), blockNumber, fullTx)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if !blockFound(block) {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "block not found"})
 		return
 	}
 
 	writeJSONResponse(w, http.StatusOK, BlockResponse{Block: block})
 }
 
-// HandleJSONRPC handles JSON-RPC requests directly
+// HandleGetBlockTransactions handles the /api/v1/blocks/{id}/transactions
+// endpoint, returning the block's full transaction objects
+func (s *Server) HandleGetBlockTransactions(w http.ResponseWriter, r *http.Request) {
+	blockNumber, err := parseBlockID(r.PathValue("id"))
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid block id"})
+		return
+	}
+
+	block, err := s.getBlock(r.Context(), blockNumber, true)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if !blockFound(block) {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "block not found"})
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, BlockTransactionsResponse{
+		BlockNumber:  block.Number,
+		BlockHash:    block.Hash,
+		Transactions: block.Transactions,
+	})
+}
+
+// blockFound reports whether the upstream knew the block. A null result, such
+// as for a block past the head, decodes to a block without a number.
+func blockFound(block *blockchain.Block) bool {
+	return block != nil && block.Number != ""
+}
+
+// handleGetVerifiedBlock fetches a block from the upstream, bypassing the
+// store, and serves it only if its hash matches the header fields
+func (s *Server) handleGetVerifiedBlock(w http.ResponseWriter, r *http.Request, blockNumber string, fullTx bool) {
+	block, err := s.client.GetBlockByNumber(r.Context(), blockNumber, fullTx)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if block == nil || block.Hash == "" {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "block not found"})
+		return
+	}
+	if err := block.VerifyHash(); err != nil {
+		s.verificationFailed(w, r, "block", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, BlockResponse{Block: block, Verified: true})
+}
+
+// verificationFailed responds with 502 when upstream data fails verification
+func (s *Server) verificationFailed(w http.ResponseWriter, r *http.Request, what string, err error) {
+	slog.WarnContext(r.Context(), "upstream data failed verification", "data", what, "error", err)
+	writeJSONResponse(w, http.StatusBadGateway, ErrorResponse{Error: what + " verification failed: " + err.Error()})
+}
+
+// parseBlockParam parses a block height given in decimal or 0x-prefixed hex
+func parseBlockParam(value string) (uint64, error) {
+	if strings.HasPrefix(value, "0x") {
+		return blockchain.ParseQuantity(value)
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// HandleGetAccountTransactions handles the
+// /api/v1/accounts/{address}/transactions endpoint
+func (s *Server) HandleGetAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeJSONResponse(w, http.StatusServiceUnavailable, ErrorResponse{Error: "address index is not enabled"})
+		return
+	}
+
+	query := r.URL.Query()
+	q := store.ActivityQuery{
+		Address: r.PathValue("address"),
+		Cursor:  query.Get("cursor"),
+	}
+
+	if direction := query.Get("direction"); direction != "" && direction != "all" {
+		q.Roles = strings.Split(direction, ",")
+	}
+
+	var err error
+	if v := query.Get("fromBlock"); v != "" {
+		if q.FromBlock, err = parseBlockParam(v); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid fromBlock"})
+			return
+		}
+	}
+	if v := query.Get("toBlock"); v != "" {
+		if q.ToBlock, err = parseBlockParam(v); err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid toBlock"})
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 {
+			writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid limit"})
+			return
+		}
+	}
+
+	page, err := s.store.GetAddressActivity(q)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidAddress) || errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidRole) {
+			writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSONResponse(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, page)
+}
+
+// HandleJSONRPC handles JSON-RPC 2.0 requests and batches. Notifications,
+// requests without an id, are executed but receive no response.
 func (s *Server) HandleJSONRPC(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
@@ -124,137 +475,193 @@ func (s *Server) HandleJSONRPC(w http.ResponseWriter, r *http.Request) {
 	// Read request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+		if isBodyTooLarge(err) {
+			writeJSONResponse(w, http.StatusRequestEntityTooLarge, rpcErrorResponse(nil, -32600, "request body too large"))
+			return
+		}
+		writeJSONResponse(w, http.StatusBadRequest, rpcErrorResponse(nil, -32600, "failed to read request body"))
 		return
 	}
 	defer r.Body.Close()
 
-	// Parse JSON-RPC request
-	var request RPCRequest
-	if err := json.Unmarshal(body, &request); err != nil {
-		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid JSON-RPC request"})
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		writeJSONResponse(w, http.StatusBadRequest, rpcErrorResponse(nil, -32700, "parse error"))
+		return
+	}
+	if body[0] == '[' {
+		s.handleJSONRPCBatch(w, r, body)
 		return
 	}
 
-	// Check JSON-RPC version
-	if request.JSONRPC != "2.0" {
-		writeJSONResponse(w, http.StatusBadRequest, RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32600,
-				Message: "invalid JSON-RPC version, expected 2.0",
-			},
-			ID: request.ID,
-		})
+	request, rpcError := parseRPCRequest(body)
+	setRPCMethod(r.Context(), request.Method)
+	if rpcError != nil {
+		// Invalid requests are answered even without an id
+		writeJSONResponse(w, http.StatusBadRequest, RPCResponse{JSONRPC: "2.0", Error: rpcError, ID: request.ID})
 		return
 	}
 
-	// Process request based on method
-	var result interface{}
-	var rpcError *RPCError
+	response, status := s.callRPC(w, r, request, false)
+	writeRPCResponse(w, status, request, response)
+}
 
-	switch request.Method {
-	case "eth_blockNumber":
-		blockNumber, err := s.client.GetBlockNumber()
-		if err != nil {
-			rpcError = &RPCError{
-				Code:    -32603,
-				Message: err.Error(),
-			}
-		} else {
-			result = blockNumber
-		}
+// handleJSONRPCBatch handles a batch of JSON-RPC requests. The batch is rate
+// limited as a whole, charging the sum of its calls' compute units. Invalid
+// entries are answered individually; notifications get no response, and a
+// batch of only notifications gets no body at all.
+func (s *Server) handleJSONRPCBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(body, &entries); err != nil || len(entries) == 0 {
+		writeJSONResponse(w, http.StatusBadRequest, rpcErrorResponse(nil, -32600, "invalid JSON-RPC request: empty batch"))
+		return
+	}
 
-	case "eth_getBlockByNumber":
-		if len(request.Params) < 2 {
-			rpcError = &RPCError{
-				Code:    -32602,
-				Message: "invalid params for eth_getBlockByNumber",
-			}
-			break
-		}
+	setRPCMethod(r.Context(), batchMethod)
 
-		// Get block number from params
-		blockNumberParam, ok := request.Params[0].(string)
-		if !ok {
-			rpcError = &RPCError{
-				Code:    -32602,
-				Message: "invalid block number parameter",
-			}
-			break
+	requests := make([]RPCRequest, len(entries))
+	invalid := make([]*RPCError, len(entries))
+	cost := 0
+	for i, entry := range entries {
+		requests[i], invalid[i] = parseRPCRequest(entry)
+		if invalid[i] == nil {
+			cost += s.requestCost(r, requests[i].Method)
 		}
+	}
 
-		// Get full transactions from params
-		fullTransactions, ok := request.Params[1].(bool)
-		if !ok {
-			rpcError = &RPCError{
-				Code:    -32602,
-				Message: "invalid full transactions parameter",
+	responses := make([]RPCResponse, 0, len(requests))
+	if status, rpcError := s.limitRPC(w, r, cost); rpcError != nil {
+		for _, request := range requests {
+			if !request.isNotification() {
+				responses = append(responses, RPCResponse{JSONRPC: "2.0", Error: rpcError, ID: request.ID})
 			}
-			break
 		}
+		writeBatchResponse(w, status, responses)
+		return
+	}
 
-		// Get block
-		block, err := s.client.GetBlockByNumber(blockNumberParam, fullTransactions)
-		if err != nil {
-			rpcError = &RPCError{
-				Code:    -32603,
-				Message: err.Error(),
-			}
-		} else {
-			result = block
+	for i, request := range requests {
+		if invalid[i] != nil {
+			responses = append(responses, RPCResponse{JSONRPC: "2.0", Error: invalid[i], ID: request.ID})
+			continue
 		}
-
-	default:
-		rpcError = &RPCError{
-			Code:    -32601,
-			Message: "method not found",
+		response, _ := s.callRPC(w, r, request, true)
+		if !request.isNotification() {
+			responses = append(responses, response)
 		}
 	}
 
-	// Prepare response
-	response := RPCResponse{
-		JSONRPC: "2.0",
-		ID:      request.ID,
-		Error:   rpcError,
+	writeBatchResponse(w, http.StatusOK, responses)
+}
+
+// SetupRoutes sets up the API routes
+func (s *Server) SetupRoutes() http.Handler {
+	var handler http.Handler = s.routes()
+	if len(s.chains) > 0 {
+		handler = s.routeChains(handler)
 	}
+	return requestID(s.instrument(limitBody(handler, s.httpConfig.withDefaults().MaxBodyBytes)))
+}
 
-	if result != nil && rpcError == nil {
-		resultBytes, err := json.Marshal(result)
-		if err != nil {
-			response.Error = &RPCError{
-				Code:    -32603,
-				Message: "failed to marshal result",
-			}
-		} else {
-			response.Result = resultBytes
+// apiPrefix is the prefix of the current version of the REST endpoints
+const apiPrefix = "/api/v1"
+
+// deprecated marks responses from the unversioned REST endpoints, which
+// predate apiPrefix, as deprecated
+func deprecated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		next(w, r)
+	})
+}
+
+// routedMethods are the methods the REST endpoints are registered for
+var routedMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete}
+
+// unrouted answers requests that mux has no route for with a JSON error: 405
+// listing the allowed methods when the path is routed for other methods, and
+// 404 otherwise
+func unrouted(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
 		}
-	}
 
-	// Send response
-	writeJSONResponse(w, http.StatusOK, response)
+		var allowed []string
+		for _, method := range routedMethods {
+			probe := &http.Request{Method: method, Host: r.Host, URL: r.URL}
+			if _, pattern := mux.Handler(probe); pattern != "" {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeJSONResponse(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+			return
+		}
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "not found"})
+	})
 }
 
-// SetupRoutes sets up the API routes
-func (s *Server) SetupRoutes() http.Handler {
+// routes returns the handler serving the API of the server's own chain
+func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
 
-	// Original REST endpoints
-	mux.HandleFunc("/api/blocks/latest", s.HandleGetBlockNumber)
-	mux.HandleFunc("/api/blocks", s.HandleGetBlockByNumber)
+	// REST endpoints
+	mux.HandleFunc("GET "+apiPrefix+"/blocks", s.HandleGetBlockRange)
+	mux.HandleFunc("GET "+apiPrefix+"/blocks/{id}", s.HandleGetBlock)
+	mux.HandleFunc("GET "+apiPrefix+"/blocks/{id}/transactions", s.HandleGetBlockTransactions)
+	mux.HandleFunc("GET "+apiPrefix+"/tx/{hash}", s.HandleGetTransaction)
+	mux.HandleFunc("GET "+apiPrefix+"/accounts/{address}", s.HandleGetAccount)
+	mux.HandleFunc("GET "+apiPrefix+"/accounts/{address}/transactions", s.HandleGetAccountTransactions)
+	mux.HandleFunc("GET "+apiPrefix+"/exports", s.HandleListExports)
+	mux.HandleFunc("POST "+apiPrefix+"/exports", s.HandleStartExport)
+	mux.HandleFunc("GET "+apiPrefix+"/exports/{id}", s.HandleGetExport)
+	mux.HandleFunc("DELETE "+apiPrefix+"/exports/{id}", s.HandleCancelExport)
+
+	// Original unversioned REST endpoints, kept as deprecated aliases
+	mux.Handle("GET /api/blocks/latest", deprecated(s.HandleGetBlockNumber))
+	mux.Handle("GET /api/blocks", deprecated(s.HandleGetBlockByNumber))
+	mux.Handle("GET /api/accounts/{address}", deprecated(s.HandleGetAccount))
+	mux.Handle("GET /api/accounts/{address}/transactions", deprecated(s.HandleGetAccountTransactions))
+	mux.Handle("GET /api/exports", deprecated(s.HandleListExports))
+	mux.Handle("POST /api/exports", deprecated(s.HandleStartExport))
+	mux.Handle("GET /api/exports/{id}", deprecated(s.HandleGetExport))
+	mux.Handle("DELETE /api/exports/{id}", deprecated(s.HandleCancelExport))
+
+	// Health and status endpoints
+	mux.HandleFunc("/healthz", s.HandleHealthz)
+	mux.HandleFunc("/readyz", s.HandleReadyz)
+	mux.HandleFunc("/status", s.HandleStatus)
+
+	// API documentation
+	mux.HandleFunc("/openapi.json", s.HandleOpenAPI)
+	mux.HandleFunc("/docs", s.HandleDocs)
+
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics.Handler())
+	}
 
-	// New JSON-RPC endpoint
-	mux.HandleFunc("/", s.HandleJSONRPC)
+	// JSON-RPC endpoint
+	mux.HandleFunc("/{$}", s.HandleJSONRPC)
 
-	return mux
+	var handler http.Handler = s.requestQuorum(unrouted(mux))
+	if s.validation.Requests || s.validation.Responses {
+		handler = s.validateREST(handler)
+	}
+	if s.limiter != nil {
+		handler = s.limitREST(handler)
+	}
+	if s.keys != nil {
+		// JSON-RPC with the API key in the path, as hosted providers offer
+		mux.HandleFunc(keyPathPrefix+"{key}", s.HandleJSONRPC)
+		handler = s.authenticate(handler)
+	}
+	return handler
 }
 
-// Start starts the API server
+// Start starts the API server and serves until it fails
 func (s *Server) Start(addr string) error {
-	if addr == "" {
-		addr = ":8080"
-	}
-
-	log.Printf("Starting API server on %s", addr)
-	return http.ListenAndServe(addr, s.SetupRoutes())
+	return s.Run(context.Background(), addr)
 }
@@ -2,33 +2,61 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/store"
 )
 
 // mockBlockchainClient is a mock implementation of the blockchain client for testing
 type mockBlockchainClient struct {
-	getBlockNumberFunc   func() (string, error)
-	getBlockByNumberFunc func(blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	getBlockNumberFunc    func() (string, error)
+	getBlockByNumberFunc  func(blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	getBlocksByNumberFunc func(blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error)
+	getBlockReceiptsFunc  func(blockNumber string) ([]*blockchain.Receipt, error)
+	getChainIDFunc        func() (string, error)
+	getProofFunc          func(address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error)
+	getTransactionFunc    func(hash string) (json.RawMessage, error)
 }
 
-func (m *mockBlockchainClient) GetBlockNumber() (string, error) {
+func (m *mockBlockchainClient) GetBlockNumber(ctx context.Context) (string, error) {
 	return m.getBlockNumberFunc()
 }
 
-func (m *mockBlockchainClient) GetBlockByNumber(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+func (m *mockBlockchainClient) GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
 	return m.getBlockByNumberFunc(blockNumber, fullTransactions)
 }
 
+func (m *mockBlockchainClient) GetBlockReceipts(ctx context.Context, blockNumber string) ([]*blockchain.Receipt, error) {
+	return m.getBlockReceiptsFunc(blockNumber)
+}
+
+func (m *mockBlockchainClient) GetBlocksByNumber(ctx context.Context, blockNumbers []string, fullTransactions bool) ([]*blockchain.Block, error) {
+	return m.getBlocksByNumberFunc(blockNumbers, fullTransactions)
+}
+
+func (m *mockBlockchainClient) GetChainID(ctx context.Context) (string, error) {
+	return m.getChainIDFunc()
+}
+
+func (m *mockBlockchainClient) GetProof(ctx context.Context, address string, storageKeys []string, blockNumber string) (*blockchain.AccountProof, error) {
+	return m.getProofFunc(address, storageKeys, blockNumber)
+}
+
+func (m *mockBlockchainClient) GetTransactionByHash(ctx context.Context, hash string) (json.RawMessage, error) {
+	return m.getTransactionFunc(hash)
+}
+
 // We need to modify the Server struct in tests to accept the interface instead of the concrete type
 type blockchainClient interface {
-	GetBlockNumber() (string, error)
-	GetBlockByNumber(blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	GetBlockNumber(ctx context.Context) (string, error)
+	GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error)
 }
 
 // testServer wraps Server for testing
@@ -166,6 +194,51 @@ func TestHandleGetBlockByNumber(t *testing.T) {
 	}
 }
 
+func TestHandleGetBlockByNumberVerify(t *testing.T) {
+	block := &blockchain.Block{
+		ParentHash:       "0x" + strings.Repeat("a1", 32),
+		Sha3Uncles:       "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+		Miner:            "0x" + strings.Repeat("00", 20),
+		StateRoot:        "0x" + strings.Repeat("b2", 32),
+		TransactionsRoot: "0x" + strings.Repeat("c3", 32),
+		ReceiptsRoot:     "0x" + strings.Repeat("d4", 32),
+		LogsBloom:        "0x" + strings.Repeat("00", 256),
+		Difficulty:       "0x0",
+		Number:           "0x10",
+		GasLimit:         "0x1c9c380",
+		GasUsed:          "0x0",
+		Timestamp:        "0x65a7751f",
+		ExtraData:        "0x",
+		MixHash:          "0x" + strings.Repeat("e5", 32),
+		Nonce:            "0x0000000000000000",
+		BaseFeePerGas:    "0x7",
+	}
+	block.Hash, _ = block.ComputeHash()
+
+	ts := newTestServer()
+	// A stale stored copy must not be served in place of the upstream block
+	ts.server.store = &mockBlockStore{blocks: map[uint64]*blockchain.Block{16: {Number: "0x10", Hash: "0xstale"}}}
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		b := *block
+		return &b, nil
+	}
+
+	rec := httptest.NewRecorder()
+	ts.server.HandleGetBlockByNumber(rec, httptest.NewRequest("GET", "/api/blocks?number=0x10&verify=true", nil))
+	var resp BlockResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusOK || !resp.Verified || resp.Block.Hash != block.Hash {
+		t.Fatalf("expected a verified block, got %d %+v", rec.Code, resp)
+	}
+
+	block.GasUsed = "0x1"
+	rec = httptest.NewRecorder()
+	ts.server.HandleGetBlockByNumber(rec, httptest.NewRequest("GET", "/api/blocks?number=0x10&verify=true", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "hash mismatch") {
+		t.Errorf("expected a tampered block to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestHandleJSONRPC(t *testing.T) {
 	t.Run("eth_blockNumber", func(t *testing.T) {
 		// Create a test server with mock client
@@ -211,7 +284,7 @@ func TestHandleJSONRPC(t *testing.T) {
 			t.Errorf("expected jsonrpc 2.0; got %v", resp.JSONRPC)
 		}
 
-		if resp.ID != 2 {
+		if string(resp.ID) != "2" {
 			t.Errorf("expected id 2; got %v", resp.ID)
 		}
 
@@ -299,7 +372,7 @@ func TestHandleJSONRPC(t *testing.T) {
 			t.Errorf("expected jsonrpc 2.0; got %v", resp.JSONRPC)
 		}
 
-		if resp.ID != 2 {
+		if string(resp.ID) != "2" {
 			t.Errorf("expected id 2; got %v", resp.ID)
 		}
 
@@ -361,7 +434,7 @@ func TestHandleJSONRPC(t *testing.T) {
 			t.Errorf("expected jsonrpc 2.0; got %v", resp.JSONRPC)
 		}
 
-		if resp.ID != 2 {
+		if string(resp.ID) != "2" {
 			t.Errorf("expected id 2; got %v", resp.ID)
 		}
 
@@ -373,6 +446,71 @@ func TestHandleJSONRPC(t *testing.T) {
 	})
 }
 
+func TestHandleJSONRPCBatch(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x1234567", nil
+	}
+
+	reqBody := `[
+		{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": 1},
+		{"jsonrpc": "1.0", "method": "eth_blockNumber", "id": 2},
+		{"jsonrpc": "2.0", "method": "eth_unknown", "id": 3}
+	]`
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+	rec := httptest.NewRecorder()
+	ts.server.HandleJSONRPC(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", rec.Code)
+	}
+
+	var responses []RPCResponse
+	if err := json.NewDecoder(rec.Body).Decode(&responses); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses; got %d", len(responses))
+	}
+	if string(responses[0].ID) != "1" || string(responses[0].Result) != `"0x1234567"` {
+		t.Errorf("unexpected first response %+v", responses[0])
+	}
+	if string(responses[1].ID) != "2" || responses[1].Error == nil || responses[1].Error.Code != -32600 {
+		t.Errorf("expected invalid version error; got %+v", responses[1])
+	}
+	if string(responses[2].ID) != "3" || responses[2].Error == nil || responses[2].Error.Code != -32601 {
+		t.Errorf("expected method not found error; got %+v", responses[2])
+	}
+}
+
+func TestEnabledMethods(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) {
+		return "0x10", nil
+	}
+
+	call := func() *RPCResponse {
+		req := httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`))
+		rec := httptest.NewRecorder()
+		ts.server.HandleJSONRPC(rec, req)
+		var resp RPCResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		return &resp
+	}
+
+	ts.server.SetMethods([]string{"eth_getBlockByNumber"})
+	if resp := call(); resp.Error == nil || resp.Error.Code != -32601 {
+		t.Errorf("expected disabled method to be not found; got %+v", resp)
+	}
+
+	ts.server.SetMethods(nil)
+	if resp := call(); resp.Error != nil {
+		t.Errorf("expected every method to be enabled; got %+v", resp.Error)
+	}
+}
+
 func TestDirectBlockJson(t *testing.T) {
 	// Create a Block with transaction count explicitly set
 	txsJSON := json.RawMessage(`["0xtx1", "0xtx2"]`)
@@ -415,3 +553,266 @@ func TestDirectBlockJson(t *testing.T) {
 		t.Errorf("expected TransactionCount 2 after unmarshal; got %v", newResp.Block.TransactionCount)
 	}
 }
+
+// mockBlockStore is a mock implementation of the block store for testing
+type mockBlockStore struct {
+	blocks                 map[uint64]*blockchain.Block
+	transactions           map[string]json.RawMessage
+	getAddressActivityFunc func(q store.ActivityQuery) (*store.ActivityPage, error)
+}
+
+func (m *mockBlockStore) GetAddressActivity(q store.ActivityQuery) (*store.ActivityPage, error) {
+	return m.getAddressActivityFunc(q)
+}
+
+func (m *mockBlockStore) GetTransaction(hash string) (json.RawMessage, error) {
+	tx, ok := m.transactions[hash]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return tx, nil
+}
+
+func (m *mockBlockStore) GetBlock(number uint64, fullTransactions bool) (*blockchain.Block, error) {
+	block, ok := m.blocks[number]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return block, nil
+}
+
+func TestGetBlockFromStore(t *testing.T) {
+	ts := newTestServer()
+	ts.server.store = &mockBlockStore{blocks: map[uint64]*blockchain.Block{
+		0x10: {Number: "0x10", Hash: "0xstored"},
+	}}
+
+	upstreamCalls := 0
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		upstreamCalls++
+		return &blockchain.Block{Number: blockNumber, Hash: "0xupstream"}, nil
+	}
+
+	t.Run("served from store", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/blocks?number=0x10", nil)
+		rec := httptest.NewRecorder()
+		ts.server.HandleGetBlockByNumber(rec, req)
+
+		var resp BlockResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if resp.Block.Hash != "0xstored" {
+			t.Errorf("expected block from store; got %v", resp.Block.Hash)
+		}
+		if upstreamCalls != 0 {
+			t.Errorf("expected no upstream calls; got %d", upstreamCalls)
+		}
+	})
+
+	t.Run("falls back to upstream", func(t *testing.T) {
+		for _, number := range []string{"0x11", "latest"} {
+			req := httptest.NewRequest("GET", "/api/blocks?number="+number, nil)
+			rec := httptest.NewRecorder()
+			ts.server.HandleGetBlockByNumber(rec, req)
+
+			var resp BlockResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if resp.Block.Hash != "0xupstream" {
+				t.Errorf("expected block %s from upstream; got %v", number, resp.Block.Hash)
+			}
+		}
+		if upstreamCalls != 2 {
+			t.Errorf("expected 2 upstream calls; got %d", upstreamCalls)
+		}
+	})
+}
+
+func TestHandleGetAccountTransactions(t *testing.T) {
+	const address = "0x1111111111111111111111111111111111111111"
+
+	t.Run("passes filters to the index", func(t *testing.T) {
+		ts := newTestServer()
+		ts.server.store = &mockBlockStore{getAddressActivityFunc: func(q store.ActivityQuery) (*store.ActivityPage, error) {
+			if q.Address != address {
+				t.Errorf("expected address %s; got %s", address, q.Address)
+			}
+			if len(q.Roles) != 2 || q.Roles[0] != "from" || q.Roles[1] != "to" {
+				t.Errorf("expected roles [from to]; got %v", q.Roles)
+			}
+			if q.FromBlock != 16 || q.ToBlock != 100 {
+				t.Errorf("expected block range 16-100; got %d-%d", q.FromBlock, q.ToBlock)
+			}
+			if q.Cursor != "abc" || q.Limit != 5 {
+				t.Errorf("expected cursor abc and limit 5; got %s and %d", q.Cursor, q.Limit)
+			}
+			return &store.ActivityPage{
+				Items:      []*store.Activity{{BlockNumber: "0x20", TransactionHash: "0xtx", Role: "from"}},
+				NextCursor: "def",
+			}, nil
+		}}
+
+		req := httptest.NewRequest("GET", "/api/accounts/"+address+"/transactions?direction=from,to&fromBlock=0x10&toBlock=100&cursor=abc&limit=5", nil)
+		rec := httptest.NewRecorder()
+		ts.server.SetupRoutes().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status OK; got %v", rec.Code)
+		}
+		var page store.ActivityPage
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		if len(page.Items) != 1 || page.NextCursor != "def" {
+			t.Errorf("expected one item and next cursor def; got %+v", page)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ts := newTestServer()
+		ts.server.store = &mockBlockStore{getAddressActivityFunc: func(q store.ActivityQuery) (*store.ActivityPage, error) {
+			return nil, store.ErrInvalidAddress
+		}}
+
+		tests := []struct {
+			path string
+			code int
+		}{
+			{"/api/accounts/0x12/transactions", http.StatusBadRequest},
+			{"/api/accounts/" + address + "/transactions?limit=-1", http.StatusBadRequest},
+			{"/api/accounts/" + address + "/transactions?fromBlock=abc", http.StatusBadRequest},
+			{"/api/accounts/" + address + "/balances", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			rec := httptest.NewRecorder()
+			ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.code {
+				t.Errorf("%s: expected status %d; got %d", tt.path, tt.code, rec.Code)
+			}
+		}
+
+		ts.server.store = nil
+		rec := httptest.NewRecorder()
+		ts.server.SetupRoutes().ServeHTTP(rec, httptest.NewRequest("GET", "/api/accounts/"+address+"/transactions", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503 without a store; got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandleGetBlock(t *testing.T) {
+	ts := newTestServer()
+	var requested []string
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		requested = append(requested, blockNumber)
+		if blockNumber == "0xffff" {
+			return &blockchain.Block{}, nil
+		}
+		return &blockchain.Block{Number: blockNumber, Transactions: json.RawMessage(`[{"hash":"0xtx1"}]`)}, nil
+	}
+	handler := ts.server.SetupRoutes()
+
+	tests := []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/api/v1/blocks/100", http.StatusOK, "0x64"},
+		{"/api/v1/blocks/0x64", http.StatusOK, "0x64"},
+		{"/api/v1/blocks/finalized", http.StatusOK, "finalized"},
+		{"/api/v1/blocks/65535", http.StatusNotFound, "0xffff"},
+		{"/api/v1/blocks/0x64/transactions", http.StatusOK, "0x64"},
+		{"/api/v1/blocks/65535/transactions", http.StatusNotFound, "0xffff"},
+		{"/api/v1/blocks/head", http.StatusBadRequest, ""},
+		{"/api/v1/blocks/-1/transactions", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		requested = nil
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status %d; got %d: %s", tt.path, tt.code, rec.Code, rec.Body)
+		}
+		if tt.want != "" && (len(requested) != 1 || requested[0] != tt.want) {
+			t.Errorf("%s: expected block %s to be requested; got %v", tt.path, tt.want, requested)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/blocks/100/transactions", nil))
+	var resp BlockTransactionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if resp.BlockNumber != "0x64" || string(resp.Transactions) != `[{"hash":"0xtx1"}]` {
+		t.Errorf("expected the block's transactions; got %+v", resp)
+	}
+}
+
+func TestHandleGetBlockPastHead(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req blockchain.RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(blockchain.RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`null`)})
+	}))
+	defer upstream.Close()
+	handler := (&Server{client: blockchain.NewClient(upstream.URL)}).SetupRoutes()
+
+	for _, path := range []string{"/api/v1/blocks/99999999", "/api/v1/blocks/99999999/transactions"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404 for a block past the head; got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRouting(t *testing.T) {
+	ts := newTestServer()
+	ts.mock.getBlockNumberFunc = func() (string, error) { return "0x10", nil }
+	ts.mock.getBlockByNumberFunc = func(blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+		return &blockchain.Block{Number: blockNumber}, nil
+	}
+	handler := ts.server.SetupRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		code       int
+		body       string
+		allow      string
+		deprecated bool
+	}{
+		{name: "versioned route", method: "GET", path: "/api/v1/blocks/0x10", code: http.StatusOK},
+		{name: "deprecated alias", method: "GET", path: "/api/blocks/latest", code: http.StatusOK, deprecated: true},
+		{name: "unknown REST path", method: "GET", path: "/api/block", code: http.StatusNotFound, body: `{"error":"not found"}`},
+		{name: "unknown path", method: "POST", path: "/eth", code: http.StatusNotFound, body: `{"error":"not found"}`},
+		{name: "trailing segment", method: "GET", path: "/api/v1/blocks/0x10/receipts", code: http.StatusNotFound, body: `{"error":"not found"}`},
+		{name: "wrong method", method: "POST", path: "/api/v1/blocks/0x10", code: http.StatusMethodNotAllowed, body: `{"error":"method not allowed"}`, allow: "GET"},
+		{name: "wrong method on exports", method: "PUT", path: "/api/v1/exports/job", code: http.StatusMethodNotAllowed, body: `{"error":"method not allowed"}`, allow: "GET, DELETE"},
+		{name: "JSON-RPC", method: "POST", path: "/", code: http.StatusOK, body: `{"jsonrpc":"2.0","result":"0x10","id":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			body := strings.NewReader(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, body))
+
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d; got %d: %s", tt.code, rec.Code, rec.Body)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); tt.body != "" && got != tt.body {
+				t.Errorf("unexpected response\n got: %s\nwant: %s", got, tt.body)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("expected Allow %q; got %q", tt.allow, got)
+			}
+			if got := rec.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+				t.Errorf("expected deprecated %v; got %v", tt.deprecated, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCertReloadInterval is how often the certificate files are checked
+// for changes
+const DefaultCertReloadInterval = time.Minute
+
+// TLSConfig represents the HTTPS settings. The server serves plaintext HTTP
+// when CertFile is empty.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of CAs; when set, clients must present a
+	// certificate signed by one of them (mutual TLS)
+	ClientCAFile string
+	// RedirectAddr is an address on which plaintext requests are redirected
+	// to HTTPS, e.g. ":80"; empty disables the redirect
+	RedirectAddr string
+	// ReloadInterval is how often the certificate, key and CA files are
+	// checked for changes
+	ReloadInterval time.Duration
+}
+
+// WithTLS serves the API over HTTPS
+func WithTLS(cfg TLSConfig) Option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// certReloader serves the certificate and client CAs loaded from disk,
+// reloading them when the files change
+type certReloader struct {
+	cfg TLSConfig
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  []time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader loads the files named in cfg
+func newCertReloader(cfg TLSConfig) (*certReloader, error) {
+	if cfg.KeyFile == "" {
+		return nil, errors.New("TLS key file is required with a certificate file")
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultCertReloadInterval
+	}
+
+	r := &certReloader{cfg: cfg}
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files returns the files the TLS configuration is built from
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// statFiles returns the modification time of each file
+func (r *certReloader) statFiles() ([]time.Time, error) {
+	var times []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		times = append(times, info.ModTime())
+	}
+	return times, nil
+}
+
+// load builds the TLS configuration from the files. Callers hold r.mu or
+// have exclusive access to r.
+func (r *certReloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
+
+// current returns the TLS configuration, first reloading it if the files
+// changed since the last check. A failed reload keeps the previous
+// configuration in effect.
+func (r *certReloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastCheck) < r.cfg.ReloadInterval {
+		return r.config
+	}
+	r.lastCheck = now
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		slog.Error("failed to check TLS files, keeping previous certificate", "error", err)
+		return r.config
+	}
+	if !changed(r.modTimes, modTimes) {
+		return r.config
+	}
+	if err := r.load(modTimes); err != nil {
+		slog.Error("failed to reload TLS certificate, keeping previous certificate", "error", err)
+		return r.config
+	}
+	slog.Info("reloaded TLS certificate")
+	return r.config
+}
+
+// changed reports whether any modification time differs
+func changed(old, cur []time.Time) bool {
+	for i := range cur {
+		if !cur[i].Equal(old[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverConfig returns the configuration for the HTTP server, which looks up
+// the current certificate for every handshake
+func (r *certReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// redirectHandler redirects plaintext requests to the same path over HTTPS
+// on httpsPort
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
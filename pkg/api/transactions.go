@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"blockchain-client/pkg/store"
+)
+
+// txHashPattern matches a hex-encoded transaction hash
+var txHashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// TransactionResponse represents the response for the transaction endpoint
+type TransactionResponse struct {
+	// Transaction is the transaction object as returned by
+	// eth_getTransactionByHash
+	Transaction json.RawMessage `json:"transaction"`
+}
+
+// getTransaction returns a transaction from the store if its block has been
+// ingested, otherwise from the upstream RPC endpoint
+func (s *Server) getTransaction(ctx context.Context, hash string) (json.RawMessage, error) {
+	if s.store != nil {
+		tx, err := s.store.GetTransaction(hash)
+		s.observeCache(err == nil)
+		if err == nil {
+			return tx, nil
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			slog.ErrorContext(ctx, "failed to read transaction from store", "transaction", hash, "error", err)
+		}
+	}
+
+	return s.client.GetTransactionByHash(ctx, hash)
+}
+
+// HandleGetTransaction handles the /api/v1/tx/{hash} endpoint
+func (s *Server) HandleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+	if !txHashPattern.MatchString(hash) {
+		writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "invalid transaction hash"})
+		return
+	}
+
+	tx, err := s.getTransaction(r.Context(), hash)
+	if err != nil {
+		upstreamFailed(w, err)
+		return
+	}
+	if tx == nil {
+		writeJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: "transaction not found"})
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, TransactionResponse{Transaction: tx})
+}
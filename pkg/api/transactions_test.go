@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+const testTxHash = "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+
+func TestHandleGetTransaction(t *testing.T) {
+	stored := "0x" + strings.Repeat("11", 32)
+
+	ts := newTestServer()
+	ts.server.store = &mockBlockStore{transactions: map[string]json.RawMessage{
+		stored: json.RawMessage(`{"hash":"` + stored + `"}`),
+	}}
+	var upstreamCalls []string
+	ts.mock.getTransactionFunc = func(hash string) (json.RawMessage, error) {
+		upstreamCalls = append(upstreamCalls, hash)
+		switch hash {
+		case testTxHash:
+			return json.RawMessage(`{"hash":"` + hash + `"}`), nil
+		case "0x" + strings.Repeat("ee", 32):
+			return nil, &blockchain.RPCError{Code: -32000, Message: "request timed out"}
+		}
+		return nil, nil
+	}
+	handler := ts.server.SetupRoutes()
+
+	tests := []struct {
+		name string
+		hash string
+		code int
+		want string
+	}{
+		{"served from store", stored, http.StatusOK, `{"transaction":{"hash":"` + stored + `"}}`},
+		{"falls back to upstream", testTxHash, http.StatusOK, `{"transaction":{"hash":"` + testTxHash + `"}}`},
+		{"not found", "0x" + strings.Repeat("00", 32), http.StatusNotFound, `{"error":"transaction not found"}`},
+		{"upstream error", "0x" + strings.Repeat("ee", 32), http.StatusBadGateway, `{"error":"RPC error: request timed out (code: -32000)","code":-32000}`},
+		{"invalid hash", "0x1234", http.StatusBadRequest, `{"error":"invalid transaction hash"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/tx/"+tt.hash, nil))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d; got %d", tt.code, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.want {
+				t.Errorf("unexpected response\n got: %s\nwant: %s", body, tt.want)
+			}
+		})
+	}
+
+	for _, hash := range upstreamCalls {
+		if hash == stored {
+			t.Errorf("expected stored transaction to be served without an upstream call")
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidationConfig configures checking REST traffic against the server's
+// OpenAPI document
+type ValidationConfig struct {
+	// Requests rejects requests whose parameters or body do not match the
+	// document with 400 Bad Request
+	Requests bool
+	// Responses checks responses against the document. Mismatches are
+	// reported to OnInvalidResponse, or logged when it is nil; the response
+	// is sent unchanged.
+	Responses         bool
+	OnInvalidResponse func(r *http.Request, err error)
+}
+
+// WithValidation checks REST requests and responses against the OpenAPI
+// document served at /openapi.json
+func WithValidation(cfg ValidationConfig) Option {
+	return func(s *Server) {
+		s.validation = cfg
+	}
+}
+
+// validateREST applies the server's ValidationConfig to the documented REST
+// routes. Undocumented paths and methods are passed through for the handlers
+// to reject.
+func (s *Server) validateREST(next http.Handler) http.Handler {
+	cfg := s.validation
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc := s.openAPI()
+		op, pathParams := doc.match(r.Method, r.URL.Path)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cfg.Requests {
+			body, err := readBody(r)
+			if err != nil {
+				if isBodyTooLarge(err) {
+					writeJSONResponse(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "request body too large"})
+					return
+				}
+				writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+				return
+			}
+			if err := doc.validateRequest(op, r, pathParams, body); err != nil {
+				writeJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+				return
+			}
+			if body != nil {
+				routed := r.WithContext(r.Context())
+				routed.Body = io.NopCloser(bytes.NewReader(body))
+				// The ServeMux records its pattern on the copy
+				defer func() { setRoute(routed.Context(), routed.Pattern) }()
+				r = routed
+			}
+		}
+
+		if !cfg.Responses {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &bodyRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if err := doc.validateResponse(op, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+			err = fmt.Errorf("%s %s: %w", r.Method, r.URL.Path, err)
+			if cfg.OnInvalidResponse != nil {
+				cfg.OnInvalidResponse(r, err)
+			} else {
+				slog.WarnContext(r.Context(), "response does not match the OpenAPI document", "error", err)
+			}
+		}
+	})
+}
+
+// readBody reads the request body, returning nil when there is none
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	return body, nil
+}
+
+// bodyRecorder keeps a copy of the response as it is written through
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming handlers
+func (r *bodyRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// match returns the operation documented for method on path and the values
+// of its path parameters. Paths with more literal segments win, so
+// /api/blocks/latest is preferred over /api/blocks/{id}.
+func (d *openAPIDocument) match(method, path string) (*operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var (
+		best       *operation
+		bestParams map[string]string
+		bestScore  = -1
+	)
+	for template, item := range d.Paths {
+		op := item[strings.ToLower(method)]
+		if op == nil {
+			continue
+		}
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		score := 0
+		for i, part := range parts {
+			if name, ok := strings.CutPrefix(part, "{"); ok {
+				if segments[i] == "" {
+					score = -1
+					break
+				}
+				params[strings.TrimSuffix(name, "}")] = segments[i]
+				continue
+			}
+			if part != segments[i] {
+				score = -1
+				break
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestParams, bestScore = op, params, score
+		}
+	}
+	return best, bestParams
+}
+
+// validateRequest checks the parameters and body of a request to op
+func (d *openAPIDocument) validateRequest(op *operation, r *http.Request, pathParams map[string]string, body []byte) error {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var (
+			value string
+			ok    bool
+		)
+		switch p.In {
+		case "path":
+			value, ok = pathParams[p.Name]
+		case "query":
+			ok = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			ok = value != ""
+		}
+		if !ok {
+			if p.Required {
+				return fmt.Errorf("%s parameter %q is required", p.In, p.Name)
+			}
+			continue
+		}
+		if err := d.validateParam(p.Schema, value); err != nil {
+			return fmt.Errorf("invalid %s parameter %q: %w", p.In, p.Name, err)
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	if len(body) == 0 {
+		if op.RequestBody.Required {
+			return errors.New("request body is required")
+		}
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	value, err := decodeJSONValue(body)
+	if err != nil {
+		return errors.New("request body is not valid JSON")
+	}
+	if err := d.validate(media.Schema, value, ""); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// validateParam checks a parameter given as a string, converting it to the
+// schema's type first
+func (d *openAPIDocument) validateParam(s *schema, value string) error {
+	var v any = value
+	switch d.resolve(s).Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		v = json.Number(value)
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		v = value == "true"
+	}
+	return d.validate(s, v, "")
+}
+
+// validateResponse checks the status and body of a response to op. JSON
+// bodies are checked against their schema, and NDJSON bodies line by line.
+func (d *openAPIDocument) validateResponse(op *operation, status int, contentType string, body []byte) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp, ok := op.Responses[statusKey(status)]
+	if !ok {
+		if resp, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("status %d is not documented", status)
+		}
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", contentType, status)
+	}
+
+	if mediaType == ndjsonContentType {
+		lines := bufio.NewScanner(bytes.NewReader(body))
+		lines.Buffer(nil, len(body)+1)
+		for n := 1; lines.Scan(); n++ {
+			value, err := decodeJSONValue(lines.Bytes())
+			if err != nil {
+				return fmt.Errorf("line %d is not valid JSON", n)
+			}
+			if err := d.validate(media.Schema, value, fmt.Sprintf("line %d", n)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := decodeJSONValue(body)
+	if err != nil {
+		return errors.New("response body is not valid JSON")
+	}
+	if err := d.validate(media.Schema, value, ""); err != nil {
+		return fmt.Errorf("response body: %w", err)
+	}
+	return nil
+}
+
+// decodeJSONValue decodes a single JSON value, keeping numbers verbatim
+func decodeJSONValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// resolve follows a reference to a component schema
+func (d *openAPIDocument) resolve(s *schema) *schema {
+	for s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validate checks value, decoded with decodeJSONValue, against s. path
+// locates value in the request or response for error messages.
+func (d *openAPIDocument) validate(s *schema, value any, path string) error {
+	s = d.resolve(s)
+	fail := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if path == "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+
+	if value == nil {
+		if s.Nullable || (s.Type == "" && len(s.AllOf) == 0 && len(s.OneOf) == 0) {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	for _, sub := range s.AllOf {
+		if err := d.validate(sub, value, path); err != nil {
+			return err
+		}
+	}
+	if len(s.OneOf) > 0 {
+		matches := 0
+		var firstErr error
+		for _, sub := range s.OneOf {
+			if err := d.validate(sub, value, path); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			matches++
+		}
+		switch {
+		case matches == 0 && len(s.OneOf) == 1:
+			return firstErr
+		case matches != 1:
+			return fail("must match exactly one of %d schemas, matches %d", len(s.OneOf), matches)
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fail("%s is required", name)
+			}
+		}
+		for name, v := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := d.validate(prop, v, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if s.Items != nil {
+			for i, v := range items {
+				if err := d.validate(s.Items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		if s.Pattern != "" && !compilePattern(s.Pattern).MatchString(str) {
+			return fail("%q does not match %s", str, s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fail("%q is not an RFC 3339 date-time", str)
+			}
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fail("must be a number")
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return fail("%s is not a number", num)
+		}
+		if s.Type == "integer" && f != math.Trunc(f) {
+			return fail("must be an integer")
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// patterns caches the compiled schema patterns
+var patterns sync.Map
+
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	patterns.Store(pattern, re)
+	return re
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"blockchain-client/pkg/ratelimit"
+)
+
+// DefaultReloadInterval is how often Watch checks the key file for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// Errors returned by Keyring.Authorize
+var (
+	ErrUnknownKey       = errors.New("invalid API key")
+	ErrMethodNotAllowed = errors.New("method not allowed for this API key")
+)
+
+// LimitError reports that a key exceeded its rate limit or daily quota
+type LimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return e.Reason
+}
+
+// Key represents one API key entry in the key file
+type Key struct {
+	// Name identifies the key in logs and metrics without revealing it
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Methods lists the JSON-RPC methods and REST routes the key may call.
+	// A trailing * matches any suffix; an empty list allows everything.
+	Methods []string `json:"methods,omitempty"`
+	// RateLimit is the sustained requests per second; 0 means unlimited
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	// Burst is the number of requests allowed at once, defaulting to the
+	// rate limit
+	Burst int `json:"burst,omitempty"`
+	// DailyQuota is the compute units the key may spend per UTC day; 0 means
+	// unlimited
+	DailyQuota int64 `json:"dailyQuota,omitempty"`
+}
+
+// Allows reports whether the key may call method
+func (k *Key) Allows(method string) bool {
+	if len(k.Methods) == 0 {
+		return true
+	}
+	for _, allowed := range k.Methods {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(method, prefix) {
+				return true
+			}
+		} else if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFile represents the key file format
+type keyFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// keyState holds the limits and usage of one key
+type keyState struct {
+	key    Key
+	bucket *ratelimit.Bucket
+
+	mu   sync.Mutex
+	day  string
+	used int64
+}
+
+// Keyring holds the API keys loaded from a key file
+type Keyring struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	keys    map[string]*keyState
+	modTime time.Time
+	size    int64
+}
+
+// Load reads the key file at path
+func Load(path string) (*Keyring, error) {
+	k := &Keyring{path: path, now: time.Now}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload re-reads the key file. Existing keys keep their quota usage, and
+// their rate limit state unless the limit changed. On error the current keys stay in effect.
+func (k *Keyring) Reload() error {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := make(map[string]*keyState, len(file.Keys))
+	for i, key := range file.Keys {
+		if key.Key == "" {
+			return fmt.Errorf("key file entry %d has no key", i)
+		}
+		if _, ok := keys[key.Key]; ok {
+			return fmt.Errorf("key file entry %d (%s) duplicates another key", i, key.Name)
+		}
+		if key.RateLimit < 0 || key.Burst < 0 || key.DailyQuota < 0 {
+			return fmt.Errorf("key file entry %d (%s) has a negative limit", i, key.Name)
+		}
+
+		state := &keyState{key: key}
+		if key.RateLimit > 0 {
+			state.bucket = ratelimit.NewBucket(key.RateLimit, key.Burst)
+		}
+		if old, ok := k.keys[key.Key]; ok {
+			if old.bucket != nil && sameLimits(old.key, key) {
+				state.bucket = old.bucket
+			}
+			old.mu.Lock()
+			state.day, state.used = old.day, old.used
+			old.mu.Unlock()
+		}
+		keys[key.Key] = state
+	}
+
+	k.keys = keys
+	k.modTime = info.ModTime()
+	k.size = info.Size()
+	return nil
+}
+
+// sameLimits reports whether a and b share their rate limit, so a reloaded
+// key can keep its bucket
+func sameLimits(a, b Key) bool {
+	return a.RateLimit == b.RateLimit && a.Burst == b.Burst
+}
+
+// changed reports whether the key file differs from the loaded one
+func (k *Keyring) changed() bool {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return false
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return !info.ModTime().Equal(k.modTime) || info.Size() != k.size
+}
+
+// Watch reloads the key file whenever it changes until ctx is cancelled
+func (k *Keyring) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !k.changed() {
+			continue
+		}
+		if err := k.Reload(); err != nil {
+			slog.Error("failed to reload API keys, keeping previous keys", "error", err)
+			continue
+		}
+		slog.Info("reloaded API keys", "keys", k.Len())
+	}
+}
+
+// Len returns the number of loaded keys
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys)
+}
+
+// Lookup returns the key entry for a caller-supplied key
+func (k *Keyring) Lookup(key string) (*Key, bool) {
+	state, ok := k.state(key)
+	if !ok {
+		return nil, false
+	}
+	entry := state.key
+	return &entry, true
+}
+
+func (k *Keyring) state(key string) (*keyState, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	state, ok := k.keys[key]
+	return state, ok
+}
+
+// Authorize checks that key may call method now and charges its rate limit
+// and daily quota. cost is the compute units of the call.
+func (k *Keyring) Authorize(key, method string, cost int) error {
+	state, ok := k.state(key)
+	if !ok {
+		return ErrUnknownKey
+	}
+	if !state.key.Allows(method) {
+		return ErrMethodNotAllowed
+	}
+
+	if state.bucket != nil {
+		if ok, wait := state.bucket.Take(1); !ok {
+			return &LimitError{Reason: "rate limit exceeded", RetryAfter: wait}
+		}
+	}
+
+	if state.key.DailyQuota > 0 {
+		now := k.now().UTC()
+		day := now.Format(time.DateOnly)
+
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if state.day != day {
+			state.day = day
+			state.used = 0
+		}
+		if state.used+int64(cost) > state.key.DailyQuota {
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			return &LimitError{Reason: "daily compute unit quota exceeded", RetryAfter: midnight.Sub(now)}
+		}
+		state.used += int64(cost)
+	}
+	return nil
+}
+
+// Usage returns the compute units key has spent today
+func (k *Keyring) Usage(key string) int64 {
+	state, ok := k.state(key)
+	if !ok {
+		return 0
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.day != k.now().UTC().Format(time.DateOnly) {
+		return 0
+	}
+	return state.used
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKeyFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+}
+
+func TestKeyAllows(t *testing.T) {
+	key := Key{Methods: []string{"eth_blockNumber", "/api/blocks*"}}
+
+	tests := map[string]bool{
+		"eth_blockNumber":      true,
+		"eth_getBlockByNumber": false,
+		"/api/blocks":          true,
+		"/api/blocks/latest":   true,
+		"/api/accounts/":       false,
+	}
+	for method, want := range tests {
+		if got := key.Allows(method); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", method, got, want)
+		}
+	}
+
+	if !(&Key{}).Allows("anything") {
+		t.Error("expected an empty allowlist to allow everything")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeyFile(t, path, `{"keys":[
+		{"name":"limited","key":"k1","methods":["eth_*"],"rateLimit":1,"burst":2},
+		{"name":"quota","key":"k2","dailyQuota":25}
+	]}`)
+
+	keys, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	keys.now = func() time.Time { return now }
+
+	if err := keys.Authorize("nope", "eth_blockNumber", 10); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+	if err := keys.Authorize("k1", "/api/blocks", 10); !errors.Is(err, ErrMethodNotAllowed) {
+		t.Errorf("expected ErrMethodNotAllowed, got %v", err)
+	}
+
+	// The refused call above does not consume the burst
+	for i := 0; i < 2; i++ {
+		if err := keys.Authorize("k1", "eth_blockNumber", 10); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+	var limitErr *LimitError
+	if err := keys.Authorize("k1", "eth_blockNumber", 10); !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+		t.Errorf("expected rate limit error with retry delay, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := keys.Authorize("k2", "eth_blockNumber", 10); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+	err = keys.Authorize("k2", "eth_blockNumber", 10)
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter != time.Minute {
+		t.Errorf("expected quota error retrying at midnight, got %v", err)
+	}
+	if used := keys.Usage("k2"); used != 20 {
+		t.Errorf("expected 20 compute units used, got %d", used)
+	}
+
+	now = now.Add(time.Minute)
+	if err := keys.Authorize("k2", "eth_blockNumber", 10); err != nil {
+		t.Errorf("expected quota to reset at midnight, got %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeyFile(t, path, `{"keys":[{"name":"a","key":"k1","dailyQuota":100}]}`)
+
+	keys, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	if err := keys.Authorize("k1", "eth_blockNumber", 30); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go keys.Watch(ctx, 10*time.Millisecond)
+
+	writeKeyFile(t, path, `{"keys":[{"name":"a","key":"k1","dailyQuota":100},{"name":"b","key":"k2"}]}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for keys.Len() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("key file was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if used := keys.Usage("k1"); used != 30 {
+		t.Errorf("expected quota usage to survive reload, got %d", used)
+	}
+
+	// An invalid file keeps the previous keys
+	writeKeyFile(t, path, `{"keys":[{"name":"broken"}]}`)
+	if err := keys.Reload(); err == nil {
+		t.Error("expected reload of invalid key file to fail")
+	}
+	if _, ok := keys.Lookup("k2"); !ok {
+		t.Error("expected previous keys to stay in effect")
+	}
+}
@@ -2,10 +2,22 @@ package blockchain
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"blockchain-client/pkg/logging"
 )
 
 const (
@@ -16,6 +28,12 @@ const (
 type Client struct {
 	httpClient *http.Client
 	rpcURL     string
+	flights    *coalescer
+	observers  []CallObserver
+	// lastID is the id of the most recent request; ids are unique per client
+	lastID atomic.Int64
+
+	verifyBlocks bool
 }
 
 // RPCRequest represents a JSON-RPC request
@@ -34,79 +52,250 @@ type RPCResponse struct {
 	ID      int             `json:"id"`
 }
 
-// RPCError represents a JSON-RPC error
-type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+// ClientOption configures optional Client behaviour
+type ClientOption func(*Client)
+
+// WithTimeout bounds each upstream round-trip
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
 }
 
 // NewClient creates a new blockchain client
-func NewClient(rpcURL string) *Client {
+func NewClient(rpcURL string, opts ...ClientOption) *Client {
 	if rpcURL == "" {
 		rpcURL = PolygonRPC
 	}
-	return &Client{
+	c := &Client{
 		httpClient: &http.Client{},
 		rpcURL:     rpcURL,
+		flights:    newCoalescer(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CoalesceStats returns counters describing how many calls were collapsed
+// into a shared upstream round-trip
+func (c *Client) CoalesceStats() CoalesceStats {
+	return c.flights.snapshot()
+}
+
+// call makes an RPC call to the blockchain, sharing the upstream round-trip
+// with any identical call that is already in flight. The call returns when ctx
+// is done; the shared round-trip is cancelled once no caller waits on it.
+func (c *Client) call(ctx context.Context, method string, params []interface{}) (*RPCResponse, error) {
+	ctx, span := c.startSpan(ctx, method)
+	defer span.End()
+
+	key, err := coalesceKey(method, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, shared, err := c.flights.do(ctx, method, key, func(ctx context.Context) (*RPCResponse, error) {
+		return c.send(ctx, method, params)
+	})
+	span.SetAttributes(attribute.Bool(AttrCoalesced, shared))
+	endSpan(span, err)
+	return resp, err
+}
+
+// nextID returns a request id not yet used by the client
+func (c *Client) nextID() int {
+	return int(c.lastID.Add(1))
 }
 
-// call makes an RPC call to the blockchain
-func (c *Client) call(method string, params []interface{}) (*RPCResponse, error) {
+// send performs a single JSON-RPC round-trip to the upstream endpoint
+func (c *Client) send(ctx context.Context, method string, params []interface{}) (resp *RPCResponse, err error) {
 	request := RPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
 		Params:  params,
-		ID:      2,
+		ID:      c.nextID(),
 	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int(AttrRequestID, request.ID))
 
 	reqBody, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.rpcURL, "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	start := time.Now()
+	defer func() { c.observe(method, start, err) }()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := c.roundTrip(ctx, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var rpcResp RPCResponse
 	if err := json.Unmarshal(bodyBytes, &rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, decodeError("failed to unmarshal response: %w", err)
 	}
 
+	// Upstreams answer requests they cannot parse with a null id
+	if rpcResp.Error != nil && rpcResp.ID == 0 {
+		return nil, rpcResp.Error
+	}
+	if rpcResp.ID != request.ID {
+		return nil, c.idMismatch(ctx, method, request.ID, rpcResp.ID)
+	}
 	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error: %s (code: %d)", rpcResp.Error.Message, rpcResp.Error.Code)
+		return nil, rpcResp.Error
 	}
 
 	return &rpcResp, nil
 }
 
+// sendBatch sends requests as a single JSON-RPC batch, assigning each a
+// request id, and returns the responses in request order
+func (c *Client) sendBatch(ctx context.Context, requests []RPCRequest) (responses []*RPCResponse, err error) {
+	ids := make([]int, len(requests))
+	for i := range requests {
+		requests[i].ID = c.nextID()
+		ids[i] = requests[i].ID
+	}
+
+	ctx, span := c.startSpan(ctx, BatchMethod)
+	span.SetAttributes(attribute.Int(AttrBatchSize, len(requests)), attribute.IntSlice(AttrRequestIDs, ids))
+	defer func() {
+		endSpan(span, err)
+		span.End()
+	}()
+
+	reqBody, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	start := time.Now()
+	defer func() { c.observe(BatchMethod, start, err) }()
+
+	bodyBytes, err := c.roundTrip(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch []*RPCResponse
+	if err := json.Unmarshal(bodyBytes, &batch); err != nil {
+		// Upstreams that reject a batch outright reply with a single error
+		var single RPCResponse
+		if json.Unmarshal(bodyBytes, &single) == nil && single.Error != nil {
+			return nil, single.Error
+		}
+		return nil, decodeError("failed to unmarshal batch response: %w", err)
+	}
+
+	// Batch responses may arrive in any order
+	byID := make(map[int]*RPCResponse, len(batch))
+	for _, r := range batch {
+		byID[r.ID] = r
+	}
+	responses = make([]*RPCResponse, len(requests))
+	for i, req := range requests {
+		r, ok := byID[req.ID]
+		if ok {
+			responses[i] = r
+			delete(byID, req.ID)
+			continue
+		}
+		// A response under an id that was never sent means responses were
+		// mixed up; otherwise the upstream dropped one
+		for id := range byID {
+			if !slices.Contains(ids, id) {
+				return nil, c.idMismatch(ctx, BatchMethod, req.ID, id)
+			}
+		}
+		return nil, decodeError("missing response for request id %d", req.ID)
+	}
+
+	return responses, nil
+}
+
+// idMismatch logs a response id mismatch, with the request ids involved for
+// correlation with upstream logs, and returns the error
+func (c *Client) idMismatch(ctx context.Context, method string, sent, received int) error {
+	slog.WarnContext(ctx, "upstream response id mismatch", "upstream", RedactURL(c.rpcURL), "method", method, "sent_id", sent, "received_id", received)
+	return &IDMismatchError{Sent: sent, Received: received}
+}
+
+// roundTrip posts a request body to the upstream endpoint and returns the
+// response body. Failures are a *TransportError or, for non-200 responses, an
+// *HTTPStatusError. The trace context of ctx is propagated to the upstream in
+// the request headers.
+func (c *Client) roundTrip(ctx context.Context, reqBody []byte) ([]byte, error) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int(AttrAttempt, attempt(ctx)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, &TransportError{Err: fmt.Errorf("failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	injectTraceContext(ctx, req.Header)
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Transport errors quote the URL, which may embed an API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = RedactURL(urlErr.URL)
+		}
+		return nil, &TransportError{Err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int(AttrHTTPStatus, resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Err: fmt.Errorf("failed to read response body: %w", err)}
+	}
+	span.SetAttributes(attribute.Int(AttrResponseSize, len(bodyBytes)))
+
+	return bodyBytes, nil
+}
+
 // GetBlockNumber returns the latest block number
-func (c *Client) GetBlockNumber() (string, error) {
-	resp, err := c.call("eth_blockNumber", nil)
+func (c *Client) GetBlockNumber(ctx context.Context) (string, error) {
+	resp, err := c.call(ctx, "eth_blockNumber", nil)
 	if err != nil {
 		return "", err
 	}
 
 	var blockNumber string
 	if err := json.Unmarshal(resp.Result, &blockNumber); err != nil {
-		return "", fmt.Errorf("failed to unmarshal block number: %w", err)
+		return "", decodeError("failed to unmarshal block number: %w", err)
 	}
 
 	return blockNumber, nil
 }
 
+// GetChainID returns the chain ID reported by the upstream endpoint
+func (c *Client) GetChainID(ctx context.Context) (string, error) {
+	resp, err := c.call(ctx, "eth_chainId", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var chainID string
+	if err := json.Unmarshal(resp.Result, &chainID); err != nil {
+		return "", decodeError("failed to unmarshal chain ID: %w", err)
+	}
+
+	return chainID, nil
+}
+
 // Block represents an Ethereum block
 type Block struct {
 	Number           string          `json:"number"`
@@ -114,20 +303,104 @@ type Block struct {
 	ParentHash       string          `json:"parentHash"`
 	Nonce            string          `json:"nonce"`
 	Timestamp        string          `json:"timestamp"`
+	Miner            string          `json:"miner,omitempty"`
+	GasLimit         string          `json:"gasLimit,omitempty"`
+	GasUsed          string          `json:"gasUsed,omitempty"`
+	BaseFeePerGas    string          `json:"baseFeePerGas,omitempty"`
+	Size             string          `json:"size,omitempty"`
 	Transactions     json.RawMessage `json:"transactions"`
 	TransactionCount int             `json:"transactionCount"`
+
+	// Header fields needed to recompute the block hash
+	Sha3Uncles            string `json:"sha3Uncles,omitempty"`
+	StateRoot             string `json:"stateRoot,omitempty"`
+	TransactionsRoot      string `json:"transactionsRoot,omitempty"`
+	ReceiptsRoot          string `json:"receiptsRoot,omitempty"`
+	LogsBloom             string `json:"logsBloom,omitempty"`
+	Difficulty            string `json:"difficulty,omitempty"`
+	ExtraData             string `json:"extraData,omitempty"`
+	MixHash               string `json:"mixHash,omitempty"`
+	WithdrawalsRoot       string `json:"withdrawalsRoot,omitempty"`
+	BlobGasUsed           string `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas         string `json:"excessBlobGas,omitempty"`
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot,omitempty"`
+	RequestsHash          string `json:"requestsHash,omitempty"`
 }
 
 // GetBlockByNumber returns the block information by block number
-func (c *Client) GetBlockByNumber(blockNumber string, fullTransactions bool) (*Block, error) {
-	resp, err := c.call("eth_getBlockByNumber", []interface{}{blockNumber, fullTransactions})
+func (c *Client) GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*Block, error) {
+	resp, err := c.call(ctx, "eth_getBlockByNumber", []interface{}{blockNumber, fullTransactions})
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := decodeBlock(resp.Result, fullTransactions)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.checkBlock(block, fullTransactions); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// GetBlocksByNumber returns several blocks using a single batched upstream
+// request. Blocks the upstream does not know about are returned as nil.
+func (c *Client) GetBlocksByNumber(ctx context.Context, blockNumbers []string, fullTransactions bool) ([]*Block, error) {
+	requests := make([]RPCRequest, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		requests[i] = RPCRequest{
+			JSONRPC: "2.0",
+			Method:  "eth_getBlockByNumber",
+			Params:  []interface{}{blockNumber, fullTransactions},
+		}
+	}
+
+	responses, err := c.sendBatch(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*Block, len(responses))
+	for i, resp := range responses {
+		if resp.Error != nil {
+			return nil, fmt.Errorf("block %s: %w", blockNumbers[i], resp.Error)
+		}
+		if len(resp.Result) == 0 || string(resp.Result) == "null" {
+			continue
+		}
+		if blocks[i], err = decodeBlock(resp.Result, fullTransactions); err != nil {
+			return nil, err
+		}
+		if err := c.checkBlock(blocks[i], fullTransactions); err != nil {
+			return nil, err
+		}
+	}
 
+	return blocks, nil
+}
+
+// checkBlock verifies the block hash, and with full transactions the
+// transactions root, when block verification is enabled. Pending blocks have
+// no hash yet and are not checked.
+func (c *Client) checkBlock(block *Block, fullTransactions bool) error {
+	if !c.verifyBlocks || block.Hash == "" {
+		return nil
+	}
+	if err := block.VerifyHash(); err != nil {
+		return err
+	}
+	if fullTransactions {
+		return block.VerifyTransactionsRoot()
+	}
+	return nil
+}
+
+// decodeBlock unmarshals a block result and counts its transactions
+func decodeBlock(result json.RawMessage, fullTransactions bool) (*Block, error) {
 	var block Block
-	if err := json.Unmarshal(resp.Result, &block); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal block: %w", err)
+	if err := json.Unmarshal(result, &block); err != nil {
+		return nil, decodeError("failed to unmarshal block: %w", err)
 	}
 
 	var txCount int
@@ -147,6 +420,61 @@ func (c *Client) GetBlockByNumber(blockNumber string, fullTransactions bool) (*B
 	return &block, nil
 }
 
+// GetBlockReceipts returns the receipts of every transaction in a block
+func (c *Client) GetBlockReceipts(ctx context.Context, blockNumber string) ([]*Receipt, error) {
+	resp, err := c.call(ctx, "eth_getBlockReceipts", []interface{}{blockNumber})
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []*Receipt
+	if err := json.Unmarshal(resp.Result, &receipts); err != nil {
+		return nil, decodeError("failed to unmarshal block receipts: %w", err)
+	}
+
+	if c.verifyBlocks {
+		if err := c.checkReceipts(ctx, blockNumber, receipts); err != nil {
+			return nil, err
+		}
+	}
+	return receipts, nil
+}
+
+// GetTransactionByHash returns the raw JSON of the transaction with hash, or
+// nil when the upstream does not know about it
+func (c *Client) GetTransactionByHash(ctx context.Context, hash string) (json.RawMessage, error) {
+	resp, err := c.call(ctx, "eth_getTransactionByHash", []interface{}{hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return nil, nil
+	}
+	return resp.Result, nil
+}
+
+// checkReceipts verifies receipts against the receipts root of their block.
+// The header is fetched by the receipts' block hash, so that a tag such as
+// "latest" cannot resolve to a different block than it did for the receipts.
+func (c *Client) checkReceipts(ctx context.Context, blockNumber string, receipts []*Receipt) error {
+	method, param := "eth_getBlockByNumber", blockNumber
+	if len(receipts) > 0 {
+		method, param = "eth_getBlockByHash", receipts[0].BlockHash
+	}
+	resp, err := c.call(ctx, method, []interface{}{param, false})
+	if err != nil {
+		return fmt.Errorf("failed to fetch header to verify receipts: %w", err)
+	}
+	block, err := decodeBlock(resp.Result, false)
+	if err != nil {
+		return err
+	}
+	if err := block.VerifyHash(); err != nil {
+		return err
+	}
+	return block.VerifyReceipts(receipts)
+}
+
 // Create a helper method for tests
 // CreateMockBlock creates a block with the given data for testing purposes
 func CreateMockBlock(number, hash, parentHash, nonce, timestamp string, txCount int, txData json.RawMessage) *Block {
@@ -1,9 +1,14 @@
 package blockchain
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
@@ -28,8 +33,9 @@ func TestGetBlockNumber(t *testing.T) {
 			t.Errorf("expected jsonrpc 2.0, got %s", rpcReq.JSONRPC)
 		}
 
-		if rpcReq.ID != 2 {
-			t.Errorf("expected id 2, got %d", rpcReq.ID)
+		// A new client numbers its requests from 1
+		if rpcReq.ID != 1 {
+			t.Errorf("expected id 1, got %d", rpcReq.ID)
 		}
 
 		// Write response
@@ -48,7 +54,7 @@ func TestGetBlockNumber(t *testing.T) {
 	client := NewClient(server.URL)
 
 	// Test GetBlockNumber
-	blockNumber, err := client.GetBlockNumber()
+	blockNumber, err := client.GetBlockNumber(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -59,6 +65,34 @@ func TestGetBlockNumber(t *testing.T) {
 	}
 }
 
+func TestGetChainID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rpcReq RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&rpcReq); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		if rpcReq.Method != "eth_chainId" {
+			t.Errorf("expected eth_chainId method, got %s", rpcReq.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: rpcReq.ID, Result: json.RawMessage(`"0x89"`)})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	chainID, err := client.GetChainID(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if chainID != "0x89" {
+		t.Errorf("expected chain ID 0x89, got %s", chainID)
+	}
+}
+
 func TestGetBlockByNumber(t *testing.T) {
 	t.Run("with full transactions", func(t *testing.T) {
 		// Create a mock HTTP server
@@ -129,7 +163,7 @@ func TestGetBlockByNumber(t *testing.T) {
 		client := NewClient(server.URL)
 
 		// Test GetBlockByNumber with full transactions
-		block, err := client.GetBlockByNumber("0x1234567", true)
+		block, err := client.GetBlockByNumber(context.Background(), "0x1234567", true)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -204,7 +238,7 @@ func TestGetBlockByNumber(t *testing.T) {
 		client := NewClient(server.URL)
 
 		// Test GetBlockByNumber with transaction hashes only
-		block, err := client.GetBlockByNumber("0x1234567", false)
+		block, err := client.GetBlockByNumber(context.Background(), "0x1234567", false)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -242,9 +276,186 @@ func TestGetBlockByNumber(t *testing.T) {
 		client := NewClient(server.URL)
 
 		// Test GetBlockByNumber with an invalid block number
-		_, err := client.GetBlockByNumber("0xinvalid", true)
+		_, err := client.GetBlockByNumber(context.Background(), "0xinvalid", true)
 		if err == nil {
 			t.Errorf("expected error but got nil")
 		}
 	})
 }
+
+func TestGetBlocksByNumber(t *testing.T) {
+	t.Run("batched request", func(t *testing.T) {
+		requests := 0
+
+		// Create a mock HTTP server that answers batches out of order
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			var batch []RPCRequest
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				t.Fatalf("expected a batch request: %v", err)
+			}
+			if len(batch) != 3 {
+				t.Errorf("expected 3 requests in batch, got %d", len(batch))
+			}
+
+			var responses []RPCResponse
+			for i := len(batch) - 1; i >= 0; i-- {
+				req := batch[i]
+				result := json.RawMessage(`null`)
+				if req.Params[0] != "0x3" {
+					result, _ = json.Marshal(map[string]interface{}{
+						"number":       req.Params[0],
+						"transactions": []string{"0xtx"},
+					})
+				}
+				responses = append(responses, RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(responses)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+
+		blocks, err := client.GetBlocksByNumber(context.Background(), []string{"0x1", "0x2", "0x3"}, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("expected 1 upstream request, got %d", requests)
+		}
+		if blocks[0].Number != "0x1" || blocks[1].Number != "0x2" {
+			t.Errorf("expected blocks in request order, got %s and %s", blocks[0].Number, blocks[1].Number)
+		}
+		if blocks[0].TransactionCount != 1 {
+			t.Errorf("expected transaction count 1, got %d", blocks[0].TransactionCount)
+		}
+		if blocks[2] != nil {
+			t.Errorf("expected missing block to be nil")
+		}
+	})
+
+	t.Run("batch rejected by upstream", func(t *testing.T) {
+		// Create a mock HTTP server that does not support batches
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(RPCResponse{
+				JSONRPC: "2.0",
+				Error:   &RPCError{Code: -32600, Message: "batch requests not supported"},
+			})
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+
+		if _, err := client.GetBlocksByNumber(context.Background(), []string{"0x1"}, false); err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+}
+
+func TestGetTransactionByHash(t *testing.T) {
+	const known = "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rpcReq RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&rpcReq); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if rpcReq.Method != "eth_getTransactionByHash" {
+			t.Errorf("expected eth_getTransactionByHash method, got %s", rpcReq.Method)
+		}
+
+		result := json.RawMessage(`null`)
+		if rpcReq.Params[0] == known {
+			result = json.RawMessage(`{"hash":"` + known + `","blockNumber":"0x10"}`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: rpcReq.ID, Result: result})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	tx, err := client.GetTransactionByHash(context.Background(), known)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"hash":"` + known + `","blockNumber":"0x10"}`; string(tx) != want {
+		t.Errorf("expected %s, got %s", want, tx)
+	}
+
+	tx, err = client.GetTransactionByHash(context.Background(), "0x"+known[4:]+"00")
+	if err != nil || tx != nil {
+		t.Errorf("expected an unknown transaction to be nil, got %s, %v", tx, err)
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		if seen[req.ID] {
+			t.Errorf("request id %d sent twice", req.ID)
+		}
+		seen[req.ID] = true
+		mu.Unlock()
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"0x1"`)})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Distinct params so that the calls are not coalesced
+			if _, err := client.call(context.Background(), "eth_getBalance", []interface{}{i}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 20 {
+		t.Errorf("expected 20 distinct request ids, got %d", len(seen))
+	}
+}
+
+func TestResponseIDMismatch(t *testing.T) {
+	// The proxy answers every request with the response to request 7
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.HasPrefix(body, []byte("[")) {
+			w.Write([]byte(`[{"jsonrpc":"2.0","id":7,"result":null}]`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":7,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var mismatch *IDMismatchError
+	_, err := client.GetBlockNumber(context.Background())
+	if !errors.As(err, &mismatch) || mismatch.Sent != 1 || mismatch.Received != 7 {
+		t.Errorf("expected an id mismatch, got %v", err)
+	}
+
+	_, err = client.GetBlocksByNumber(context.Background(), []string{"0x1"}, false)
+	if !errors.As(err, &mismatch) || mismatch.Sent != 2 || mismatch.Received != 7 {
+		t.Errorf("expected an id mismatch in the batch, got %v", err)
+	}
+
+	// Errors for requests the upstream could not parse carry a null id
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`))
+	})
+	var rpcErr *RPCError
+	if _, err := client.GetBlockNumber(context.Background()); !errors.As(err, &rpcErr) || rpcErr.Code != -32700 {
+		t.Errorf("expected the upstream parse error, got %v", err)
+	}
+}
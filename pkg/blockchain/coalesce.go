@@ -0,0 +1,140 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CoalesceStats represents counters for upstream call deduplication
+type CoalesceStats struct {
+	// Requests is the number of calls made through the client
+	Requests uint64 `json:"requests"`
+	// Upstream is the number of calls actually sent to the RPC endpoint
+	Upstream uint64 `json:"upstream"`
+	// Coalesced is the number of calls that shared another call's round-trip
+	Coalesced uint64 `json:"coalesced"`
+	// CoalescedByMethod breaks Coalesced down by JSON-RPC method
+	CoalescedByMethod map[string]uint64 `json:"coalescedByMethod"`
+}
+
+// flight represents an upstream call shared by concurrent identical callers.
+// The call runs until it completes or every caller waiting on it has given up.
+type flight struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	resp   *RPCResponse
+	err    error
+
+	// waiters is the number of callers still waiting, guarded by the
+	// coalescer's mutex
+	waiters int
+}
+
+// coalescer deduplicates concurrent identical upstream calls so that callers
+// asking for the same method and params while a call is in flight wait for
+// that call instead of issuing their own
+type coalescer struct {
+	mu       sync.Mutex
+	inflight map[string]*flight
+	stats    CoalesceStats
+}
+
+// newCoalescer creates a new coalescer
+func newCoalescer() *coalescer {
+	return &coalescer{
+		inflight: make(map[string]*flight),
+		stats:    CoalesceStats{CoalescedByMethod: make(map[string]uint64)},
+	}
+}
+
+// coalesceKey builds the deduplication key for a method and its params
+func coalesceKey(method string, params []interface{}) (string, error) {
+	paramBytes, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return method + "\x00" + string(paramBytes), nil
+}
+
+// do runs fn for key unless an identical call is already in flight, in which
+// case it waits for and returns that call's result. shared reports whether the
+// result came from another caller's call.
+//
+// fn runs on a context that keeps the values of ctx but is cancelled only
+// once every caller waiting on it has returned, so one caller giving up does
+// not fail the others. Each caller returns as soon as its own ctx is done.
+func (g *coalescer) do(ctx context.Context, method, key string, fn func(context.Context) (*RPCResponse, error)) (resp *RPCResponse, shared bool, err error) {
+	g.mu.Lock()
+	g.stats.Requests++
+	f, shared := g.inflight[key]
+	if shared {
+		g.stats.Coalesced++
+		g.stats.CoalescedByMethod[method]++
+	} else {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.inflight[key] = f
+		g.stats.Upstream++
+		go g.run(callCtx, key, f, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.resp, shared, f.err
+	case <-ctx.Done():
+		g.leave(key, f)
+		return nil, shared, ctx.Err()
+	}
+}
+
+// run makes the shared call and releases its waiters. A panic in fn is
+// returned to the waiters as an error so that done is always closed.
+func (g *coalescer) run(ctx context.Context, key string, f *flight, fn func(context.Context) (*RPCResponse, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.resp, f.err = nil, fmt.Errorf("upstream call panicked: %v", r)
+		}
+		g.mu.Lock()
+		if g.inflight[key] == f {
+			delete(g.inflight, key)
+		}
+		g.mu.Unlock()
+		f.cancel()
+		close(f.done)
+	}()
+
+	f.resp, f.err = fn(ctx)
+}
+
+// leave records that a caller stopped waiting on f, cancelling the call once
+// no callers are left. Later identical calls start a new flight.
+func (g *coalescer) leave(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	if g.inflight[key] == f {
+		delete(g.inflight, key)
+	}
+	f.cancel()
+}
+
+// snapshot returns a copy of the current counters
+func (g *coalescer) snapshot() CoalesceStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := g.stats
+	stats.CoalescedByMethod = make(map[string]uint64, len(g.stats.CoalescedByMethod))
+	for method, n := range g.stats.CoalescedByMethod {
+		stats.CoalescedByMethod[method] = n
+	}
+	return stats
+}
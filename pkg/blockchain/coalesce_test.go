@@ -0,0 +1,246 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForRequests blocks until the client has seen n calls
+func waitForRequests(t *testing.T, client *Client, n uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.CoalesceStats().Requests < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d requests", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCallCoalescing(t *testing.T) {
+	t.Run("identical concurrent calls share one round-trip", func(t *testing.T) {
+		var upstreamCalls atomic.Int32
+		release := make(chan struct{})
+
+		// Create a mock HTTP server that holds requests until released
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamCalls.Add(1)
+			<-release
+
+			var rpcReq RPCRequest
+			json.NewDecoder(r.Body).Decode(&rpcReq)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(RPCResponse{
+				JSONRPC: "2.0",
+				ID:      rpcReq.ID,
+				Result:  json.RawMessage(`"0x1234567"`),
+			})
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+
+		const callers = 50
+		var wg sync.WaitGroup
+		results := make([]string, callers)
+		errs := make([]error, callers)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = client.GetBlockNumber(context.Background())
+			}(i)
+		}
+
+		waitForRequests(t, client, callers)
+		close(release)
+		wg.Wait()
+
+		for i := 0; i < callers; i++ {
+			if errs[i] != nil {
+				t.Errorf("caller %d: unexpected error: %v", i, errs[i])
+			}
+			if results[i] != "0x1234567" {
+				t.Errorf("caller %d: expected block number 0x1234567, got %s", i, results[i])
+			}
+		}
+
+		if got := upstreamCalls.Load(); got != 1 {
+			t.Errorf("expected 1 upstream call, got %d", got)
+		}
+
+		stats := client.CoalesceStats()
+		if stats.Upstream != 1 {
+			t.Errorf("expected 1 upstream call in stats, got %d", stats.Upstream)
+		}
+		if stats.Coalesced != callers-1 {
+			t.Errorf("expected %d coalesced calls, got %d", callers-1, stats.Coalesced)
+		}
+		if stats.CoalescedByMethod["eth_blockNumber"] != callers-1 {
+			t.Errorf("expected %d coalesced eth_blockNumber calls, got %d", callers-1, stats.CoalescedByMethod["eth_blockNumber"])
+		}
+	})
+
+	t.Run("different params are not coalesced", func(t *testing.T) {
+		var upstreamCalls atomic.Int32
+
+		// Create a mock HTTP server that echoes the requested block number
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamCalls.Add(1)
+
+			var rpcReq RPCRequest
+			json.NewDecoder(r.Body).Decode(&rpcReq)
+
+			result, _ := json.Marshal(map[string]interface{}{
+				"number":       rpcReq.Params[0],
+				"transactions": []string{},
+			})
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(RPCResponse{
+				JSONRPC: "2.0",
+				ID:      rpcReq.ID,
+				Result:  result,
+			})
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+
+		first, err := client.GetBlockByNumber(context.Background(), "0x1", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := client.GetBlockByNumber(context.Background(), "0x2", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if first.Number != "0x1" || second.Number != "0x2" {
+			t.Errorf("expected blocks 0x1 and 0x2, got %s and %s", first.Number, second.Number)
+		}
+		if got := upstreamCalls.Load(); got != 2 {
+			t.Errorf("expected 2 upstream calls, got %d", got)
+		}
+		if stats := client.CoalesceStats(); stats.Coalesced != 0 {
+			t.Errorf("expected no coalesced calls, got %d", stats.Coalesced)
+		}
+	})
+
+	t.Run("errors are shared with waiting callers", func(t *testing.T) {
+		release := make(chan struct{})
+
+		// Create a mock HTTP server that fails after being released
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 3)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = client.GetBlockNumber(context.Background())
+			}(i)
+		}
+
+		waitForRequests(t, client, 3)
+		close(release)
+		wg.Wait()
+
+		for i, err := range errs {
+			if err == nil {
+				t.Errorf("caller %d: expected error but got nil", i)
+			}
+		}
+	})
+	t.Run("callers return when their context is done", func(t *testing.T) {
+		release := make(chan struct{})
+		cancelled := make(chan struct{})
+
+		// Create a mock HTTP server that hangs until released or abandoned
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rpcReq RPCRequest
+			json.NewDecoder(r.Body).Decode(&rpcReq)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			}
+			json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: rpcReq.ID, Result: json.RawMessage(`"0x1"`)})
+		}))
+		defer server.Close()
+		defer close(release)
+
+		client := NewClient(server.URL)
+
+		// A caller that stays keeps the shared call alive for the others
+		waiting := make(chan error, 1)
+		go func() {
+			_, err := client.GetBlockNumber(context.Background())
+			waiting <- err
+		}()
+		waitForRequests(t, client, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := client.GetBlockNumber(ctx); err != context.DeadlineExceeded {
+			t.Errorf("expected the caller's deadline to be exceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the caller to return at its deadline, took %s", elapsed)
+		}
+		select {
+		case err := <-waiting:
+			t.Fatalf("expected the other caller to keep waiting, got %v", err)
+		case <-cancelled:
+			t.Fatal("expected the shared call to continue while a caller waits")
+		default:
+		}
+
+		// Once every caller has gone the upstream request is cancelled
+		ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		other := NewClient(server.URL)
+		if _, err := other.GetBlockNumber(ctx); err != context.DeadlineExceeded {
+			t.Errorf("expected the caller's deadline to be exceeded, got %v", err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Error("expected the abandoned upstream request to be cancelled")
+		}
+	})
+
+	t.Run("a panicking call releases its waiters", func(t *testing.T) {
+		g := newCoalescer()
+		_, _, err := g.do(context.Background(), "eth_blockNumber", "key", func(context.Context) (*RPCResponse, error) {
+			panic("boom")
+		})
+		if err == nil {
+			t.Fatal("expected the panic to be returned as an error")
+		}
+
+		resp, shared, err := g.do(context.Background(), "eth_blockNumber", "key", func(context.Context) (*RPCResponse, error) {
+			return &RPCResponse{Result: json.RawMessage(`"0x1"`)}, nil
+		})
+		if err != nil || shared || string(resp.Result) != `"0x1"` {
+			t.Errorf("expected a new call after the panic, got %v %v %v", resp, shared, err)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"blockchain-client/pkg/rlp"
+)
+
+// Transaction and receipt types
+const (
+	TxTypeLegacy     = 0x00
+	TxTypeAccessList = 0x01
+	TxTypeDynamicFee = 0x02
+	TxTypeBlob       = 0x03
+	TxTypeSetCode    = 0x04
+	TxTypeDeposit    = 0x7e
+)
+
+// ErrUnsupportedType is returned for transaction types that cannot be
+// encoded, such as chain-specific types other than OP Stack deposits
+var ErrUnsupportedType = errors.New("unsupported transaction type")
+
+// fieldEncoder accumulates the RLP-encoded fields of a list, keeping the
+// first error
+type fieldEncoder struct {
+	items [][]byte
+	err   error
+}
+
+func (e *fieldEncoder) add(name string, item []byte, err error) {
+	if err != nil {
+		if e.err == nil {
+			e.err = fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return
+	}
+	e.items = append(e.items, item)
+}
+
+func (e *fieldEncoder) quantity(name, value string) {
+	item, err := encodeQuantity(value)
+	e.add(name, item, err)
+}
+
+func (e *fieldEncoder) data(name, value string) {
+	item, err := encodeData(value)
+	e.add(name, item, err)
+}
+
+// address encodes a recipient, which is empty for contract creations
+func (e *fieldEncoder) address(name, value string) {
+	if value == "" {
+		e.add(name, rlp.EncodeBytes(nil), nil)
+		return
+	}
+	e.data(name, value)
+}
+
+func (e *fieldEncoder) boolean(value bool) {
+	if value {
+		e.items = append(e.items, rlp.EncodeUint(1))
+	} else {
+		e.items = append(e.items, rlp.EncodeUint(0))
+	}
+}
+
+// dataList encodes a list of hex-encoded byte strings
+func (e *fieldEncoder) dataList(name string, values []string) {
+	var l fieldEncoder
+	for _, v := range values {
+		l.data(name, v)
+	}
+	e.nested(name, &l)
+}
+
+// nested adds the list built by l
+func (e *fieldEncoder) nested(name string, l *fieldEncoder) {
+	if l.err != nil {
+		if e.err == nil {
+			e.err = l.err
+		}
+		return
+	}
+	e.items = append(e.items, rlp.EncodeList(l.items...))
+}
+
+func (e *fieldEncoder) accessList(list []AccessTuple) {
+	var l fieldEncoder
+	for _, tuple := range list {
+		var t fieldEncoder
+		t.data("accessList address", tuple.Address)
+		t.dataList("accessList storage key", tuple.StorageKeys)
+		l.nested("accessList", &t)
+	}
+	e.nested("accessList", &l)
+}
+
+func (e *fieldEncoder) authorizations(list []Authorization) {
+	var l fieldEncoder
+	for _, auth := range list {
+		var a fieldEncoder
+		a.quantity("authorization chainId", auth.ChainID)
+		a.data("authorization address", auth.Address)
+		a.quantity("authorization nonce", auth.Nonce)
+		a.quantity("authorization yParity", auth.YParity)
+		a.quantity("authorization r", auth.R)
+		a.quantity("authorization s", auth.S)
+		l.nested("authorizationList", &a)
+	}
+	e.nested("authorizationList", &l)
+}
+
+func (e *fieldEncoder) logs(logs []*Log) {
+	var l fieldEncoder
+	for _, log := range logs {
+		var le fieldEncoder
+		le.data("log address", log.Address)
+		le.dataList("log topic", log.Topics)
+		le.data("log data", log.Data)
+		l.nested("logs", &le)
+	}
+	e.nested("logs", &l)
+}
+
+// list returns the RLP list of the fields
+func (e *fieldEncoder) list() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return rlp.EncodeList(e.items...), nil
+}
+
+// encode returns the RLP list of the fields, prefixed with typ unless it is a
+// legacy type
+func (e *fieldEncoder) encode(typ uint64) ([]byte, error) {
+	payload, err := e.list()
+	if err != nil || typ == TxTypeLegacy {
+		return payload, err
+	}
+	return append([]byte{byte(typ)}, payload...), nil
+}
+
+// txType returns the transaction's type, which is legacy when unset
+func txType(typ string) (uint64, error) {
+	if typ == "" {
+		return TxTypeLegacy, nil
+	}
+	return ParseQuantity(typ)
+}
+
+// yParity returns the signature parity of a typed transaction, which older
+// nodes report only as v
+func (tx *Transaction) yParity() string {
+	if tx.YParity != "" {
+		return tx.YParity
+	}
+	return tx.V
+}
+
+// Encode returns the transaction's consensus encoding: the RLP list of a
+// legacy transaction, or the type byte followed by the RLP payload of a typed
+// one. Its Keccak-256 hash is the transaction hash.
+func (tx *Transaction) Encode() ([]byte, error) {
+	typ, err := txType(tx.Type)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type: %w", err)
+	}
+
+	var e fieldEncoder
+	switch typ {
+	case TxTypeLegacy:
+		e.quantity("nonce", tx.Nonce)
+		e.quantity("gasPrice", tx.GasPrice)
+		e.quantity("gas", tx.Gas)
+		e.address("to", tx.To)
+		e.quantity("value", tx.Value)
+		e.data("input", tx.Input)
+		e.quantity("v", tx.V)
+		e.quantity("r", tx.R)
+		e.quantity("s", tx.S)
+
+	case TxTypeAccessList, TxTypeDynamicFee, TxTypeBlob, TxTypeSetCode:
+		e.quantity("chainId", tx.ChainID)
+		e.quantity("nonce", tx.Nonce)
+		if typ == TxTypeAccessList {
+			e.quantity("gasPrice", tx.GasPrice)
+		} else {
+			e.quantity("maxPriorityFeePerGas", tx.MaxPriorityFeePerGas)
+			e.quantity("maxFeePerGas", tx.MaxFeePerGas)
+		}
+		e.quantity("gas", tx.Gas)
+		e.address("to", tx.To)
+		e.quantity("value", tx.Value)
+		e.data("input", tx.Input)
+		e.accessList(tx.AccessList)
+		switch typ {
+		case TxTypeBlob:
+			e.quantity("maxFeePerBlobGas", tx.MaxFeePerBlobGas)
+			e.dataList("blobVersionedHashes", tx.BlobVersionedHashes)
+		case TxTypeSetCode:
+			e.authorizations(tx.AuthorizationList)
+		}
+		e.quantity("yParity", tx.yParity())
+		e.quantity("r", tx.R)
+		e.quantity("s", tx.S)
+
+	case TxTypeDeposit:
+		e.data("sourceHash", tx.SourceHash)
+		e.data("from", tx.From)
+		e.address("to", tx.To)
+		e.quantity("mint", valueOrZero(tx.Mint))
+		e.quantity("value", tx.Value)
+		e.quantity("gas", tx.Gas)
+		e.boolean(tx.IsSystemTx)
+		e.data("input", tx.Input)
+
+	default:
+		return nil, fmt.Errorf("%w %#x", ErrUnsupportedType, typ)
+	}
+	return e.encode(typ)
+}
+
+// ComputeHash recomputes the transaction hash from its fields
+func (tx *Transaction) ComputeHash() (string, error) {
+	enc, err := tx.Encode()
+	if err != nil {
+		return "", err
+	}
+	return encodeHash(Keccak256(enc)), nil
+}
+
+// valueOrZero returns the quantity v, or zero when it is unset
+func valueOrZero(v string) string {
+	if v == "" {
+		return "0x0"
+	}
+	return v
+}
+
+// Encode returns the receipt's consensus encoding, prefixed with its type
+// unless it is a legacy receipt
+func (r *Receipt) Encode() ([]byte, error) {
+	typ, err := txType(r.Type)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type: %w", err)
+	}
+	if typ > TxTypeSetCode && typ != TxTypeDeposit {
+		return nil, fmt.Errorf("%w %#x", ErrUnsupportedType, typ)
+	}
+
+	var e fieldEncoder
+	// Nodes report an empty root for receipts that carry a status
+	if r.Root != "" && r.Root != "0x" {
+		e.data("root", r.Root)
+	} else {
+		e.quantity("status", r.Status)
+	}
+	e.quantity("cumulativeGasUsed", r.CumulativeGasUsed)
+	e.data("logsBloom", r.LogsBloom)
+	e.logs(r.Logs)
+
+	if typ == TxTypeDeposit {
+		if r.DepositNonce != "" {
+			e.quantity("depositNonce", r.DepositNonce)
+		}
+		if r.DepositReceiptVersion != "" {
+			e.quantity("depositReceiptVersion", r.DepositReceiptVersion)
+		}
+	}
+	return e.encode(typ)
+}
@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RPCError is an error returned by the upstream in a JSON-RPC response. Data
+// holds any detail the upstream attached, such as the revert data of a
+// failed call.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error: %s (code: %d)", e.Message, e.Code)
+}
+
+// TransportError is returned when a request could not be sent to the
+// upstream or its response could not be read
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError is returned when the upstream answers with a status other
+// than 200 OK
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// DecodeError is returned when an upstream response is not valid JSON-RPC or
+// its result does not have the expected shape
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// IDMismatchError is returned when the upstream answers a request with a
+// response for a different request id, as misbehaving proxies that mix up
+// responses do
+type IDMismatchError struct {
+	Sent     int
+	Received int
+}
+
+func (e *IDMismatchError) Error() string {
+	return fmt.Sprintf("upstream response id %d does not match request id %d", e.Received, e.Sent)
+}
+
+// decodeError wraps a failure to decode an upstream response
+func decodeError(format string, args ...any) error {
+	return &DecodeError{Err: fmt.Errorf(format, args...)}
+}
+
+// retryable reports whether err may be particular to the upstream that
+// returned it, making the call worth retrying on another: transport failures,
+// timeouts, rate limiting, server errors, malformed or mismatched responses,
+// and blocks that fail hash or root verification. JSON-RPC errors are answers
+// the next upstream would give as well.
+func retryable(err error) bool {
+	var (
+		transportErr *TransportError
+		statusErr    *HTTPStatusError
+		decodeErr    *DecodeError
+		idErr        *IDMismatchError
+		hashErr      *HashMismatchError
+		rootErr      *RootMismatchError
+	)
+	switch {
+	case errors.As(err, &transportErr), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &decodeErr), errors.As(err, &idErr):
+		return true
+	case errors.As(err, &hashErr), errors.As(err, &rootErr), errors.Is(err, ErrIncompleteHeader):
+		return true
+	}
+	return false
+}
+
+// errorCode classifies err for observers: empty for success, the JSON-RPC
+// error code for RPC errors, "http_<status>" for non-200 responses, or
+// ErrorCodeTransport, ErrorCodeDecode or ErrorCodeIDMismatch
+func errorCode(err error) string {
+	var (
+		rpcErr    *RPCError
+		statusErr *HTTPStatusError
+		decodeErr *DecodeError
+		idErr     *IDMismatchError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &rpcErr):
+		return strconv.Itoa(rpcErr.Code)
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("http_%d", statusErr.StatusCode)
+	case errors.As(err, &decodeErr):
+		return ErrorCodeDecode
+	case errors.As(err, &idErr):
+		return ErrorCodeIDMismatch
+	}
+	return ErrorCodeTransport
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// observerFunc adapts a function to CallObserver
+type observerFunc func(CallInfo)
+
+func (f observerFunc) ObserveCall(call CallInfo) { f(call) }
+
+func TestClientErrorTypes(t *testing.T) {
+	revert := `{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted","data":"0x08c379a0"}}`
+	handlers := map[string]http.HandlerFunc{
+		"rpc": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(revert))
+		},
+		"status": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		},
+		"decode": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>"))
+		},
+		"result": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":42}`))
+		},
+	}
+
+	errs := make(map[string]error)
+	codes := make(map[string]string)
+	for name, handler := range handlers {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		client := NewClient(server.URL)
+		client.AddObserver(observerFunc(func(call CallInfo) { codes[name] = call.ErrorCode }))
+		_, errs[name] = client.GetBlockNumber(context.Background())
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	_, errs["transport"] = NewClient(closed.URL).GetBlockNumber(context.Background())
+
+	var rpcErr *RPCError
+	if !errors.As(errs["rpc"], &rpcErr) || rpcErr.Code != 3 || rpcErr.Message != "execution reverted" || string(rpcErr.Data) != `"0x08c379a0"` {
+		t.Errorf("expected the upstream error with its code and data, got %#v", errs["rpc"])
+	}
+	if errs["rpc"].Error() != "RPC error: execution reverted (code: 3)" {
+		t.Errorf("unexpected message %q", errs["rpc"])
+	}
+
+	var statusErr *HTTPStatusError
+	if !errors.As(errs["status"], &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected an HTTP status error, got %#v", errs["status"])
+	}
+
+	var decodeErr *DecodeError
+	for _, name := range []string{"decode", "result"} {
+		if !errors.As(errs[name], &decodeErr) {
+			t.Errorf("%s: expected a decode error, got %#v", name, errs[name])
+		}
+	}
+
+	var transportErr *TransportError
+	if !errors.As(errs["transport"], &transportErr) {
+		t.Errorf("expected a transport error, got %#v", errs["transport"])
+	}
+
+	want := map[string]string{"rpc": "3", "status": "http_429", "decode": ErrorCodeDecode, "result": ""}
+	for name, code := range want {
+		if codes[name] != code {
+			t.Errorf("%s: expected observed error code %q, got %q", name, code, codes[name])
+		}
+	}
+}
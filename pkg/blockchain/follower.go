@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultFollowInterval is how often the head follower polls for a new head
+const DefaultFollowInterval = 2 * time.Second
+
+// HeadSource is the subset of the client used by the head follower
+type HeadSource interface {
+	GetBlockNumber(ctx context.Context) (string, error)
+}
+
+// HeadFollower polls the chain head and notifies subscribers whenever it
+// advances
+type HeadFollower struct {
+	source   HeadSource
+	interval time.Duration
+
+	mu          sync.Mutex
+	head        uint64
+	updated     time.Time
+	subscribers map[chan uint64]struct{}
+}
+
+// NewHeadFollower creates a new head follower polling source every interval
+func NewHeadFollower(source HeadSource, interval time.Duration) *HeadFollower {
+	if interval <= 0 {
+		interval = DefaultFollowInterval
+	}
+	return &HeadFollower{
+		source:      source,
+		interval:    interval,
+		subscribers: make(map[chan uint64]struct{}),
+	}
+}
+
+// Head returns the latest observed head and when it was observed
+func (f *HeadFollower) Head() (uint64, time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, f.updated
+}
+
+// Subscribe returns a channel that receives every new head, and a function
+// that cancels the subscription. Slow subscribers miss intermediate heads
+// rather than blocking the follower; the latest head is always delivered.
+func (f *HeadFollower) Subscribe() (<-chan uint64, func()) {
+	ch := make(chan uint64, 1)
+
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	if f.head > 0 {
+		ch <- f.head
+	}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subscribers, ch)
+		f.mu.Unlock()
+	}
+}
+
+// Run polls the head until ctx is cancelled
+func (f *HeadFollower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		f.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the current head and publishes it if it advanced
+func (f *HeadFollower) poll(ctx context.Context) {
+	blockNumber, err := f.source.GetBlockNumber(ctx)
+	if err != nil {
+		slog.Warn("head follower: failed to get block number", "error", err)
+		return
+	}
+	head, err := ParseQuantity(blockNumber)
+	if err != nil {
+		slog.Warn("head follower: invalid block number", "error", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.updated = time.Now()
+	if head <= f.head {
+		return
+	}
+	f.head = head
+
+	for ch := range f.subscribers {
+		// Replace any undelivered head with the newest one
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- head
+	}
+}
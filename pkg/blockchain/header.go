@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"blockchain-client/pkg/rlp"
+)
+
+// ErrIncompleteHeader is returned when a block lacks the header fields needed
+// to recompute its hash, e.g. one stored before they were recorded
+var ErrIncompleteHeader = errors.New("block header is incomplete")
+
+// HashMismatchError reports a block whose hash does not match its header
+type HashMismatchError struct {
+	Number   string
+	Reported string
+	Computed string
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("block %s hash mismatch: upstream reported %s, header hashes to %s", e.Number, e.Reported, e.Computed)
+}
+
+// WithBlockVerification makes the client recompute the hash of every block it
+// fetches from the header fields, check full transactions and block receipts
+// against the header's roots, and reject any that do not match
+func WithBlockVerification() ClientOption {
+	return func(c *Client) {
+		c.verifyBlocks = true
+	}
+}
+
+// Keccak256 returns the Keccak-256 hash of the concatenation of data
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// ComputeHash recomputes the block hash from the header fields. The header
+// variant follows from the fields present: London adds the base fee,
+// Shanghai the withdrawals root, Cancun the blob gas fields and the parent
+// beacon block root, and Prague the requests hash. Polygon blocks carry the
+// validator's seal in extraData, which is hashed like any other extraData.
+func (b *Block) ComputeHash() (string, error) {
+	enc, err := b.encodeHeader()
+	if err != nil {
+		return "", err
+	}
+	return encodeHash(Keccak256(enc)), nil
+}
+
+// VerifyHash checks that the block hash matches the header fields
+func (b *Block) VerifyHash() error {
+	computed, err := b.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(computed, b.Hash) {
+		return &HashMismatchError{Number: b.Number, Reported: b.Hash, Computed: computed}
+	}
+	return nil
+}
+
+// headerField is a header value and how it is encoded
+type headerField struct {
+	name     string
+	value    string
+	quantity bool
+}
+
+// encodeHeader returns the RLP encoding of the block header
+func (b *Block) encodeHeader() ([]byte, error) {
+	fields := []headerField{
+		{"parentHash", b.ParentHash, false},
+		{"sha3Uncles", b.Sha3Uncles, false},
+		{"miner", b.Miner, false},
+		{"stateRoot", b.StateRoot, false},
+		{"transactionsRoot", b.TransactionsRoot, false},
+		{"receiptsRoot", b.ReceiptsRoot, false},
+		{"logsBloom", b.LogsBloom, false},
+		{"difficulty", b.Difficulty, true},
+		{"number", b.Number, true},
+		{"gasLimit", b.GasLimit, true},
+		{"gasUsed", b.GasUsed, true},
+		{"timestamp", b.Timestamp, true},
+		{"extraData", b.ExtraData, false},
+		{"mixHash", b.MixHash, false},
+		{"nonce", b.Nonce, false},
+	}
+	// Fields added by later forks are appended in order, and each implies
+	// the ones before it
+	optional := []headerField{
+		{"baseFeePerGas", b.BaseFeePerGas, true},
+		{"withdrawalsRoot", b.WithdrawalsRoot, false},
+		{"blobGasUsed", b.BlobGasUsed, true},
+		{"excessBlobGas", b.ExcessBlobGas, true},
+		{"parentBeaconBlockRoot", b.ParentBeaconBlockRoot, false},
+		{"requestsHash", b.RequestsHash, false},
+	}
+	last := -1
+	for i, f := range optional {
+		if f.value != "" {
+			last = i
+		}
+	}
+	fields = append(fields, optional[:last+1]...)
+
+	items := make([][]byte, len(fields))
+	for i, f := range fields {
+		if f.value == "" {
+			return nil, fmt.Errorf("%w: missing %s", ErrIncompleteHeader, f.name)
+		}
+		var err error
+		if f.quantity {
+			items[i], err = encodeQuantity(f.value)
+		} else {
+			items[i], err = encodeData(f.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+	}
+	return rlp.EncodeList(items...), nil
+}
+
+// encodeHash formats a hash as 0x-prefixed hex
+func encodeHash(h []byte) string {
+	return "0x" + hex.EncodeToString(h)
+}
+
+// decodeData decodes a hex-encoded byte string such as a hash or address
+func decodeData(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("invalid data %q: missing 0x prefix", s)
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid data %q: %w", s, err)
+	}
+	return b, nil
+}
+
+// decodeBig decodes a hex-encoded JSON-RPC quantity of any size
+func decodeBig(s string) (*big.Int, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("invalid quantity %q: missing 0x prefix", s)
+	}
+	n, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
+
+// encodeData RLP-encodes a hex-encoded byte string
+func encodeData(s string) ([]byte, error) {
+	b, err := decodeData(s)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeBytes(b), nil
+}
+
+// encodeQuantity RLP-encodes a hex-encoded JSON-RPC quantity
+func encodeQuantity(s string) ([]byte, error) {
+	n, err := decodeBig(s)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeBig(n), nil
+}
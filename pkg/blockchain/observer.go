@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"net/url"
+	"time"
+)
+
+// BatchMethod is the method reported to observers for batched requests
+const BatchMethod = "batch"
+
+// Error codes reported to observers for failures that carry no JSON-RPC code
+const (
+	// ErrorCodeTransport marks failures to reach or read from the upstream
+	ErrorCodeTransport = "transport"
+	// ErrorCodeDecode marks upstream responses that could not be decoded
+	ErrorCodeDecode = "decode"
+	// ErrorCodeIDMismatch marks responses to a request id that was not sent
+	ErrorCodeIDMismatch = "id_mismatch"
+)
+
+// CallInfo describes a completed upstream round-trip
+type CallInfo struct {
+	// Endpoint is the upstream URL the call was sent to
+	Endpoint string
+	Method   string
+	Duration time.Duration
+	// ErrorCode is empty on success, the JSON-RPC error code for RPC errors,
+	// "http_<status>" for non-200 responses, or ErrorCodeTransport,
+	// ErrorCodeDecode or ErrorCodeIDMismatch
+	ErrorCode string
+	Err       error
+}
+
+// CallObserver receives the outcome of every upstream round-trip. Calls that
+// were coalesced into another caller's round-trip are not reported.
+type CallObserver interface {
+	ObserveCall(call CallInfo)
+}
+
+// AddObserver registers an observer for upstream calls. Observers must be
+// added before the client is used.
+func (c *Client) AddObserver(o CallObserver) {
+	c.observers = append(c.observers, o)
+}
+
+// Endpoint returns the upstream URL the client sends requests to
+func (c *Client) Endpoint() string {
+	return c.rpcURL
+}
+
+// observe reports a completed round-trip to every observer
+func (c *Client) observe(method string, start time.Time, err error) {
+	if len(c.observers) == 0 {
+		return
+	}
+
+	call := CallInfo{
+		Endpoint:  c.rpcURL,
+		Method:    method,
+		Duration:  time.Since(start),
+		ErrorCode: errorCode(err),
+		Err:       err,
+	}
+	for _, o := range c.observers {
+		o.ObserveCall(call)
+	}
+}
+
+// EndpointHost returns the host of an upstream URL, which identifies the
+// endpoint without exposing API keys embedded in its path or query
+func EndpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// RedactURL returns an upstream URL with its path, query and credentials
+// replaced, since providers commonly embed API keys in them
+func RedactURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+
+	redacted := u.Scheme + "://" + u.Host
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		redacted += "/REDACTED"
+	}
+	return redacted
+}
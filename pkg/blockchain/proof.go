@@ -0,0 +1,140 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"blockchain-client/pkg/rlp"
+	"blockchain-client/pkg/trie"
+)
+
+// ErrInvalidProof is returned when an account or storage proof does not
+// match the state root it is checked against
+var ErrInvalidProof = trie.ErrInvalidProof
+
+// emptyCodeHash is the code hash of accounts without code
+var emptyCodeHash = Keccak256(nil)
+
+// AccountProof represents the result of eth_getProof: an account's state and
+// the trie nodes proving it, with proofs for the requested storage slots
+type AccountProof struct {
+	Address      string         `json:"address"`
+	AccountProof []string       `json:"accountProof"`
+	Balance      string         `json:"balance"`
+	CodeHash     string         `json:"codeHash"`
+	Nonce        string         `json:"nonce"`
+	StorageHash  string         `json:"storageHash"`
+	StorageProof []StorageProof `json:"storageProof"`
+}
+
+// StorageProof represents the value of one storage slot and its proof
+type StorageProof struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+// GetProof returns the state of address, and of its storageKeys, at a block
+func (c *Client) GetProof(ctx context.Context, address string, storageKeys []string, blockNumber string) (*AccountProof, error) {
+	if storageKeys == nil {
+		storageKeys = []string{}
+	}
+	resp, err := c.call(ctx, "eth_getProof", []interface{}{address, storageKeys, blockNumber})
+	if err != nil {
+		return nil, err
+	}
+
+	var proof AccountProof
+	if err := json.Unmarshal(resp.Result, &proof); err != nil {
+		return nil, decodeError("failed to unmarshal proof: %w", err)
+	}
+	return &proof, nil
+}
+
+// Verify checks the account state against stateRoot, the state root of the
+// block the proof was requested at, and each storage value against the
+// account's storage root
+func (p *AccountProof) Verify(stateRoot string) error {
+	root, err := decodeData(stateRoot)
+	if err != nil {
+		return fmt.Errorf("invalid state root: %w", err)
+	}
+	address, err := decodeData(p.Address)
+	if err != nil || len(address) != 20 {
+		return fmt.Errorf("invalid address %q", p.Address)
+	}
+
+	var e fieldEncoder
+	e.quantity("nonce", p.Nonce)
+	e.quantity("balance", p.Balance)
+	e.data("storageHash", p.StorageHash)
+	e.data("codeHash", p.CodeHash)
+	account, err := e.list()
+	if err != nil {
+		return err
+	}
+
+	value, err := verifyProof(root, Keccak256(address), p.AccountProof)
+	if err != nil {
+		return fmt.Errorf("account %s: %w", p.Address, err)
+	}
+	if value == nil {
+		// An absent account must be reported as empty
+		value = rlp.EncodeList(rlp.EncodeUint(0), rlp.EncodeUint(0), rlp.EncodeBytes(trie.EmptyRoot), rlp.EncodeBytes(emptyCodeHash))
+	}
+	if !bytes.Equal(value, account) {
+		return fmt.Errorf("%w: account %s state does not match the proof", ErrInvalidProof, p.Address)
+	}
+
+	storageRoot, _ := decodeData(p.StorageHash)
+	for _, sp := range p.StorageProof {
+		if err := sp.verify(storageRoot); err != nil {
+			return fmt.Errorf("account %s slot %s: %w", p.Address, sp.Key, err)
+		}
+	}
+	return nil
+}
+
+// verify checks the slot value against the account's storage root
+func (sp *StorageProof) verify(storageRoot []byte) error {
+	key, err := decodeBig(sp.Key)
+	if err != nil || key.BitLen() > 256 {
+		return fmt.Errorf("invalid storage key %q", sp.Key)
+	}
+	want, err := decodeBig(sp.Value)
+	if err != nil {
+		return fmt.Errorf("invalid storage value: %w", err)
+	}
+
+	value, err := verifyProof(storageRoot, Keccak256(key.FillBytes(make([]byte, 32))), sp.Proof)
+	if err != nil {
+		return err
+	}
+	got := new(big.Int)
+	if value != nil {
+		content, err := rlp.DecodeBytes(value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+		}
+		got.SetBytes(content)
+	}
+	if got.Cmp(want) != 0 {
+		return fmt.Errorf("%w: proof holds %#x, upstream reported %s", ErrInvalidProof, got, sp.Value)
+	}
+	return nil
+}
+
+// verifyProof decodes hex-encoded proof nodes and walks them from root
+func verifyProof(root, key []byte, proof []string) ([]byte, error) {
+	nodes := make([][]byte, len(proof))
+	for i, node := range proof {
+		var err error
+		if nodes[i], err = decodeData(node); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+		}
+	}
+	return trie.VerifyProof(root, key, nodes)
+}
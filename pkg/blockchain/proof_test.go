@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// proofFixture holds eth_getProof results for a contract with storage and
+// for an absent account, generated with go-ethereum
+type proofFixture struct {
+	StateRoot string        `json:"stateRoot"`
+	Proof     *AccountProof `json:"proof"`
+	Absent    *AccountProof `json:"absent"`
+}
+
+func loadProofFixture(t *testing.T) *proofFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/proof.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var f proofFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	return &f
+}
+
+func TestAccountProofVerify(t *testing.T) {
+	f := loadProofFixture(t)
+	if err := f.Proof.Verify(f.StateRoot); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := f.Absent.Verify(f.StateRoot); err != nil {
+		t.Errorf("unexpected error for an absent account: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(p *AccountProof)
+	}{
+		{"balance", func(p *AccountProof) { p.Balance = "0x1" }},
+		{"nonce", func(p *AccountProof) { p.Nonce = "0xd" }},
+		{"storage value", func(p *AccountProof) { p.StorageProof[0].Value = "0x1" }},
+		{"absent storage value", func(p *AccountProof) { p.StorageProof[2].Value = "0x1" }},
+		{"missing node", func(p *AccountProof) { p.AccountProof = p.AccountProof[:1] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadProofFixture(t)
+			tt.tamper(f.Proof)
+			if err := f.Proof.Verify(f.StateRoot); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("expected an invalid proof, got %v", err)
+			}
+		})
+	}
+
+	f.Absent.Balance = "0x1"
+	if err := f.Absent.Verify(f.StateRoot); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("expected a balance for an absent account to be rejected, got %v", err)
+	}
+}
+
+func TestGetProof(t *testing.T) {
+	f := loadProofFixture(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method != "eth_getProof" || len(req.Params) != 3 || req.Params[2] != "0x10" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		result, _ := json.Marshal(f.Proof)
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	defer server.Close()
+
+	proof, err := NewClient(server.URL).GetProof(context.Background(), f.Proof.Address, []string{"0x3"}, "0x10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proof.Balance != f.Proof.Balance || len(proof.StorageProof) != 3 {
+		t.Errorf("unexpected proof %+v", proof)
+	}
+}
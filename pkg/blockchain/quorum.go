@@ -0,0 +1,218 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QuorumMethods lists the upstream methods that can be read with a quorum
+var QuorumMethods = []string{"eth_blockNumber", "eth_chainId", "eth_getBlockByNumber", "eth_getBlockReceipts", "eth_getProof"}
+
+// Quorum requires Agree upstreams to return the same result before a read
+// succeeds. Size limits the read to the first Size upstreams; 0 queries all
+// of them.
+type Quorum struct {
+	Agree int
+	Size  int
+}
+
+// ParseQuorum parses a quorum written as "M" (M of all upstreams) or "M/N"
+// (M of the first N upstreams)
+func ParseQuorum(s string) (Quorum, error) {
+	agree, size, hasSize := strings.Cut(s, "/")
+	var q Quorum
+	var err error
+	if q.Agree, err = strconv.Atoi(strings.TrimSpace(agree)); err != nil || q.Agree < 1 {
+		return Quorum{}, fmt.Errorf("invalid quorum %q: agreement must be a positive number", s)
+	}
+	if hasSize {
+		if q.Size, err = strconv.Atoi(strings.TrimSpace(size)); err != nil || q.Size < q.Agree {
+			return Quorum{}, fmt.Errorf("invalid quorum %q: size must be a number no less than the agreement", s)
+		}
+	}
+	return q, nil
+}
+
+func (q Quorum) String() string {
+	if q.Size == 0 {
+		return strconv.Itoa(q.Agree)
+	}
+	return fmt.Sprintf("%d/%d", q.Agree, q.Size)
+}
+
+type quorumKey struct{}
+
+// WithQuorum makes upstream reads made with the returned context require q,
+// overriding any quorum configured for the method
+func WithQuorum(ctx context.Context, q Quorum) context.Context {
+	return context.WithValue(ctx, quorumKey{}, q)
+}
+
+// SetQuorum requires a quorum for reads of the methods in quorums, keyed by
+// upstream method name. It must be called before the set serves any reads.
+func (u *UpstreamSet) SetQuorum(quorums map[string]Quorum) {
+	u.quorums = quorums
+}
+
+// quorumFor returns the quorum required for method, if any. A quorum of one
+// is served by plain failover.
+func (u *UpstreamSet) quorumFor(ctx context.Context, method string) (Quorum, bool) {
+	q, ok := ctx.Value(quorumKey{}).(Quorum)
+	if !ok {
+		q, ok = u.quorums[method]
+	}
+	return q, ok && q.Agree > 1
+}
+
+// UpstreamResult is what one upstream returned for a quorum read
+type UpstreamResult struct {
+	Upstream string
+	// Result summarizes the value returned, e.g. a block hash
+	Result string
+	Err    error
+}
+
+func (r UpstreamResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s failed: %v", r.Upstream, r.Err)
+	}
+	return fmt.Sprintf("%s returned %s", r.Upstream, r.Result)
+}
+
+// DisagreementError is returned when too few upstreams agree on the result of
+// a quorum read. Results lists what each upstream queried returned.
+type DisagreementError struct {
+	Method  string
+	Quorum  Quorum
+	Results []UpstreamResult
+}
+
+func (e *DisagreementError) Error() string {
+	results := make([]string, len(e.Results))
+	for i, r := range e.Results {
+		results[i] = r.String()
+	}
+	return fmt.Sprintf("no quorum for %s: %d of %d upstreams must agree; %s", e.Method, e.Quorum.Agree, len(e.Results), strings.Join(results, "; "))
+}
+
+// read calls fn with failover, or on several upstreams in parallel when
+// method requires a quorum
+func read[T any](ctx context.Context, u *UpstreamSet, method string, fn func(context.Context, *Client) (T, error)) (T, error) {
+	if q, ok := u.quorumFor(ctx, method); ok {
+		return quorum(ctx, u, method, q, fn)
+	}
+	return failover(ctx, u, fn)
+}
+
+// quorum calls fn on the upstreams selected by q in parallel, and returns the
+// first result that q.Agree of them return. Results are compared after
+// normalization, so that upstreams differing only in hex case or field order
+// agree.
+func quorum[T any](ctx context.Context, u *UpstreamSet, method string, q Quorum, fn func(context.Context, *Client) (T, error)) (T, error) {
+	var zero T
+	clients := u.clients
+	if q.Size > 0 && q.Size < len(clients) {
+		clients = clients[:q.Size]
+	}
+	if q.Agree > len(clients) {
+		return zero, fmt.Errorf("quorum %s for %s needs %d upstreams, only %d configured", q, method, q.Agree, len(clients))
+	}
+
+	type reply struct {
+		index  int
+		result T
+		err    error
+	}
+	replies := make(chan reply, len(clients))
+	for i, c := range clients {
+		go func() {
+			result, err := fn(ctx, c)
+			replies <- reply{i, result, err}
+		}()
+	}
+
+	results := make([]UpstreamResult, len(clients))
+	for i, c := range clients {
+		results[i] = UpstreamResult{Upstream: RedactURL(c.Endpoint()), Result: "no response"}
+	}
+	votes := make(map[string]int)
+	failed := 0
+	for range clients {
+		var r reply
+		select {
+		case r = <-replies:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+
+		if r.err != nil {
+			results[r.index].Err = r.err
+			failed++
+		} else {
+			key, err := normalize(r.result)
+			if err != nil {
+				return zero, fmt.Errorf("failed to compare %s results: %w", method, err)
+			}
+			results[r.index].Result = summarize(r.result, key)
+			if votes[key]++; votes[key] >= q.Agree {
+				return r.result, nil
+			}
+		}
+
+		// Stop early once the replies still outstanding cannot make up a
+		// quorum with the largest group so far
+		best := 0
+		for _, n := range votes {
+			best = max(best, n)
+		}
+		pending := len(clients) - failed - sumVotes(votes)
+		if best+pending < q.Agree {
+			break
+		}
+	}
+	return zero, &DisagreementError{Method: method, Quorum: q, Results: results}
+}
+
+func sumVotes(votes map[string]int) int {
+	total := 0
+	for _, n := range votes {
+		total += n
+	}
+	return total
+}
+
+// normalize returns a comparable encoding of v: its JSON encoding with hex
+// digits lowercased. Every string value upstreams return is a hex quantity,
+// hash or address, so lowercasing does not merge distinct results.
+func normalize(v any) (string, error) {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.ToLower(enc)), nil
+}
+
+// summarize describes a result briefly enough to list in a disagreement,
+// falling back to a digest of its normalized encoding
+func summarize(v any, normalized string) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case *Block:
+		if v == nil {
+			return "no block"
+		}
+		return "block " + v.Hash
+	case *AccountProof:
+		if v == nil {
+			return "no account"
+		}
+		return fmt.Sprintf("balance %s nonce %s storage hash %s", v.Balance, v.Nonce, v.StorageHash)
+	}
+	return "result " + hex.EncodeToString(Keccak256([]byte(normalized))[:8])
+}
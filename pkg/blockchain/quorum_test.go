@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newBlockNumberServer answers eth_blockNumber with head, or fails every call
+// when head is empty
+func newBlockNumberServer(t *testing.T, head string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if head == "" {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"` + head + `"`)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseQuorum(t *testing.T) {
+	tests := map[string]Quorum{"2": {Agree: 2}, "2/3": {Agree: 2, Size: 3}, " 3 / 5 ": {Agree: 3, Size: 5}}
+	for s, want := range tests {
+		if q, err := ParseQuorum(s); err != nil || q != want {
+			t.Errorf("ParseQuorum(%q) = %+v, %v; want %+v", s, q, err, want)
+		}
+	}
+	for _, s := range []string{"", "0", "two", "3/2", "2/x"} {
+		if _, err := ParseQuorum(s); err == nil {
+			t.Errorf("expected ParseQuorum(%q) to fail", s)
+		}
+	}
+}
+
+func TestUpstreamSetQuorum(t *testing.T) {
+	a := newBlockNumberServer(t, "0x10")
+	b := newBlockNumberServer(t, "0x10")
+	lagging := newBlockNumberServer(t, "0xF")
+	down := newBlockNumberServer(t, "")
+
+	upstreams := NewUpstreamSet([]string{lagging.URL, down.URL, a.URL, b.URL})
+	upstreams.SetQuorum(map[string]Quorum{"eth_blockNumber": {Agree: 2}})
+
+	blockNumber, err := upstreams.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("expected two upstreams to agree, got %v", err)
+	}
+	if blockNumber != "0x10" {
+		t.Errorf("expected the agreed block number 0x10, got %s", blockNumber)
+	}
+
+	// Results differing only in hex case agree
+	upper := newBlockNumberServer(t, "0xF")
+	blockNumber, err = NewUpstreamSet([]string{lagging.URL, upper.URL}).GetBlockNumber(WithQuorum(context.Background(), Quorum{Agree: 2}))
+	if err != nil || !strings.EqualFold(blockNumber, "0xf") {
+		t.Errorf("expected normalized results to agree, got %s, %v", blockNumber, err)
+	}
+
+	// The request quorum overrides the configured one, and Size limits the
+	// upstreams queried
+	_, err = upstreams.GetBlockNumber(WithQuorum(context.Background(), Quorum{Agree: 2, Size: 3}))
+	var disagreement *DisagreementError
+	if !errors.As(err, &disagreement) {
+		t.Fatalf("expected a disagreement, got %v", err)
+	}
+	if disagreement.Method != "eth_blockNumber" || len(disagreement.Results) != 3 {
+		t.Fatalf("unexpected disagreement %+v", disagreement)
+	}
+	if r := disagreement.Results[0]; r.Result != "0xF" || r.Err != nil {
+		t.Errorf("expected the first upstream to report 0xF, got %+v", r)
+	}
+	if r := disagreement.Results[1]; r.Err == nil {
+		t.Errorf("expected the second upstream to report its failure, got %+v", r)
+	}
+	if r := disagreement.Results[2]; r.Result != "0x10" {
+		t.Errorf("expected the third upstream to report 0x10, got %+v", r)
+	}
+	if !strings.Contains(err.Error(), RedactURL(lagging.URL)+" returned 0xF") {
+		t.Errorf("expected the error to list each upstream's result, got %v", err)
+	}
+
+	// Quorums larger than the set fail without querying
+	if _, err := upstreams.GetBlockNumber(WithQuorum(context.Background(), Quorum{Agree: 5})); err == nil || errors.As(err, &disagreement) {
+		t.Errorf("expected an unsatisfiable quorum to be rejected, got %v", err)
+	}
+
+	// Methods without a quorum still fail over
+	upstreams.SetQuorum(nil)
+	if blockNumber, err := upstreams.GetBlockNumber(context.Background()); err != nil || blockNumber != "0xF" {
+		t.Errorf("expected the first upstream's result, got %s, %v", blockNumber, err)
+	}
+}
@@ -0,0 +1,90 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+
+	"blockchain-client/pkg/trie"
+)
+
+// zeroAddress is the sender and recipient of Polygon state-sync transactions
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// RootMismatchError reports a block whose transactions or receipts do not
+// hash to the root committed to in its header
+type RootMismatchError struct {
+	Number   string
+	Root     string
+	Reported string
+	Computed string
+}
+
+func (e *RootMismatchError) Error() string {
+	return fmt.Sprintf("block %s %s mismatch: header has %s, computed %s", e.Number, e.Root, e.Reported, e.Computed)
+}
+
+// isStateSync reports whether a transaction or receipt between from and to
+// is a Polygon state-sync transaction. Bor returns these with the block but
+// leaves them out of the transactions and receipts roots.
+func isStateSync(from, to string) bool {
+	return strings.EqualFold(from, zeroAddress) && strings.EqualFold(to, zeroAddress)
+}
+
+// VerifyTransactionsRoot checks that the block's transactions, which must
+// have been fetched in full, hash to the header's transactions root and that
+// each transaction's hash matches its fields
+func (b *Block) VerifyTransactionsRoot() error {
+	if b.TransactionsRoot == "" {
+		return fmt.Errorf("%w: missing transactionsRoot", ErrIncompleteHeader)
+	}
+	txs, err := b.FullTransactions()
+	if err != nil {
+		return err
+	}
+
+	values := make([][]byte, 0, len(txs))
+	for i, tx := range txs {
+		if i == len(txs)-1 && isStateSync(tx.From, tx.To) {
+			break
+		}
+		enc, err := tx.Encode()
+		if err != nil {
+			return fmt.Errorf("block %s transaction %d: %w", b.Number, i, err)
+		}
+		if hash := encodeHash(Keccak256(enc)); !strings.EqualFold(hash, tx.Hash) {
+			return fmt.Errorf("block %s transaction %d hash mismatch: upstream reported %s, fields hash to %s", b.Number, i, tx.Hash, hash)
+		}
+		values = append(values, enc)
+	}
+	return b.checkRoot("transactionsRoot", b.TransactionsRoot, values)
+}
+
+// VerifyReceipts checks that receipts, all the receipts of the block, hash to
+// the header's receipts root
+func (b *Block) VerifyReceipts(receipts []*Receipt) error {
+	if b.ReceiptsRoot == "" {
+		return fmt.Errorf("%w: missing receiptsRoot", ErrIncompleteHeader)
+	}
+
+	values := make([][]byte, 0, len(receipts))
+	for i, r := range receipts {
+		if i == len(receipts)-1 && isStateSync(r.From, r.To) {
+			break
+		}
+		enc, err := r.Encode()
+		if err != nil {
+			return fmt.Errorf("block %s receipt %d: %w", b.Number, i, err)
+		}
+		values = append(values, enc)
+	}
+	return b.checkRoot("receiptsRoot", b.ReceiptsRoot, values)
+}
+
+// checkRoot compares the root of the trie of values with the header's root
+func (b *Block) checkRoot(name, reported string, values [][]byte) error {
+	computed := encodeHash(trie.DeriveRoot(values))
+	if !strings.EqualFold(computed, reported) {
+		return &RootMismatchError{Number: b.Number, Root: name, Reported: reported, Computed: computed}
+	}
+	return nil
+}
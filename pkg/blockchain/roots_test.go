@@ -0,0 +1,143 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// rootsFixture holds transactions of every supported type, including an OP
+// Stack deposit, and their receipts, generated with op-geth
+type rootsFixture struct {
+	TransactionsRoot     string          `json:"transactionsRoot"`
+	ReceiptsRoot         string          `json:"receiptsRoot"`
+	Transactions         json.RawMessage `json:"transactions"`
+	Receipts             []*Receipt      `json:"receipts"`
+	PreByzantiumRoot     string          `json:"preByzantiumRoot"`
+	PreByzantiumReceipts []*Receipt      `json:"preByzantiumReceipts"`
+}
+
+func loadRootsFixture(t *testing.T) *rootsFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/roots.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var f rootsFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	return &f
+}
+
+func TestVerifyTransactionsRoot(t *testing.T) {
+	f := loadRootsFixture(t)
+	block := &Block{Number: "0x1", TransactionsRoot: f.TransactionsRoot, Transactions: f.Transactions}
+	if err := block.VerifyTransactionsRoot(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	txs, _ := block.FullTransactions()
+	for _, tx := range txs {
+		if hash, err := tx.ComputeHash(); err != nil || hash != tx.Hash {
+			t.Errorf("type %s: expected hash %s, got %s (%v)", tx.Type, tx.Hash, hash, err)
+		}
+	}
+
+	// Dropping a transaction changes the root
+	block.Transactions, _ = json.Marshal(txs[1:])
+	var mismatch *RootMismatchError
+	if err := block.VerifyTransactionsRoot(); !errors.As(err, &mismatch) || mismatch.Root != "transactionsRoot" {
+		t.Errorf("expected a root mismatch, got %v", err)
+	}
+
+	// Tampering with a field is caught by the transaction hash
+	txs[2].Value = "0x1"
+	block.Transactions, _ = json.Marshal(txs)
+	if err := block.VerifyTransactionsRoot(); err == nil {
+		t.Error("expected a tampered transaction to be rejected")
+	}
+}
+
+func TestVerifyTransactionsRootSkipsStateSync(t *testing.T) {
+	f := loadRootsFixture(t)
+	var txs []json.RawMessage
+	json.Unmarshal(f.Transactions, &txs)
+	stateSync := json.RawMessage(`{"type":"0x0","hash":"0x01","from":"` + zeroAddress + `","to":"` + zeroAddress + `","value":"0x0","gas":"0x0","gasPrice":"0x0","input":"0x","nonce":"0x0","v":"0x0","r":"0x0","s":"0x0"}`)
+	block := &Block{Number: "0x1", TransactionsRoot: f.TransactionsRoot}
+	block.Transactions, _ = json.Marshal(append(txs, stateSync))
+	if err := block.VerifyTransactionsRoot(); err != nil {
+		t.Errorf("expected the Polygon state-sync transaction to be skipped, got %v", err)
+	}
+}
+
+func TestVerifyReceipts(t *testing.T) {
+	f := loadRootsFixture(t)
+	block := &Block{Number: "0x1", ReceiptsRoot: f.ReceiptsRoot}
+	if err := block.VerifyReceipts(f.Receipts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	preByzantium := &Block{Number: "0x1", ReceiptsRoot: f.PreByzantiumRoot}
+	if err := preByzantium.VerifyReceipts(f.PreByzantiumReceipts); err != nil {
+		t.Errorf("unexpected error for pre-Byzantium receipts: %v", err)
+	}
+
+	f.Receipts[3].Status = "0x0"
+	var mismatch *RootMismatchError
+	if err := block.VerifyReceipts(f.Receipts); !errors.As(err, &mismatch) || mismatch.Root != "receiptsRoot" {
+		t.Errorf("expected a root mismatch, got %v", err)
+	}
+
+	if err := block.VerifyReceipts([]*Receipt{{Type: "0x66"}}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected an unsupported type error, got %v", err)
+	}
+}
+
+func TestClientReceiptVerification(t *testing.T) {
+	f := loadRootsFixture(t)
+	header := testHeaders(t)["Cancun"]
+	header.ReceiptsRoot = f.ReceiptsRoot
+	header.Hash, _ = header.ComputeHash()
+	for _, r := range f.Receipts {
+		r.BlockHash = header.Hash
+	}
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = append(requested, req.Method)
+
+		var result []byte
+		switch req.Method {
+		case "eth_getBlockReceipts":
+			result, _ = json.Marshal(f.Receipts)
+		case "eth_getBlockByHash":
+			if req.Params[0] != header.Hash {
+				t.Errorf("expected the header to be fetched by the receipts' block hash, got %v", req.Params[0])
+			}
+			result, _ = json.Marshal(header)
+		}
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithBlockVerification())
+	if _, err := client.GetBlockReceipts(context.Background(), "latest"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requested) != 2 || requested[1] != "eth_getBlockByHash" {
+		t.Errorf("unexpected upstream calls %v", requested)
+	}
+
+	f.Receipts[0].CumulativeGasUsed = "0x1"
+	var mismatch *RootMismatchError
+	if _, err := client.GetBlockReceipts(context.Background(), "latest"); !errors.As(err, &mismatch) {
+		t.Errorf("expected a root mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"sync"
+	"time"
+)
+
+// statsWindow is the number of recent calls the error rate is computed over
+const statsWindow = 100
+
+// latencyWeight is the weight of the newest sample in the latency average
+const latencyWeight = 0.2
+
+// UpstreamStats represents recent call statistics for one upstream endpoint
+type UpstreamStats struct {
+	// Endpoint is the upstream URL with any embedded API key redacted
+	Endpoint string `json:"endpoint"`
+	Requests uint64 `json:"requests"`
+	Errors   uint64 `json:"errors"`
+	// ErrorRate is the fraction of the most recent calls that failed
+	ErrorRate float64 `json:"errorRate"`
+	// LatencyMs is a moving average of round-trip latency in milliseconds
+	LatencyMs   float64    `json:"latencyMs"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// endpointStats accumulates statistics for one endpoint
+type endpointStats struct {
+	stats  UpstreamStats
+	recent [statsWindow]bool
+	next   int
+	filled int
+}
+
+// StatsRecorder is a CallObserver that keeps per-endpoint call statistics
+type StatsRecorder struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+	order     []string
+}
+
+// NewStatsRecorder creates a new stats recorder
+func NewStatsRecorder() *StatsRecorder {
+	return &StatsRecorder{endpoints: make(map[string]*endpointStats)}
+}
+
+// ObserveCall implements CallObserver
+func (r *StatsRecorder) ObserveCall(call CallInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.endpoints[call.Endpoint]
+	if !ok {
+		e = &endpointStats{stats: UpstreamStats{Endpoint: RedactURL(call.Endpoint)}}
+		r.endpoints[call.Endpoint] = e
+		r.order = append(r.order, call.Endpoint)
+	}
+
+	failed := call.ErrorCode != ""
+	e.stats.Requests++
+	if failed {
+		e.stats.Errors++
+		now := time.Now()
+		e.stats.LastErrorAt = &now
+		if call.Err != nil {
+			e.stats.LastError = call.Err.Error()
+		}
+	}
+
+	latency := float64(call.Duration) / float64(time.Millisecond)
+	if e.stats.Requests == 1 {
+		e.stats.LatencyMs = latency
+	} else {
+		e.stats.LatencyMs += latencyWeight * (latency - e.stats.LatencyMs)
+	}
+
+	e.recent[e.next] = failed
+	e.next = (e.next + 1) % statsWindow
+	if e.filled < statsWindow {
+		e.filled++
+	}
+}
+
+// Snapshot returns the statistics of every endpoint seen so far
+func (r *StatsRecorder) Snapshot() []UpstreamStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make([]UpstreamStats, 0, len(r.order))
+	for _, endpoint := range r.order {
+		e := r.endpoints[endpoint]
+		stats := e.stats
+		failures := 0
+		for _, failed := range e.recent[:e.filled] {
+			if failed {
+				failures++
+			}
+		}
+		if e.filled > 0 {
+			stats.ErrorRate = float64(failures) / float64(e.filled)
+		}
+		snapshot = append(snapshot, stats)
+	}
+	return snapshot
+}
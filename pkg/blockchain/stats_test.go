@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatsRecorder(t *testing.T) {
+	r := NewStatsRecorder()
+
+	for i := 0; i < statsWindow; i++ {
+		r.ObserveCall(CallInfo{Endpoint: "https://a.example.com/key", Method: "eth_blockNumber", ErrorCode: "transport", Err: errors.New("refused")})
+	}
+	// Once the window is full, old failures age out
+	for i := 0; i < statsWindow/2; i++ {
+		r.ObserveCall(CallInfo{Endpoint: "https://a.example.com/key", Method: "eth_blockNumber", Duration: 10 * time.Millisecond})
+	}
+	r.ObserveCall(CallInfo{Endpoint: "https://b.example.com", Method: "eth_chainId", Duration: 20 * time.Millisecond})
+
+	snapshot := r.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(snapshot))
+	}
+
+	a := snapshot[0]
+	if a.Endpoint != "https://a.example.com/REDACTED" {
+		t.Errorf("expected redacted endpoint, got %q", a.Endpoint)
+	}
+	if a.Requests != 150 || a.Errors != 100 {
+		t.Errorf("expected 150 requests and 100 errors, got %d and %d", a.Requests, a.Errors)
+	}
+	if a.ErrorRate != 0.5 {
+		t.Errorf("expected windowed error rate 0.5, got %v", a.ErrorRate)
+	}
+	if a.LastError != "refused" || a.LastErrorAt == nil {
+		t.Errorf("expected last error to be recorded, got %+v", a)
+	}
+
+	b := snapshot[1]
+	if b.ErrorRate != 0 || b.LatencyMs != 20 {
+		t.Errorf("unexpected stats %+v", b)
+	}
+}
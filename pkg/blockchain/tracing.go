@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName identifies spans created by the client
+const TracerName = "blockchain-client/pkg/blockchain"
+
+// Span attributes recorded on upstream calls
+const (
+	AttrRPCSystem    = "rpc.system"
+	AttrRPCMethod    = "rpc.method"
+	AttrURL          = "url.full"
+	AttrServer       = "server.address"
+	AttrAttempt      = "rpc.attempt"
+	AttrHTTPStatus   = "http.response.status_code"
+	AttrResponseSize = "http.response.body.size"
+	AttrCoalesced    = "rpc.coalesced"
+	AttrBatchSize    = "rpc.batch_size"
+	// AttrRequestID is the JSON-RPC id sent upstream, and AttrRequestIDs the
+	// ids of a batch in request order
+	AttrRequestID  = "rpc.jsonrpc.request_id"
+	AttrRequestIDs = "rpc.jsonrpc.request_ids"
+)
+
+type attemptKey struct{}
+
+// withAttempt records that upstream calls made with the returned context are
+// the attempt'th try of a read, counting from 1
+func withAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// attempt returns the attempt recorded on ctx by withAttempt, or 1
+func attempt(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// startSpan starts a client span for an upstream call. The tracer is looked
+// up on every call so a provider installed after the client was created is
+// still used.
+func (c *Client) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(AttrRPCSystem, "jsonrpc"),
+			attribute.String(AttrRPCMethod, method),
+			attribute.String(AttrURL, RedactURL(c.rpcURL)),
+			attribute.String(AttrServer, EndpointHost(c.rpcURL)),
+		),
+	)
+}
+
+// endSpan marks span as failed when err is set
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// injectTraceContext writes the W3C traceparent of ctx into header
+func injectTraceContext(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
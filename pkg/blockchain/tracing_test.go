@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that records finished spans for the
+// duration of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanAttrs indexes the attributes of a span by key
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestCallTracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/v2/secret-key")
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	if _, err := client.GetBlockNumber(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected call and parent spans, got %d", len(spans))
+	}
+	call := spans[0]
+	if call.Name() != "eth_blockNumber" || call.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected call span %q of kind %v", call.Name(), call.SpanKind())
+	}
+	if call.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected call span to be a child of the caller's span")
+	}
+
+	attrs := spanAttrs(call)
+	if attrs[AttrRPCMethod].AsString() != "eth_blockNumber" {
+		t.Errorf("expected rpc.method attribute, got %v", attrs[AttrRPCMethod].AsString())
+	}
+	if url := attrs[AttrURL].AsString(); url != server.URL+"/REDACTED" {
+		t.Errorf("expected redacted upstream URL, got %q", url)
+	}
+	if attrs[AttrRequestID].AsInt64() != 1 {
+		t.Errorf("expected request id 1, got %d", attrs[AttrRequestID].AsInt64())
+	}
+	if attrs[AttrAttempt].AsInt64() != 1 {
+		t.Errorf("expected attempt 1, got %d", attrs[AttrAttempt].AsInt64())
+	}
+	if size := attrs[AttrResponseSize].AsInt64(); size != 40 {
+		t.Errorf("expected response size 40, got %d", size)
+	}
+
+	want := "00-" + call.SpanContext().TraceID().String() + "-" + call.SpanContext().SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("expected traceparent %q, got %q", want, traceparent)
+	}
+}
+
+func TestFailoverAttemptTracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	down := newChainServer(t, 0)
+	up := newChainServer(t, 1)
+	if _, err := NewUpstreamSet([]string{down.URL, up.URL}).GetBlockNumber(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a span for each upstream, got %d", len(spans))
+	}
+	for i, span := range spans {
+		if got := spanAttrs(span)[AttrAttempt].AsInt64(); got != int64(i+1) {
+			t.Errorf("expected call %d to record attempt %d, got %d", i, i+1, got)
+		}
+	}
+}
+
+func TestCallTracingError(t *testing.T) {
+	recorder := recordSpans(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.GetBlockByNumber(context.Background(), "0x1", false); err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[0].Status().Code)
+	}
+	if status := spanAttrs(spans[0])[AttrHTTPStatus].AsInt64(); status != http.StatusBadGateway {
+		t.Errorf("expected status attribute 502, got %d", status)
+	}
+}
@@ -0,0 +1,151 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Transaction represents an Ethereum transaction as returned by
+// eth_getBlockByNumber with full transactions
+type Transaction struct {
+	Hash                 string `json:"hash"`
+	BlockHash            string `json:"blockHash"`
+	BlockNumber          string `json:"blockNumber"`
+	TransactionIndex     string `json:"transactionIndex"`
+	From                 string `json:"from"`
+	To                   string `json:"to"`
+	Value                string `json:"value"`
+	Gas                  string `json:"gas"`
+	GasPrice             string `json:"gasPrice"`
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+	Input                string `json:"input"`
+	Nonce                string `json:"nonce"`
+	Type                 string `json:"type"`
+
+	// Fields needed to recompute the transaction hash and the block's
+	// transactions root
+	ChainID             string          `json:"chainId,omitempty"`
+	AccessList          []AccessTuple   `json:"accessList,omitempty"`
+	MaxFeePerBlobGas    string          `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []string        `json:"blobVersionedHashes,omitempty"`
+	AuthorizationList   []Authorization `json:"authorizationList,omitempty"`
+	V                   string          `json:"v,omitempty"`
+	R                   string          `json:"r,omitempty"`
+	S                   string          `json:"s,omitempty"`
+	YParity             string          `json:"yParity,omitempty"`
+
+	// OP Stack deposit transaction fields, e.g. on Base
+	SourceHash string `json:"sourceHash,omitempty"`
+	Mint       string `json:"mint,omitempty"`
+	IsSystemTx bool   `json:"isSystemTx,omitempty"`
+}
+
+// AccessTuple is an entry of an EIP-2930 access list
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// Authorization is an entry of an EIP-7702 authorization list
+type Authorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"`
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+}
+
+// Receipt represents an Ethereum transaction receipt
+type Receipt struct {
+	TransactionHash   string `json:"transactionHash"`
+	TransactionIndex  string `json:"transactionIndex"`
+	BlockHash         string `json:"blockHash"`
+	BlockNumber       string `json:"blockNumber"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+	ContractAddress   string `json:"contractAddress"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	LogsBloom         string `json:"logsBloom"`
+	Status            string `json:"status"`
+	Type              string `json:"type"`
+	Logs              []*Log `json:"logs"`
+
+	// Root is the post-transaction state root that receipts carried in place
+	// of a status before Byzantium
+	Root string `json:"root,omitempty"`
+
+	// OP Stack deposit receipt fields, e.g. on Base
+	DepositNonce          string `json:"depositNonce,omitempty"`
+	DepositReceiptVersion string `json:"depositReceiptVersion,omitempty"`
+}
+
+// Log represents an Ethereum log entry
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// ParseQuantity decodes a hex-encoded JSON-RPC quantity such as "0x1b4"
+func ParseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return 0, fmt.Errorf("invalid quantity %q: missing 0x prefix", s)
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+	return n, nil
+}
+
+// EncodeQuantity encodes n as a hex JSON-RPC quantity
+func EncodeQuantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// FullTransactions decodes the block's transactions, which must have been
+// requested with full transaction objects
+func (b *Block) FullTransactions() ([]*Transaction, error) {
+	var txs []*Transaction
+	if len(b.Transactions) == 0 {
+		return txs, nil
+	}
+	if err := json.Unmarshal(b.Transactions, &txs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transactions: %w", err)
+	}
+	return txs, nil
+}
+
+// TransactionHashes returns the hashes of the block's transactions regardless
+// of whether the block was fetched with full transaction objects
+func (b *Block) TransactionHashes() ([]string, error) {
+	var hashes []string
+	if len(b.Transactions) == 0 {
+		return hashes, nil
+	}
+	if err := json.Unmarshal(b.Transactions, &hashes); err == nil {
+		return hashes, nil
+	}
+
+	txs, err := b.FullTransactions()
+	if err != nil {
+		return nil, err
+	}
+	hashes = make([]string, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash
+	}
+	return hashes, nil
+}
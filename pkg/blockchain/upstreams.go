@@ -0,0 +1,142 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// UpstreamSet serves calls from a list of RPC endpoints for the same chain,
+// trying them in order and failing over to the next one when a call fails
+// with a retryable error.
+// Reads that require a quorum are sent to several upstreams at once instead.
+type UpstreamSet struct {
+	clients []*Client
+	quorums map[string]Quorum
+}
+
+// NewUpstreamSet creates a client for each of urls
+func NewUpstreamSet(urls []string, opts ...ClientOption) *UpstreamSet {
+	u := &UpstreamSet{}
+	for _, url := range urls {
+		u.clients = append(u.clients, NewClient(url, opts...))
+	}
+	return u
+}
+
+// Clients returns the client for each upstream, in failover order
+func (u *UpstreamSet) Clients() []*Client {
+	return u.clients
+}
+
+// AddObserver registers o on every upstream
+func (u *UpstreamSet) AddObserver(o CallObserver) {
+	for _, c := range u.clients {
+		c.AddObserver(o)
+	}
+}
+
+// CoalesceStats returns the coalescing counters summed over every upstream
+func (u *UpstreamSet) CoalesceStats() CoalesceStats {
+	var total CoalesceStats
+	for _, c := range u.clients {
+		stats := c.CoalesceStats()
+		total.Requests += stats.Requests
+		total.Upstream += stats.Upstream
+		total.Coalesced += stats.Coalesced
+	}
+	return total
+}
+
+// VerifyChainID checks that every upstream serves chain want. Upstreams that
+// cannot be reached are logged and skipped so that one outage does not stop
+// the server from starting.
+func (u *UpstreamSet) VerifyChainID(ctx context.Context, want uint64) error {
+	var errs []error
+	for _, c := range u.clients {
+		endpoint := RedactURL(c.Endpoint())
+		chainID, err := c.GetChainID(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "could not verify upstream chain ID", "upstream", endpoint, "error", err)
+			continue
+		}
+		id, err := ParseQuantity(chainID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("upstream %s returned invalid chain ID %q", endpoint, chainID))
+			continue
+		}
+		if id != want {
+			errs = append(errs, fmt.Errorf("upstream %s serves chain %d, expected %d", endpoint, id, want))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// failover calls fn on each upstream in turn until one succeeds, returning
+// the last error if none does. Calls only move on to the next upstream when
+// the error is retryable; an upstream's JSON-RPC error is returned as is.
+func failover[T any](ctx context.Context, u *UpstreamSet, fn func(context.Context, *Client) (T, error)) (T, error) {
+	var zero T
+	err := errors.New("no upstreams configured")
+	for i, c := range u.clients {
+		var result T
+		if result, err = fn(withAttempt(ctx, i+1), c); err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return zero, err
+		}
+	}
+	return zero, err
+}
+
+// GetBlockNumber returns the latest block number
+func (u *UpstreamSet) GetBlockNumber(ctx context.Context) (string, error) {
+	return read(ctx, u, "eth_blockNumber", func(ctx context.Context, c *Client) (string, error) {
+		return c.GetBlockNumber(ctx)
+	})
+}
+
+// GetChainID returns the chain ID served by the upstreams
+func (u *UpstreamSet) GetChainID(ctx context.Context) (string, error) {
+	return read(ctx, u, "eth_chainId", func(ctx context.Context, c *Client) (string, error) {
+		return c.GetChainID(ctx)
+	})
+}
+
+// GetBlockByNumber returns a block by number
+func (u *UpstreamSet) GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*Block, error) {
+	return read(ctx, u, "eth_getBlockByNumber", func(ctx context.Context, c *Client) (*Block, error) {
+		return c.GetBlockByNumber(ctx, blockNumber, fullTransactions)
+	})
+}
+
+// GetBlocksByNumber returns several blocks in one batch request
+func (u *UpstreamSet) GetBlocksByNumber(ctx context.Context, blockNumbers []string, fullTransactions bool) ([]*Block, error) {
+	return read(ctx, u, "eth_getBlockByNumber", func(ctx context.Context, c *Client) ([]*Block, error) {
+		return c.GetBlocksByNumber(ctx, blockNumbers, fullTransactions)
+	})
+}
+
+// GetBlockReceipts returns the receipts of every transaction in a block
+func (u *UpstreamSet) GetBlockReceipts(ctx context.Context, blockNumber string) ([]*Receipt, error) {
+	return read(ctx, u, "eth_getBlockReceipts", func(ctx context.Context, c *Client) ([]*Receipt, error) {
+		return c.GetBlockReceipts(ctx, blockNumber)
+	})
+}
+
+// GetTransactionByHash returns the transaction with hash
+func (u *UpstreamSet) GetTransactionByHash(ctx context.Context, hash string) (json.RawMessage, error) {
+	return read(ctx, u, "eth_getTransactionByHash", func(ctx context.Context, c *Client) (json.RawMessage, error) {
+		return c.GetTransactionByHash(ctx, hash)
+	})
+}
+
+// GetProof returns the state of address, and of its storageKeys, at a block
+func (u *UpstreamSet) GetProof(ctx context.Context, address string, storageKeys []string, blockNumber string) (*AccountProof, error) {
+	return read(ctx, u, "eth_getProof", func(ctx context.Context, c *Client) (*AccountProof, error) {
+		return c.GetProof(ctx, address, storageKeys, blockNumber)
+	})
+}
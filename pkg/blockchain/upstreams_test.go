@@ -0,0 +1,170 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newChainServer serves eth_chainId and eth_blockNumber for chain, or fails
+// every call when chain is 0
+func newChainServer(t *testing.T, chain uint64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chain == 0 {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		result := `"0x10"`
+		if req.Method == "eth_chainId" {
+			result = `"` + EncodeQuantity(chain) + `"`
+		}
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(result)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpstreamSetFailover(t *testing.T) {
+	down := newChainServer(t, 0)
+	up := newChainServer(t, 137)
+
+	upstreams := NewUpstreamSet([]string{down.URL, up.URL})
+	stats := NewStatsRecorder()
+	upstreams.AddObserver(stats)
+
+	blockNumber, err := upstreams.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("expected failover to the second upstream, got %v", err)
+	}
+	if blockNumber != "0x10" {
+		t.Errorf("expected block number 0x10, got %s", blockNumber)
+	}
+	if snapshot := stats.Snapshot(); len(snapshot) != 2 {
+		t.Errorf("expected calls to both upstreams to be observed, got %+v", snapshot)
+	}
+
+	if _, err := NewUpstreamSet([]string{down.URL}).GetBlockNumber(context.Background()); err == nil {
+		t.Error("expected an error when every upstream fails")
+	}
+}
+
+func TestUpstreamSetFailoverRetryableOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		failover bool
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, true},
+		{"rate limited", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, true},
+		{"client error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}, false},
+		{"RPC error", func(w http.ResponseWriter, r *http.Request) {
+			var req RPCRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Error: &RPCError{Code: -32000, Message: "execution reverted"}})
+		}, false},
+		{"malformed response", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>bad gateway</html>"))
+		}, true},
+		{"id mismatch", func(w http.ResponseWriter, r *http.Request) {
+			var req RPCRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID + 1, Result: json.RawMessage(`"0x10"`)})
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := httptest.NewServer(tt.handler)
+			defer first.Close()
+			var calls int
+			second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				var req RPCRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"0x10"`)})
+			}))
+			defer second.Close()
+
+			_, err := NewUpstreamSet([]string{first.URL, second.URL}).GetBlockNumber(context.Background())
+			if tt.failover && (err != nil || calls != 1) {
+				t.Errorf("expected failover to the second upstream, got %v after %d calls", err, calls)
+			}
+			if !tt.failover && (err == nil || calls != 0) {
+				t.Errorf("expected the first upstream's error without failover, got %v after %d calls", err, calls)
+			}
+		})
+	}
+
+	var rpcErr *RPCError
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Error: &RPCError{Code: -32000, Message: "header not found"}})
+	}))
+	defer missing.Close()
+	_, err := NewUpstreamSet([]string{missing.URL, newChainServer(t, 1).URL}).GetBlockByNumber(context.Background(), "0x1", false)
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "header not found" {
+		t.Errorf("expected the upstream's RPC error, got %v", err)
+	}
+}
+
+func TestUpstreamSetFailoverBadHash(t *testing.T) {
+	block := testHeaders(t)["Cancun"]
+	tampered := *block
+	tampered.GasUsed = "0x0"
+
+	serve := func(b *Block) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req RPCRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			result, _ := json.Marshal(b)
+			json.NewEncoder(w).Encode(RPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	bad, good := serve(&tampered), serve(block)
+
+	upstreams := NewUpstreamSet([]string{bad.URL, good.URL}, WithBlockVerification())
+	got, err := upstreams.GetBlockByNumber(context.Background(), block.Number, false)
+	if err != nil {
+		t.Fatalf("expected failover to the second upstream, got %v", err)
+	}
+	if got.Hash != block.Hash || got.GasUsed != block.GasUsed {
+		t.Errorf("expected the second upstream's block, got %s with gas used %s", got.Hash, got.GasUsed)
+	}
+
+	var mismatch *HashMismatchError
+	_, err = NewUpstreamSet([]string{bad.URL}, WithBlockVerification()).GetBlockByNumber(context.Background(), block.Number, false)
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected a hash mismatch when no upstream verifies, got %v", err)
+	}
+}
+
+func TestUpstreamSetVerifyChainID(t *testing.T) {
+	polygon := newChainServer(t, 137)
+	mainnet := newChainServer(t, 1)
+	down := newChainServer(t, 0)
+
+	if err := NewUpstreamSet([]string{polygon.URL, down.URL}).VerifyChainID(context.Background(), 137); err != nil {
+		t.Errorf("expected unreachable upstreams to be skipped, got %v", err)
+	}
+
+	err := NewUpstreamSet([]string{polygon.URL, mainnet.URL}).VerifyChainID(context.Background(), 137)
+	if err == nil || !strings.Contains(err.Error(), "serves chain 1, expected 137") {
+		t.Errorf("expected a chain ID mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,457 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/logging"
+	"blockchain-client/pkg/tracing"
+)
+
+// Duration is a time.Duration written as a string such as "30s" or "5m"
+type Duration time.Duration
+
+// UnmarshalText parses a duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, expected a value such as \"30s\" or \"5m\"", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration as a string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config represents the server configuration. Zero durations and sizes select
+// the server defaults.
+type Config struct {
+	Server    Server    `json:"server" yaml:"server" toml:"server"`
+	Upstream  Upstream  `json:"upstream" yaml:"upstream" toml:"upstream"`
+	Chains    []Chain   `json:"chains" yaml:"chains" toml:"chains"`
+	Index     Index     `json:"index" yaml:"index" toml:"index"`
+	Export    Export    `json:"export" yaml:"export" toml:"export"`
+	Methods   []string  `json:"methods" yaml:"methods" toml:"methods"`
+	RateLimit RateLimit `json:"rateLimit" yaml:"rateLimit" toml:"rateLimit"`
+	Auth      Auth      `json:"auth" yaml:"auth" toml:"auth"`
+	Logging   Logging   `json:"logging" yaml:"logging" toml:"logging"`
+	Tracing   Tracing   `json:"tracing" yaml:"tracing" toml:"tracing"`
+	Metrics   Metrics   `json:"metrics" yaml:"metrics" toml:"metrics"`
+}
+
+// Server represents the HTTP server settings
+type Server struct {
+	Addr              string   `json:"addr" yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout" yaml:"readHeaderTimeout" toml:"readHeaderTimeout"`
+	ReadTimeout       Duration `json:"readTimeout" yaml:"readTimeout" toml:"readTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout" yaml:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout" yaml:"idleTimeout" toml:"idleTimeout"`
+	MaxHeaderBytes    int      `json:"maxHeaderBytes" yaml:"maxHeaderBytes" toml:"maxHeaderBytes"`
+	MaxBodyBytes      int64    `json:"maxBodyBytes" yaml:"maxBodyBytes" toml:"maxBodyBytes"`
+	DrainDelay        Duration `json:"drainDelay" yaml:"drainDelay" toml:"drainDelay"`
+	ShutdownTimeout   Duration `json:"shutdownTimeout" yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	// ValidateRequests rejects REST requests that do not match the OpenAPI
+	// document served at /openapi.json
+	ValidateRequests bool `json:"validateRequests" yaml:"validateRequests" toml:"validateRequests"`
+	TLS              TLS  `json:"tls" yaml:"tls" toml:"tls"`
+}
+
+// TLS represents the HTTPS settings
+type TLS struct {
+	CertFile       string   `json:"certFile" yaml:"certFile" toml:"certFile"`
+	KeyFile        string   `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+	ClientCAFile   string   `json:"clientCAFile" yaml:"clientCAFile" toml:"clientCAFile"`
+	RedirectAddr   string   `json:"redirectAddr" yaml:"redirectAddr" toml:"redirectAddr"`
+	ReloadInterval Duration `json:"reloadInterval" yaml:"reloadInterval" toml:"reloadInterval"`
+}
+
+// Upstream represents the upstream JSON-RPC endpoint
+type Upstream struct {
+	URL string `json:"url" yaml:"url" toml:"url"`
+	// Timeout bounds each upstream round-trip; 0 means no timeout
+	Timeout Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	// ChainID is the chain the upstream must serve; 0 skips the check
+	ChainID    uint64   `json:"chainId" yaml:"chainId" toml:"chainId"`
+	MaxHeadAge Duration `json:"maxHeadAge" yaml:"maxHeadAge" toml:"maxHeadAge"`
+	// VerifyBlocks rejects upstream blocks whose hash does not match their
+	// header
+	VerifyBlocks bool `json:"verifyBlocks" yaml:"verifyBlocks" toml:"verifyBlocks"`
+	// Quorum maps upstream methods to the number of upstreams that must
+	// agree on their results, as "M" or "M/N" for M of the first N, e.g.
+	// eth_getProof: 2/3
+	Quorum map[string]string `json:"quorum" yaml:"quorum" toml:"quorum"`
+}
+
+// Chain represents one of several chains hosted by the server. The first
+// chain also serves requests that select no chain. Upstream timeouts and head
+// age and the index worker settings are shared by every chain.
+type Chain struct {
+	// Name selects the chain in request paths, e.g. /polygon/api/blocks/latest
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// ChainID also selects the chain, e.g. /137/api/blocks/latest, and every
+	// upstream must serve it
+	ChainID uint64 `json:"chainId" yaml:"chainId" toml:"chainId"`
+	// Upstreams are tried in order, failing over to the next on errors
+	Upstreams []string `json:"upstreams" yaml:"upstreams" toml:"upstreams"`
+	// IndexDB is the chain's block store path; empty disables its indexer
+	IndexDB    string `json:"indexDb" yaml:"indexDb" toml:"indexDb"`
+	IndexStart uint64 `json:"indexStart" yaml:"indexStart" toml:"indexStart"`
+}
+
+// reservedChainNames are path segments served by the API itself
+var reservedChainNames = []string{"api", "rpc", "healthz", "readyz", "status", "metrics", "openapi.json", "docs"}
+
+// HostedChains returns the chains to serve. Without chains configured, the
+// upstream and index sections describe a single unnamed chain.
+func (cfg *Config) HostedChains() []Chain {
+	if len(cfg.Chains) > 0 {
+		return cfg.Chains
+	}
+	return []Chain{{
+		ChainID:    cfg.Upstream.ChainID,
+		Upstreams:  []string{cfg.Upstream.URL},
+		IndexDB:    cfg.Index.DB,
+		IndexStart: cfg.Index.Start,
+	}}
+}
+
+// Index represents the block store that caches ingested blocks
+type Index struct {
+	// DB is the block store path; empty disables the indexer and cache
+	DB       string `json:"db" yaml:"db" toml:"db"`
+	Start    uint64 `json:"start" yaml:"start" toml:"start"`
+	Workers  int    `json:"workers" yaml:"workers" toml:"workers"`
+	Receipts bool   `json:"receipts" yaml:"receipts" toml:"receipts"`
+}
+
+// Export represents the export job settings
+type Export struct {
+	// Dir enables the export endpoints when set
+	Dir string `json:"dir" yaml:"dir" toml:"dir"`
+	// MaxJobs is the number of export jobs that may run at once
+	MaxJobs int `json:"maxJobs" yaml:"maxJobs" toml:"maxJobs"`
+	// MaxBlocks is the largest block range one job may export
+	MaxBlocks uint64 `json:"maxBlocks" yaml:"maxBlocks" toml:"maxBlocks"`
+	// Retention is how long finished jobs stay in the job list
+	Retention Duration `json:"retention" yaml:"retention" toml:"retention"`
+}
+
+// RateLimit represents the per-client compute unit limits
+type RateLimit struct {
+	// Rate is compute units per second per client; 0 disables the limit
+	Rate              float64        `json:"rate" yaml:"rate" toml:"rate"`
+	Burst             int            `json:"burst" yaml:"burst" toml:"burst"`
+	ComputeUnits      map[string]int `json:"computeUnits" yaml:"computeUnits" toml:"computeUnits"`
+	TrustForwardedFor bool           `json:"trustForwardedFor" yaml:"trustForwardedFor" toml:"trustForwardedFor"`
+}
+
+// Auth represents the API key settings
+type Auth struct {
+	// KeysFile enables API key authentication when set
+	KeysFile       string   `json:"keysFile" yaml:"keysFile" toml:"keysFile"`
+	ReloadInterval Duration `json:"reloadInterval" yaml:"reloadInterval" toml:"reloadInterval"`
+}
+
+// Logging represents the logging settings
+type Logging struct {
+	Level  string `json:"level" yaml:"level" toml:"level"`
+	Format string `json:"format" yaml:"format" toml:"format"`
+}
+
+// Tracing represents the tracing settings
+type Tracing struct {
+	Exporter    string  `json:"exporter" yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `json:"endpoint" yaml:"endpoint" toml:"endpoint"`
+	File        string  `json:"file" yaml:"file" toml:"file"`
+	SampleRatio float64 `json:"sampleRatio" yaml:"sampleRatio" toml:"sampleRatio"`
+}
+
+// Metrics represents the Prometheus metrics settings. The endpoint is off by
+// default because it is served without an API key.
+type Metrics struct {
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+}
+
+// Default returns the configuration used when nothing is configured
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			WriteTimeout:      Duration(60 * time.Second),
+			IdleTimeout:       Duration(120 * time.Second),
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+			DrainDelay:        Duration(5 * time.Second),
+			ShutdownTimeout:   Duration(20 * time.Second),
+			TLS:               TLS{ReloadInterval: Duration(time.Minute)},
+		},
+		Upstream: Upstream{
+			URL:        "https://polygon-rpc.com/",
+			MaxHeadAge: Duration(time.Minute),
+		},
+		Index:   Index{Workers: 4, Receipts: true},
+		Export:  Export{MaxJobs: 4, MaxBlocks: 1000000, Retention: Duration(24 * time.Hour)},
+		Auth:    Auth{ReloadInterval: Duration(10 * time.Second)},
+		Logging: Logging{Level: "info", Format: logging.FormatText},
+		Tracing: Tracing{Exporter: tracing.ExporterNone, File: "traces.jsonl", SampleRatio: 1},
+	}
+}
+
+// LoadFile decodes the YAML, TOML or JSON file at path, chosen by its
+// extension, over cfg. Unknown keys are rejected.
+func LoadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("%s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if dec.More() {
+			return fmt.Errorf("%s: unexpected data after the configuration object", path)
+		}
+
+	default:
+		return fmt.Errorf("%s: unsupported config file extension %q, expected .yaml, .yml, .toml or .json", path, ext)
+	}
+	return nil
+}
+
+// ApplyEnv overrides cfg with the environment variables returned by getenv
+func (cfg *Config) ApplyEnv(getenv func(string) string) error {
+	vars := map[string]*string{
+		"BLOCKCHAIN_RPC_URL": &cfg.Upstream.URL,
+		"API_PORT":           &cfg.Server.Addr,
+		"INDEX_DB_PATH":      &cfg.Index.DB,
+		"EXPORT_DIR":         &cfg.Export.Dir,
+		"API_KEYS_FILE":      &cfg.Auth.KeysFile,
+		"TLS_CERT_FILE":      &cfg.Server.TLS.CertFile,
+		"TLS_KEY_FILE":       &cfg.Server.TLS.KeyFile,
+		"TLS_CLIENT_CA_FILE": &cfg.Server.TLS.ClientCAFile,
+		"TLS_REDIRECT_ADDR":  &cfg.Server.TLS.RedirectAddr,
+		"TRACE_EXPORTER":     &cfg.Tracing.Exporter,
+		"LOG_LEVEL":          &cfg.Logging.Level,
+		"LOG_FORMAT":         &cfg.Logging.Format,
+	}
+	for name, field := range vars {
+		if v := getenv(name); v != "" {
+			*field = v
+		}
+	}
+
+	if v := getenv("CHAIN_ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("CHAIN_ID: invalid chain ID %q", v)
+		}
+		cfg.Upstream.ChainID = id
+	}
+	return nil
+}
+
+// Validate checks cfg for invalid values, reporting every problem found
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(cfg.Server.Addr != "", "server.addr", "must not be empty")
+	durations := []struct {
+		key string
+		d   Duration
+	}{
+		{"server.readHeaderTimeout", cfg.Server.ReadHeaderTimeout},
+		{"server.readTimeout", cfg.Server.ReadTimeout},
+		{"server.writeTimeout", cfg.Server.WriteTimeout},
+		{"server.idleTimeout", cfg.Server.IdleTimeout},
+		{"server.drainDelay", cfg.Server.DrainDelay},
+		{"server.shutdownTimeout", cfg.Server.ShutdownTimeout},
+		{"server.tls.reloadInterval", cfg.Server.TLS.ReloadInterval},
+		{"upstream.timeout", cfg.Upstream.Timeout},
+		{"upstream.maxHeadAge", cfg.Upstream.MaxHeadAge},
+		{"auth.reloadInterval", cfg.Auth.ReloadInterval},
+		{"export.retention", cfg.Export.Retention},
+	}
+	for _, v := range durations {
+		check(v.d >= 0, v.key, "must not be negative, got %s", time.Duration(v.d))
+	}
+	check(cfg.Server.MaxHeaderBytes >= 0, "server.maxHeaderBytes", "must not be negative")
+	check(cfg.Server.MaxBodyBytes >= 0, "server.maxBodyBytes", "must not be negative")
+
+	tls := cfg.Server.TLS
+	check(tls.CertFile == "" || tls.KeyFile != "", "server.tls.keyFile", "is required with server.tls.certFile")
+	check(tls.KeyFile == "" || tls.CertFile != "", "server.tls.certFile", "is required with server.tls.keyFile")
+	check(tls.ClientCAFile == "" || tls.CertFile != "", "server.tls.clientCAFile", "requires server.tls.certFile")
+	check(tls.RedirectAddr == "" || tls.CertFile != "", "server.tls.redirectAddr", "requires server.tls.certFile")
+
+	check(validURL(cfg.Upstream.URL), "upstream.url", "must be an http or https URL")
+
+	if len(cfg.Chains) > 0 {
+		check(cfg.Upstream.ChainID == 0, "upstream.chainId", "must not be set with chains; set chains[].chainId")
+		check(cfg.Index.DB == "", "index.db", "must not be set with chains; set chains[].indexDb")
+	}
+	names := make(map[string]bool)
+	ids := make(map[uint64]bool)
+	for i, chain := range cfg.Chains {
+		key := fmt.Sprintf("chains[%d]", i)
+		if _, err := strconv.ParseUint(chain.Name, 10, 64); err == nil {
+			check(false, key+".name", "must not be a number, got %q", chain.Name)
+		}
+		check(chain.Name != "", key+".name", "must not be empty")
+		check(!strings.Contains(chain.Name, "/"), key+".name", "must not contain /")
+		check(!slices.Contains(reservedChainNames, chain.Name), key+".name", "%q is reserved", chain.Name)
+		check(chain.Name == "" || !names[chain.Name], key+".name", "duplicate chain %q", chain.Name)
+		check(chain.ChainID > 0, key+".chainId", "is required")
+		check(chain.ChainID == 0 || !ids[chain.ChainID], key+".chainId", "duplicate chain ID %d", chain.ChainID)
+		check(len(chain.Upstreams) > 0, key+".upstreams", "must list at least one upstream")
+		for j, u := range chain.Upstreams {
+			check(validURL(u), fmt.Sprintf("%s.upstreams[%d]", key, j), "must be an http or https URL")
+		}
+		names[chain.Name] = true
+		ids[chain.ChainID] = true
+	}
+
+	for _, method := range slices.Sorted(maps.Keys(cfg.Upstream.Quorum)) {
+		key := "upstream.quorum." + method
+		check(slices.Contains(blockchain.QuorumMethods, method), key, "quorum reads are supported for %s", strings.Join(blockchain.QuorumMethods, ", "))
+		q, err := blockchain.ParseQuorum(cfg.Upstream.Quorum[method])
+		if err != nil {
+			check(false, key, "%v", err)
+			continue
+		}
+		if len(cfg.Chains) == 0 {
+			check(q.Agree <= 1, key, "needs %d upstreams, configure them in chains[].upstreams", q.Agree)
+		}
+		for i, chain := range cfg.Chains {
+			check(q.Agree <= len(chain.Upstreams), key, "needs %d upstreams, chains[%d] has %d", q.Agree, i, len(chain.Upstreams))
+		}
+	}
+
+	check(cfg.Index.Workers >= 1, "index.workers", "must be at least 1, got %d", cfg.Index.Workers)
+	check(cfg.Export.MaxJobs >= 1, "export.maxJobs", "must be at least 1, got %d", cfg.Export.MaxJobs)
+	check(cfg.Export.MaxBlocks >= 1, "export.maxBlocks", "must be at least 1")
+
+	for i, method := range cfg.Methods {
+		check(strings.TrimSpace(method) != "", fmt.Sprintf("methods[%d]", i), "must not be empty")
+	}
+
+	check(cfg.RateLimit.Rate >= 0, "rateLimit.rate", "must not be negative")
+	check(cfg.RateLimit.Burst >= 0, "rateLimit.burst", "must not be negative")
+	for _, op := range slices.Sorted(maps.Keys(cfg.RateLimit.ComputeUnits)) {
+		units := cfg.RateLimit.ComputeUnits[op]
+		check(units >= 0, "rateLimit.computeUnits."+op, "must not be negative, got %d", units)
+	}
+
+	if _, err := logging.ParseLevel(cfg.Logging.Level); err != nil {
+		check(false, "logging.level", "must be debug, info, warn or error, got %q", cfg.Logging.Level)
+	}
+	switch cfg.Logging.Format {
+	case logging.FormatText, logging.FormatJSON:
+	default:
+		check(false, "logging.format", "must be text or json, got %q", cfg.Logging.Format)
+	}
+
+	switch cfg.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		check(cfg.Tracing.File != "", "tracing.file", "is required for the file exporter")
+	default:
+		check(false, "tracing.exporter", "must be none, otlp, stdout or file, got %q", cfg.Tracing.Exporter)
+	}
+	check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, "tracing.sampleRatio", "must be between 0 and 1, got %g", cfg.Tracing.SampleRatio)
+
+	return errors.Join(errs...)
+}
+
+// validURL reports whether s is an absolute http or https URL
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// RestartRequired lists the settings that differ between old and cur but only
+// take effect after a restart. The log level, enabled methods, compute unit
+// costs and rate limit values are applied on reload; turning the rate limit
+// on or off is not.
+func RestartRequired(old, cur *Config) []string {
+	a, b := *old, *cur
+	a.Logging.Level = b.Logging.Level
+	a.Methods = b.Methods
+	a.RateLimit.ComputeUnits = b.RateLimit.ComputeUnits
+	if (a.RateLimit.Rate > 0) == (b.RateLimit.Rate > 0) {
+		a.RateLimit.Rate = b.RateLimit.Rate
+		a.RateLimit.Burst = b.RateLimit.Burst
+	}
+
+	sections := []struct {
+		key      string
+		old, cur any
+	}{
+		{"server", a.Server, b.Server},
+		{"upstream", a.Upstream, b.Upstream},
+		{"chains", a.Chains, b.Chains},
+		{"index", a.Index, b.Index},
+		{"export", a.Export, b.Export},
+		{"rateLimit", a.RateLimit, b.RateLimit},
+		{"auth", a.Auth, b.Auth},
+		{"logging", a.Logging, b.Logging},
+		{"tracing", a.Tracing, b.Tracing},
+		{"metrics", a.Metrics, b.Metrics},
+	}
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.old, s.cur) {
+			changed = append(changed, s.key)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  addr: ":9000"
+  readTimeout: 5s
+upstream:
+  url: https://eth.example.com
+methods: [eth_blockNumber]
+rateLimit:
+  rate: 100
+  computeUnits:
+    eth_getLogs: 150
+`,
+		"config.toml": `
+methods = ["eth_blockNumber"]
+
+[server]
+addr = ":9000"
+readTimeout = "5s"
+
+[upstream]
+url = "https://eth.example.com"
+
+[rateLimit]
+rate = 100
+computeUnits = { eth_getLogs = 150 }
+`,
+		"config.json": `{
+  "server": {"addr": ":9000", "readTimeout": "5s"},
+  "upstream": {"url": "https://eth.example.com"},
+  "methods": ["eth_blockNumber"],
+  "rateLimit": {"rate": 100, "computeUnits": {"eth_getLogs": 150}}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg := Default()
+			if err := LoadFile(writeConfig(t, name, content), cfg); err != nil {
+				t.Fatalf("failed to load: %v", err)
+			}
+
+			if cfg.Server.Addr != ":9000" || time.Duration(cfg.Server.ReadTimeout) != 5*time.Second {
+				t.Errorf("unexpected server config %+v", cfg.Server)
+			}
+			if cfg.Upstream.URL != "https://eth.example.com" || !slices.Equal(cfg.Methods, []string{"eth_blockNumber"}) {
+				t.Errorf("unexpected upstream %q or methods %v", cfg.Upstream.URL, cfg.Methods)
+			}
+			if cfg.RateLimit.Rate != 100 || cfg.RateLimit.ComputeUnits["eth_getLogs"] != 150 {
+				t.Errorf("unexpected rate limit %+v", cfg.RateLimit)
+			}
+			// Settings missing from the file keep their defaults
+			if time.Duration(cfg.Server.WriteTimeout) != 60*time.Second || cfg.Index.Workers != 4 {
+				t.Errorf("expected defaults to be kept, got %+v", cfg)
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("unexpected validation error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "server:\n  adress: \":9000\"\n",
+		"config.toml": "[server]\nadress = \":9000\"\n",
+		"config.json": `{"server": {"adress": ":9000"}}`,
+		"config.ini":  "addr = :9000",
+	}
+	for name, content := range files {
+		err := LoadFile(writeConfig(t, name, content), Default())
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if name != "config.ini" && !strings.Contains(err.Error(), "adress") {
+			t.Errorf("%s: expected the error to name the unknown key, got %v", name, err)
+		}
+	}
+
+	if err := LoadFile(writeConfig(t, "config.yaml", "server:\n  readTimeout: 30\n"), Default()); err == nil || !strings.Contains(err.Error(), "invalid duration") {
+		t.Errorf("expected durations without units to be rejected, got %v", err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"BLOCKCHAIN_RPC_URL": "https://env.example.com",
+		"CHAIN_ID":           "137",
+		"LOG_LEVEL":          "debug",
+	}
+	cfg := Default()
+	if err := cfg.ApplyEnv(func(name string) string { return env[name] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Upstream.URL != "https://env.example.com" || cfg.Upstream.ChainID != 137 || cfg.Logging.Level != "debug" {
+		t.Errorf("environment not applied: %+v", cfg)
+	}
+
+	env["CHAIN_ID"] = "polygon"
+	if err := cfg.ApplyEnv(func(name string) string { return env[name] }); err == nil {
+		t.Error("expected invalid CHAIN_ID to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.ReadTimeout = Duration(-time.Second)
+	cfg.Server.TLS.CertFile = "cert.pem"
+	cfg.Upstream.URL = "polygon-rpc.com"
+	cfg.Index.Workers = 0
+	cfg.Logging.Level = "verbose"
+	cfg.Tracing.SampleRatio = 2
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, key := range []string{"server.readTimeout", "server.tls.keyFile", "upstream.url", "index.workers", "logging.level", "tracing.sampleRatio"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("expected an error for %s, got:\n%v", key, err)
+		}
+	}
+
+	if err := Default().Validate(); err != nil {
+		t.Errorf("expected the defaults to be valid, got %v", err)
+	}
+}
+
+func TestValidateChains(t *testing.T) {
+	cfg := Default()
+	cfg.Index.DB = "blocks.db"
+	cfg.Chains = []Chain{
+		{Name: "polygon", ChainID: 137, Upstreams: []string{"https://polygon.example.com", "https://polygon-backup.example.com"}},
+		{Name: "polygon", ChainID: 137, Upstreams: []string{"eth.example.com"}},
+		{Name: "1", ChainID: 1},
+		{Name: "api", ChainID: 8453, Upstreams: []string{"https://base.example.com"}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, key := range []string{"index.db", "chains[1].name", "chains[1].chainId", "chains[1].upstreams[0]", "chains[2].name", "chains[2].upstreams", "chains[3].name"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("expected an error for %s, got:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "chains[0]") {
+		t.Errorf("expected the first chain to be valid, got:\n%v", err)
+	}
+
+	cfg.Index.DB = ""
+	cfg.Chains = cfg.Chains[:1]
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if chains := cfg.HostedChains(); len(chains) != 1 || chains[0].Name != "polygon" {
+		t.Errorf("unexpected hosted chains %+v", chains)
+	}
+
+	single := Default().HostedChains()
+	if len(single) != 1 || single[0].Name != "" || !slices.Equal(single[0].Upstreams, []string{Default().Upstream.URL}) {
+		t.Errorf("expected the upstream section to describe a single chain, got %+v", single)
+	}
+}
+
+func TestValidateQuorum(t *testing.T) {
+	cfg := Default()
+	cfg.Upstream.Quorum = map[string]string{"eth_getProof": "2"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "upstream.quorum.eth_getProof:") {
+		t.Errorf("expected a quorum larger than the single upstream to be rejected, got %v", err)
+	}
+
+	cfg.Chains = []Chain{{Name: "polygon", ChainID: 137, Upstreams: []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"}}}
+	cfg.Upstream.Quorum = map[string]string{"eth_getProof": "2/3", "eth_getBlockByNumber": "3"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	cfg.Upstream.Quorum = map[string]string{"eth_getProof": "4", "eth_getBlockByNumber": "2/1", "eth_call": "2"}
+	err := cfg.Validate()
+	for _, key := range []string{"upstream.quorum.eth_getProof", "upstream.quorum.eth_getBlockByNumber", "upstream.quorum.eth_call"} {
+		if err == nil || !strings.Contains(err.Error(), key+":") {
+			t.Errorf("expected an error for %s, got:\n%v", key, err)
+		}
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := Default()
+	old.RateLimit.Rate = 10
+
+	cur := Default()
+	cur.RateLimit.Rate = 20
+	cur.Logging.Level = "debug"
+	cur.Methods = []string{"eth_blockNumber"}
+	if changed := RestartRequired(old, cur); len(changed) != 0 {
+		t.Errorf("expected reloadable changes only, got %v", changed)
+	}
+
+	cur.Server.Addr = ":9000"
+	cur.RateLimit.Rate = 0
+	if changed := RestartRequired(old, cur); !slices.Equal(changed, []string{"server", "rateLimit"}) {
+		t.Errorf("expected server and rateLimit to require a restart, got %v", changed)
+	}
+}
@@ -0,0 +1,346 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// CheckpointFile is the name of the checkpoint file written to the output
+// directory after every completed file rotation
+const CheckpointFile = "export-checkpoint.json"
+
+const (
+	defaultRotateBlocks = 1000
+	defaultWorkers      = 4
+)
+
+// Source is the subset of the blockchain client the exporter reads from
+type Source interface {
+	GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	GetBlockReceipts(ctx context.Context, blockNumber string) ([]*blockchain.Receipt, error)
+}
+
+// Config represents the export configuration
+type Config struct {
+	// From and To are the inclusive block range to export
+	From uint64
+	To   uint64
+	// Format is the output file format
+	Format Format
+	// OutDir receives one subdirectory per table plus the checkpoint file
+	OutDir string
+	// RotateBlocks is the number of blocks written to each file
+	RotateBlocks uint64
+	// Workers is the number of blocks fetched in parallel
+	Workers int
+	// SkipReceipts disables the receipts and logs tables
+	SkipReceipts bool
+}
+
+// Checkpoint records export progress so an interrupted export can resume
+type Checkpoint struct {
+	From         uint64   `json:"from"`
+	To           uint64   `json:"to"`
+	Format       Format   `json:"format"`
+	RotateBlocks uint64   `json:"rotateBlocks"`
+	SkipReceipts bool     `json:"skipReceipts"`
+	Next         uint64   `json:"next"`
+	Files        []string `json:"files"`
+}
+
+// Progress represents the state of an export
+type Progress struct {
+	From  uint64   `json:"from"`
+	To    uint64   `json:"to"`
+	Next  uint64   `json:"next"`
+	Files []string `json:"files"`
+}
+
+// Exporter walks a block range and writes it out as tabular files
+type Exporter struct {
+	source Source
+	cfg    Config
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+// New creates a new exporter
+func New(source Source, cfg Config) (*Exporter, error) {
+	if cfg.To < cfg.From {
+		return nil, errors.New("to block must not be below from block")
+	}
+	if _, err := ParseFormat(string(cfg.Format)); err != nil {
+		return nil, err
+	}
+	if cfg.OutDir == "" {
+		return nil, errors.New("output directory is required")
+	}
+	if cfg.RotateBlocks == 0 {
+		cfg.RotateBlocks = defaultRotateBlocks
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+
+	return &Exporter{
+		source:   source,
+		cfg:      cfg,
+		progress: Progress{From: cfg.From, To: cfg.To, Next: cfg.From, Files: []string{}},
+	}, nil
+}
+
+// Progress returns a snapshot of the export progress
+func (e *Exporter) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p := e.progress
+	p.Files = append([]string(nil), e.progress.Files...)
+	return p
+}
+
+// tables returns the tables written by this export
+func (e *Exporter) tables() []Table {
+	if e.cfg.SkipReceipts {
+		return []Table{BlocksTable, TransactionsTable}
+	}
+	return Tables
+}
+
+// loadCheckpoint reads the checkpoint file, verifying it belongs to an
+// export with the same parameters
+func (e *Exporter) loadCheckpoint() (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(e.cfg.OutDir, CheckpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if cp.From != e.cfg.From || cp.To != e.cfg.To || cp.Format != e.cfg.Format || cp.RotateBlocks != e.cfg.RotateBlocks || cp.SkipReceipts != e.cfg.SkipReceipts {
+		return nil, fmt.Errorf("checkpoint in %s belongs to a different export (blocks %d-%d, format %s, rotation %d)", e.cfg.OutDir, cp.From, cp.To, cp.Format, cp.RotateBlocks)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint atomically writes the checkpoint file
+func (e *Exporter) saveCheckpoint(p Progress) error {
+	data, err := json.MarshalIndent(Checkpoint{
+		From:         e.cfg.From,
+		To:           e.cfg.To,
+		Format:       e.cfg.Format,
+		RotateBlocks: e.cfg.RotateBlocks,
+		SkipReceipts: e.cfg.SkipReceipts,
+		Next:         p.Next,
+		Files:        p.Files,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(e.cfg.OutDir, CheckpointFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Run exports the configured range, resuming after the last completed file
+// if a matching checkpoint exists
+func (e *Exporter) Run(ctx context.Context) error {
+	if err := os.MkdirAll(e.cfg.OutDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	cp, err := e.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	if cp != nil {
+		e.mu.Lock()
+		e.progress.Next = cp.Next
+		e.progress.Files = cp.Files
+		e.mu.Unlock()
+		if cp.Next <= e.cfg.To {
+			slog.Info("export: resuming", "block", cp.Next)
+		}
+	}
+
+	for {
+		p := e.Progress()
+		if p.Next > e.cfg.To {
+			return nil
+		}
+
+		start := p.Next
+		end := start + e.cfg.RotateBlocks - 1
+		if end > e.cfg.To || end < start {
+			end = e.cfg.To
+		}
+
+		files, err := e.exportChunk(ctx, start, end)
+		if err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		e.progress.Next = end + 1
+		e.progress.Files = append(e.progress.Files, files...)
+		e.mu.Unlock()
+
+		if err := e.saveCheckpoint(e.Progress()); err != nil {
+			return err
+		}
+		slog.Info("export: wrote blocks", "from", start, "to", end)
+	}
+}
+
+// chunkFile is an output file being written for one table
+type chunkFile struct {
+	file   *os.File
+	writer Writer
+	path   string
+}
+
+// exportChunk writes blocks [start, end] to one new file per table. Files are
+// written under a temporary name and renamed only once complete, so an
+// interrupted chunk leaves no partial output behind.
+func (e *Exporter) exportChunk(ctx context.Context, start, end uint64) ([]string, error) {
+	tables := e.tables()
+	files := make([]*chunkFile, len(tables))
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.file.Close()
+				os.Remove(f.path + ".tmp")
+			}
+		}
+	}()
+
+	for i, table := range tables {
+		dir := filepath.Join(e.cfg.OutDir, table.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s_%012d_%012d.%s", table.Name, start, end, e.cfg.Format))
+		file, err := os.Create(path + ".tmp")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		files[i] = &chunkFile{file: file, path: path}
+
+		if files[i].writer, err = NewWriter(e.cfg.Format, file, table); err != nil {
+			return nil, err
+		}
+	}
+
+	for batchStart := start; batchStart <= end; batchStart += uint64(e.cfg.Workers) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		batchEnd := batchStart + uint64(e.cfg.Workers) - 1
+		if batchEnd > end {
+			batchEnd = end
+		}
+		if err := e.exportBatch(ctx, batchStart, batchEnd, files); err != nil {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	for i, f := range files {
+		if err := f.writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to finish %s file: %w", tables[i].Name, err)
+		}
+		if err := f.file.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(f.path+".tmp", f.path); err != nil {
+			return nil, err
+		}
+		files[i] = nil
+		paths = append(paths, f.path)
+	}
+
+	return paths, nil
+}
+
+// blockRows holds the flattened rows of one block, indexed by table
+type blockRows [][]Row
+
+// exportBatch fetches blocks [start, end] in parallel and writes their rows
+// in block order
+func (e *Exporter) exportBatch(ctx context.Context, start, end uint64, files []*chunkFile) error {
+	results := make([]blockRows, end-start+1)
+	errs := make([]error, len(results))
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = e.fetchRows(ctx, start+uint64(i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, rows := range results {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		for t, tableRows := range rows {
+			for _, row := range tableRows {
+				if err := files[t].writer.WriteRow(row); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fetchRows fetches one block and flattens it into rows for each table
+func (e *Exporter) fetchRows(ctx context.Context, number uint64) (blockRows, error) {
+	blockNumber := blockchain.EncodeQuantity(number)
+
+	block, err := e.source.GetBlockByNumber(ctx, blockNumber, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	if block.Number == "" {
+		return nil, fmt.Errorf("block %d not available upstream", number)
+	}
+
+	blockRow, txRows, err := FlattenBlock(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten block %d: %w", number, err)
+	}
+	rows := blockRows{{blockRow}, txRows}
+
+	if !e.cfg.SkipReceipts {
+		receipts, err := e.source.GetBlockReceipts(ctx, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipts for block %d: %w", number, err)
+		}
+		receiptRows, logRows := FlattenReceipts(receipts)
+		rows = append(rows, receiptRows, logRows)
+	}
+
+	return rows, nil
+}
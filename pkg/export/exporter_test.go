@@ -0,0 +1,243 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// fakeSource serves synthetic blocks with two transactions and one log each
+type fakeSource struct {
+	mu      sync.Mutex
+	failAt  uint64
+	fetched map[uint64]int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{fetched: make(map[uint64]int)}
+}
+
+func (f *fakeSource) GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+	n, _ := blockchain.ParseQuantity(blockNumber)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failAt != 0 && n == f.failAt {
+		return nil, fmt.Errorf("upstream unavailable")
+	}
+	f.fetched[n]++
+
+	txs, _ := json.Marshal([]map[string]string{
+		{"hash": fmt.Sprintf("0x%xa", n), "from": "0xfrom", "to": "0xto", "value": "0xde0b6b3a7640000", "gas": "0x5208", "transactionIndex": "0x0", "nonce": "0x1", "type": "0x2"},
+		{"hash": fmt.Sprintf("0x%xb", n), "from": "0xfrom", "value": "0x0", "gas": "0x5208", "transactionIndex": "0x1", "nonce": "0x2", "type": "0x0"},
+	})
+	return &blockchain.Block{
+		Number:           blockNumber,
+		Hash:             fmt.Sprintf("0xblock%x", n),
+		ParentHash:       fmt.Sprintf("0xblock%x", n-1),
+		Timestamp:        "0x60000000",
+		GasUsed:          "0xa410",
+		BaseFeePerGas:    "0x7",
+		Transactions:     txs,
+		TransactionCount: 2,
+	}, nil
+}
+
+func (f *fakeSource) GetBlockReceipts(ctx context.Context, blockNumber string) ([]*blockchain.Receipt, error) {
+	return []*blockchain.Receipt{{
+		TransactionHash: blockNumber + "a",
+		BlockNumber:     blockNumber,
+		Status:          "0x1",
+		Logs: []*blockchain.Log{{
+			Address:     "0xtoken",
+			Topics:      []string{"0xsig", "0xfrom"},
+			BlockNumber: blockNumber,
+			LogIndex:    "0x0",
+		}},
+	}}, nil
+}
+
+func TestExportCSV(t *testing.T) {
+	dir := t.TempDir()
+	exporter, err := New(newFakeSource(), Config{From: 100, To: 124, Format: FormatCSV, OutDir: dir, RotateBlocks: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exporter.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Three rotations of four tables each
+	if files := exporter.Progress().Files; len(files) != 12 {
+		t.Fatalf("expected 12 files, got %d", len(files))
+	}
+
+	f, err := os.Open(filepath.Join(dir, "transactions", "transactions_000000000120_000000000124.csv"))
+	if err != nil {
+		t.Fatalf("expected last transactions file: %v", err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 11 {
+		t.Fatalf("expected header and 10 rows, got %d records", len(records))
+	}
+	if records[0][0] != "hash" || records[0][6] != "value" {
+		t.Errorf("unexpected header %v", records[0])
+	}
+	if records[1][1] != "120" || records[1][6] != "1000000000000000000" {
+		t.Errorf("expected block 120 with value 1 ether, got %v", records[1])
+	}
+	if records[2][5] != "" {
+		t.Errorf("expected empty to_address for contract creation, got %q", records[2][5])
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	exporter, err := New(newFakeSource(), Config{From: 1, To: 5, Format: FormatNDJSON, OutDir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exporter.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "logs", "logs_000000000001_000000000005.ndjson"))
+	if err != nil {
+		t.Fatalf("expected logs file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lines := 0
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("invalid NDJSON line: %v", err)
+		}
+		if row["topic1"] != "0xfrom" || row["topic2"] != nil || row["removed"] != false {
+			t.Errorf("unexpected log row %v", row)
+		}
+		lines++
+	}
+	if lines != 5 {
+		t.Errorf("expected 5 log rows, got %d", lines)
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	dir := t.TempDir()
+	exporter, err := New(newFakeSource(), Config{From: 1, To: 3, Format: FormatParquet, OutDir: dir, SkipReceipts: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exporter.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if files := exporter.Progress().Files; len(files) != 2 {
+		t.Fatalf("expected blocks and transactions files only, got %v", files)
+	}
+
+	type blockRecord struct {
+		Number        *int64  `parquet:"number,optional"`
+		Hash          *string `parquet:"hash,optional"`
+		Miner         *string `parquet:"miner,optional"`
+		BaseFeePerGas *string `parquet:"base_fee_per_gas,optional"`
+		GasUsed       *int64  `parquet:"gas_used,optional"`
+	}
+
+	rows, err := parquet.ReadFile[blockRecord](filepath.Join(dir, "blocks", "blocks_000000000001_000000000003.parquet"))
+	if err != nil {
+		t.Fatalf("failed to read parquet file: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[1].Number == nil || *rows[1].Number != 2 || *rows[1].Hash != "0xblock2" {
+		t.Errorf("unexpected second row %+v", rows[1])
+	}
+	if *rows[0].GasUsed != 42000 || *rows[0].BaseFeePerGas != "7" {
+		t.Errorf("unexpected gas values %+v", rows[0])
+	}
+	if rows[0].Miner != nil {
+		t.Errorf("expected null miner, got %q", *rows[0].Miner)
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	dir := t.TempDir()
+	source := newFakeSource()
+	source.failAt = 25
+
+	cfg := Config{From: 0, To: 39, Format: FormatCSV, OutDir: dir, RotateBlocks: 10}
+	exporter, err := New(source, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exporter.Run(context.Background()); err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+
+	// The interrupted rotation leaves no partial files
+	if _, err := os.Stat(filepath.Join(dir, "blocks", "blocks_000000000020_000000000029.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected no file for the interrupted rotation")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "*", "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Errorf("expected temporary files to be removed, got %v", leftovers)
+	}
+
+	source.mu.Lock()
+	source.failAt = 0
+	fetchedBefore := source.fetched[5]
+	source.mu.Unlock()
+
+	exporter, err = New(source, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exporter.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if source.fetched[5] != fetchedBefore {
+		t.Errorf("expected completed rotations not to be fetched again")
+	}
+	if files := exporter.Progress().Files; len(files) != 16 {
+		t.Errorf("expected 16 files after resuming, got %d", len(files))
+	}
+
+	// A different export into the same directory is rejected
+	cfg.To = 50
+	exporter, _ = New(source, cfg)
+	if err := exporter.Run(context.Background()); err == nil {
+		t.Errorf("expected mismatched checkpoint to be rejected")
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := New(newFakeSource(), Config{From: 10, To: 5, Format: FormatCSV, OutDir: "x"}); err == nil {
+		t.Errorf("expected error for inverted range")
+	}
+	if _, err := New(newFakeSource(), Config{From: 1, To: 5, Format: "xlsx", OutDir: "x"}); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+	if _, err := New(newFakeSource(), Config{From: 1, To: 5, Format: FormatCSV}); err == nil {
+		t.Errorf("expected error for missing output directory")
+	}
+}
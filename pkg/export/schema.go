@@ -0,0 +1,215 @@
+package export
+
+import (
+	"math/big"
+	"strings"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// ColumnType is the logical type of an exported column
+type ColumnType int
+
+const (
+	// TypeString columns hold text, including hashes and wei amounts that
+	// may not fit in 64 bits
+	TypeString ColumnType = iota
+	// TypeInt64 columns hold integer quantities such as heights and gas
+	TypeInt64
+	// TypeBool columns hold flags
+	TypeBool
+)
+
+// Column represents a column in an exported table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table represents the schema of an exported table
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// Row holds one value per column: string, int64, bool or nil for null
+type Row []interface{}
+
+// Exported tables
+var (
+	BlocksTable = Table{Name: "blocks", Columns: []Column{
+		{"number", TypeInt64},
+		{"hash", TypeString},
+		{"parent_hash", TypeString},
+		{"nonce", TypeString},
+		{"timestamp", TypeInt64},
+		{"miner", TypeString},
+		{"gas_limit", TypeInt64},
+		{"gas_used", TypeInt64},
+		{"base_fee_per_gas", TypeString},
+		{"size", TypeInt64},
+		{"transaction_count", TypeInt64},
+	}}
+
+	TransactionsTable = Table{Name: "transactions", Columns: []Column{
+		{"hash", TypeString},
+		{"block_number", TypeInt64},
+		{"block_hash", TypeString},
+		{"transaction_index", TypeInt64},
+		{"from_address", TypeString},
+		{"to_address", TypeString},
+		{"value", TypeString},
+		{"gas", TypeInt64},
+		{"gas_price", TypeString},
+		{"max_fee_per_gas", TypeString},
+		{"max_priority_fee_per_gas", TypeString},
+		{"input", TypeString},
+		{"nonce", TypeInt64},
+		{"type", TypeInt64},
+	}}
+
+	ReceiptsTable = Table{Name: "receipts", Columns: []Column{
+		{"transaction_hash", TypeString},
+		{"block_number", TypeInt64},
+		{"transaction_index", TypeInt64},
+		{"from_address", TypeString},
+		{"to_address", TypeString},
+		{"contract_address", TypeString},
+		{"status", TypeInt64},
+		{"gas_used", TypeInt64},
+		{"cumulative_gas_used", TypeInt64},
+		{"effective_gas_price", TypeString},
+		{"type", TypeInt64},
+	}}
+
+	LogsTable = Table{Name: "logs", Columns: []Column{
+		{"block_number", TypeInt64},
+		{"transaction_hash", TypeString},
+		{"transaction_index", TypeInt64},
+		{"log_index", TypeInt64},
+		{"address", TypeString},
+		{"data", TypeString},
+		{"topic0", TypeString},
+		{"topic1", TypeString},
+		{"topic2", TypeString},
+		{"topic3", TypeString},
+		{"removed", TypeBool},
+	}}
+
+	// Tables lists every exported table in write order
+	Tables = []Table{BlocksTable, TransactionsTable, ReceiptsTable, LogsTable}
+)
+
+// str converts an optional string field, mapping empty strings to null
+func str(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// quantity converts a hex quantity to int64, mapping missing or oversized
+// values to null
+func quantity(s string) interface{} {
+	n, err := blockchain.ParseQuantity(s)
+	if err != nil || n > 1<<63-1 {
+		return nil
+	}
+	return int64(n)
+}
+
+// decimal converts a hex quantity of arbitrary size to a decimal string
+func decimal(s string) interface{} {
+	if !strings.HasPrefix(s, "0x") {
+		return nil
+	}
+	n, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil
+	}
+	return n.String()
+}
+
+// FlattenBlock converts a block fetched with full transactions into rows
+func FlattenBlock(block *blockchain.Block) (Row, []Row, error) {
+	blockRow := Row{
+		quantity(block.Number),
+		str(block.Hash),
+		str(block.ParentHash),
+		str(block.Nonce),
+		quantity(block.Timestamp),
+		str(block.Miner),
+		quantity(block.GasLimit),
+		quantity(block.GasUsed),
+		decimal(block.BaseFeePerGas),
+		quantity(block.Size),
+		int64(block.TransactionCount),
+	}
+
+	txs, err := block.FullTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txRows := make([]Row, len(txs))
+	for i, tx := range txs {
+		txRows[i] = Row{
+			str(tx.Hash),
+			quantity(block.Number),
+			str(block.Hash),
+			quantity(tx.TransactionIndex),
+			str(tx.From),
+			str(tx.To),
+			decimal(tx.Value),
+			quantity(tx.Gas),
+			decimal(tx.GasPrice),
+			decimal(tx.MaxFeePerGas),
+			decimal(tx.MaxPriorityFeePerGas),
+			str(tx.Input),
+			quantity(tx.Nonce),
+			quantity(tx.Type),
+		}
+	}
+
+	return blockRow, txRows, nil
+}
+
+// FlattenReceipts converts receipts into receipt rows and log rows
+func FlattenReceipts(receipts []*blockchain.Receipt) ([]Row, []Row) {
+	var receiptRows, logRows []Row
+	for _, receipt := range receipts {
+		receiptRows = append(receiptRows, Row{
+			str(receipt.TransactionHash),
+			quantity(receipt.BlockNumber),
+			quantity(receipt.TransactionIndex),
+			str(receipt.From),
+			str(receipt.To),
+			str(receipt.ContractAddress),
+			quantity(receipt.Status),
+			quantity(receipt.GasUsed),
+			quantity(receipt.CumulativeGasUsed),
+			decimal(receipt.EffectiveGasPrice),
+			quantity(receipt.Type),
+		})
+
+		for _, l := range receipt.Logs {
+			row := Row{
+				quantity(l.BlockNumber),
+				str(l.TransactionHash),
+				quantity(l.TransactionIndex),
+				quantity(l.LogIndex),
+				str(l.Address),
+				str(l.Data),
+				nil, nil, nil, nil,
+				l.Removed,
+			}
+			for i, topic := range l.Topics {
+				if i < 4 {
+					row[6+i] = topic
+				}
+			}
+			logRows = append(logRows, row)
+		}
+	}
+	return receiptRows, logRows
+}
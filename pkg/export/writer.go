@@ -0,0 +1,189 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is an output file format
+type Format string
+
+// Supported output formats
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported format %q: expected csv, ndjson or parquet", s)
+}
+
+// Writer writes rows of a single table to an output file
+type Writer interface {
+	WriteRow(row Row) error
+	// Close flushes buffered rows; it does not close the underlying output
+	Close() error
+}
+
+// NewWriter creates a writer for table in the given format
+func NewWriter(format Format, w io.Writer, table Table) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, table)
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), table: table}, nil
+	case FormatParquet:
+		return newParquetWriter(w, table), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// csvWriter writes rows as CSV with a header line; nulls are empty fields
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, table Table) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(table.Columns))}
+	for i, col := range table.Columns {
+		cw.record[i] = col.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteRow(row Row) error {
+	for i, v := range row {
+		switch v := v.(type) {
+		case nil:
+			cw.record[i] = ""
+		case string:
+			cw.record[i] = v
+		case int64:
+			cw.record[i] = strconv.FormatInt(v, 10)
+		case bool:
+			cw.record[i] = strconv.FormatBool(v)
+		}
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter writes each row as a JSON object keyed by column name
+type ndjsonWriter struct {
+	enc   *json.Encoder
+	table Table
+}
+
+func (nw *ndjsonWriter) WriteRow(row Row) error {
+	obj := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		obj[nw.table.Columns[i].Name] = v
+	}
+	return nw.enc.Encode(obj)
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+// parquetWriter writes rows to a Parquet file with every column optional
+type parquetWriter struct {
+	w *parquet.Writer
+	// columnIndex maps table column position to Parquet leaf column index
+	columnIndex []int
+	rows        []parquet.Row
+}
+
+// parquetBatchSize is the number of rows buffered before writing to Parquet
+const parquetBatchSize = 1024
+
+func newParquetWriter(w io.Writer, table Table) *parquetWriter {
+	group := parquet.Group{}
+	for _, col := range table.Columns {
+		var node parquet.Node
+		switch col.Type {
+		case TypeString:
+			node = parquet.String()
+		case TypeInt64:
+			node = parquet.Int(64)
+		case TypeBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		}
+		group[col.Name] = parquet.Optional(parquet.Compressed(node, &parquet.Snappy))
+	}
+	schema := parquet.NewSchema(table.Name, group)
+
+	// Parquet orders group fields by name, which differs from table order
+	positions := make(map[string]int)
+	for i, field := range schema.Fields() {
+		positions[field.Name()] = i
+	}
+	columnIndex := make([]int, len(table.Columns))
+	for i, col := range table.Columns {
+		columnIndex[i] = positions[col.Name]
+	}
+
+	return &parquetWriter{w: parquet.NewWriter(w, schema), columnIndex: columnIndex}
+}
+
+func (pw *parquetWriter) WriteRow(row Row) error {
+	values := make(parquet.Row, len(row))
+	for i, v := range row {
+		var value parquet.Value
+		definition := 1
+		switch v := v.(type) {
+		case nil:
+			value = parquet.NullValue()
+			definition = 0
+		case string:
+			value = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			value = parquet.Int64Value(v)
+		case bool:
+			value = parquet.BooleanValue(v)
+		}
+		col := pw.columnIndex[i]
+		values[col] = value.Level(0, definition, col)
+	}
+
+	pw.rows = append(pw.rows, values)
+	if len(pw.rows) >= parquetBatchSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+func (pw *parquetWriter) flush() error {
+	if len(pw.rows) == 0 {
+		return nil
+	}
+	_, err := pw.w.WriteRows(pw.rows)
+	pw.rows = pw.rows[:0]
+	return err
+}
+
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	return pw.w.Close()
+}
@@ -0,0 +1,318 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/store"
+)
+
+// BackfillCheckpoint is the checkpoint recording the highest height below
+// which every block from the start height has been ingested
+const BackfillCheckpoint = "backfill"
+
+const (
+	defaultWorkers    = 4
+	defaultReorgDepth = 64
+)
+
+// Source is the subset of the blockchain client the indexer ingests from
+type Source interface {
+	GetBlockNumber(ctx context.Context) (string, error)
+	GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+	GetBlockReceipts(ctx context.Context, blockNumber string) ([]*blockchain.Receipt, error)
+}
+
+// Config represents the indexer configuration
+type Config struct {
+	// StartHeight is the first block to ingest
+	StartHeight uint64
+	// Workers is the number of blocks fetched in parallel during backfill
+	Workers int
+	// SkipReceipts disables fetching receipts and logs
+	SkipReceipts bool
+	// PollInterval is how often live tail mode polls for a new head
+	PollInterval time.Duration
+	// ReorgDepth is how far live tail mode walks back to find a common ancestor
+	ReorgDepth int
+}
+
+// Indexer ingests blocks from the blockchain client into the store
+type Indexer struct {
+	source Source
+	store  *store.Store
+	cfg    Config
+
+	// rollbackFrom is the tip height where the current reorg walk-back
+	// started, or zero when no walk-back is in progress
+	rollbackFrom uint64
+}
+
+// New creates a new indexer
+func New(source Source, st *store.Store, cfg Config) *Indexer {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.ReorgDepth <= 0 {
+		cfg.ReorgDepth = defaultReorgDepth
+	}
+	return &Indexer{source: source, store: st, cfg: cfg}
+}
+
+// fetch retrieves everything ingested for the block at number
+func (ix *Indexer) fetch(ctx context.Context, number uint64) (*store.BlockData, error) {
+	blockNumber := blockchain.EncodeQuantity(number)
+
+	block, err := ix.source.GetBlockByNumber(ctx, blockNumber, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	if block.Number == "" {
+		return nil, fmt.Errorf("block %d not available upstream", number)
+	}
+
+	data := &store.BlockData{Block: block}
+	if !ix.cfg.SkipReceipts {
+		receipts, err := ix.source.GetBlockReceipts(ctx, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipts for block %d: %w", number, err)
+		}
+		data.Receipts = receipts
+	}
+
+	return data, nil
+}
+
+// IngestBlock fetches and stores the block at number
+func (ix *Indexer) IngestBlock(ctx context.Context, number uint64) error {
+	data, err := ix.fetch(ctx, number)
+	if err != nil {
+		return err
+	}
+	return ix.store.PutBlock(data)
+}
+
+// resumeHeight returns the first height that still needs backfilling
+func (ix *Indexer) resumeHeight() (uint64, error) {
+	checkpoint, ok, err := ix.store.Checkpoint(BackfillCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+	if ok && checkpoint+1 > ix.cfg.StartHeight {
+		return checkpoint + 1, nil
+	}
+	return ix.cfg.StartHeight, nil
+}
+
+// progress tracks completed heights and reports the highest height below
+// which every block has completed
+type progress struct {
+	mu   sync.Mutex
+	next uint64
+	done map[uint64]bool
+}
+
+// complete marks number as done and returns the new contiguous checkpoint,
+// if it advanced
+func (p *progress) complete(number uint64) (uint64, bool) {
+	p.done[number] = true
+
+	advanced := false
+	for p.done[p.next] {
+		delete(p.done, p.next)
+		p.next++
+		advanced = true
+	}
+	return p.next - 1, advanced
+}
+
+// Backfill ingests every block in [from, to] using parallel workers, skipping
+// blocks that are already stored and advancing the backfill checkpoint as
+// contiguous ranges complete
+func (ix *Indexer) Backfill(ctx context.Context, from, to uint64) error {
+	if from > to {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slog.Info("indexer: backfilling blocks", "from", from, "to", to, "workers", ix.cfg.Workers)
+
+	heights := make(chan uint64)
+	prog := &progress{next: from, done: make(map[uint64]bool)}
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < ix.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range heights {
+				exists, err := ix.store.HasBlock(number)
+				if err != nil {
+					fail(err)
+					return
+				}
+				if !exists {
+					if err := ix.IngestBlock(ctx, number); err != nil {
+						fail(err)
+						return
+					}
+				}
+
+				prog.mu.Lock()
+				checkpoint, advanced := prog.complete(number)
+				if advanced {
+					err = ix.store.SetCheckpoint(BackfillCheckpoint, checkpoint)
+				}
+				prog.mu.Unlock()
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for number := from; number <= to; number++ {
+		select {
+		case heights <- number:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(heights)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	slog.Info("indexer: backfill complete", "from", from, "to", to)
+	return nil
+}
+
+// Tail ingests new blocks as the follower reports them, starting at next and
+// rolling back stored blocks when a reorg is detected
+func (ix *Indexer) Tail(ctx context.Context, follower *blockchain.HeadFollower, next uint64) error {
+	heads, unsubscribe := follower.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case head := <-heads:
+			for next <= head {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				n, err := ix.ingestTip(ctx, next)
+				if err != nil {
+					slog.Error("indexer: failed to ingest block", "block", next, "error", err)
+					break
+				}
+				next = n
+			}
+		}
+	}
+}
+
+// ingestTip ingests the block at number on top of the stored chain and
+// returns the next height to ingest. If the new block does not extend the
+// stored parent, the parent is removed and the returned height steps back so
+// the replaced branch is re-ingested. The walk-back fails once it has removed
+// more than ReorgDepth blocks without finding a common ancestor.
+func (ix *Indexer) ingestTip(ctx context.Context, number uint64) (uint64, error) {
+	data, err := ix.fetch(ctx, number)
+	if err != nil {
+		return number, err
+	}
+
+	if number > ix.cfg.StartHeight {
+		parent, err := ix.store.GetBlock(number-1, false)
+		switch {
+		case err == store.ErrNotFound:
+		case err != nil:
+			return number, err
+		case parent.Hash != data.Block.ParentHash:
+			if ix.rollbackFrom == 0 {
+				ix.rollbackFrom = number
+			}
+			if ix.rollbackFrom-(number-1) > uint64(ix.cfg.ReorgDepth) {
+				return number, fmt.Errorf("reorg at block %d deeper than %d blocks", ix.rollbackFrom, ix.cfg.ReorgDepth)
+			}
+
+			slog.Warn("indexer: reorg detected, rolling back parent", "block", number, "rollback", number-1)
+			if err := ix.store.DeleteBlock(number - 1); err != nil {
+				return number, err
+			}
+			if number-1 > ix.cfg.StartHeight {
+				if err := ix.store.SetCheckpoint(BackfillCheckpoint, number-2); err != nil {
+					return number, err
+				}
+			}
+			return number - 1, nil
+		}
+	}
+
+	if err := ix.store.PutBlock(data); err != nil {
+		return number, err
+	}
+	if err := ix.store.SetCheckpoint(BackfillCheckpoint, number); err != nil {
+		return number, err
+	}
+	ix.rollbackFrom = 0
+	return number + 1, nil
+}
+
+// Run backfills from the configured start height (or the last checkpoint) up
+// to the current head, then follows the chain in live tail mode until ctx is
+// cancelled
+func (ix *Indexer) Run(ctx context.Context) error {
+	from, err := ix.resumeHeight()
+	if err != nil {
+		return err
+	}
+
+	blockNumber, err := ix.source.GetBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain head: %w", err)
+	}
+	head, err := blockchain.ParseQuantity(blockNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := ix.Backfill(ctx, from, head); err != nil {
+		return err
+	}
+
+	follower := blockchain.NewHeadFollower(ix.source, ix.cfg.PollInterval)
+	go follower.Run(ctx)
+
+	next := head + 1
+	if from > next {
+		next = from
+	}
+	slog.Info("indexer: following chain head", "from", next)
+	return ix.Tail(ctx, follower, next)
+}
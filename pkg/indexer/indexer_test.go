@@ -0,0 +1,260 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+	"blockchain-client/pkg/store"
+)
+
+// fakeChain is an in-memory chain implementing Source
+type fakeChain struct {
+	mu      sync.Mutex
+	head    uint64
+	fork    string
+	fetched map[uint64]int
+	failAt  uint64
+}
+
+func newFakeChain(head uint64) *fakeChain {
+	return &fakeChain{head: head, fetched: make(map[uint64]int)}
+}
+
+// hash returns the hash of block n on the current fork
+func (c *fakeChain) hash(n uint64) string {
+	return fmt.Sprintf("0x%s%x", c.fork, n)
+}
+
+func (c *fakeChain) GetBlockNumber(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return blockchain.EncodeQuantity(c.head), nil
+}
+
+func (c *fakeChain) GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error) {
+	n, err := blockchain.ParseQuantity(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failAt != 0 && n == c.failAt {
+		return nil, fmt.Errorf("upstream unavailable")
+	}
+	c.fetched[n]++
+
+	parent := ""
+	if n > 0 {
+		parent = c.hash(n - 1)
+	}
+	txs, _ := json.Marshal([]map[string]string{{"hash": fmt.Sprintf("0xtx%x", n), "from": "0xa", "to": "0xb"}})
+	return &blockchain.Block{
+		Number:           blockNumber,
+		Hash:             c.hash(n),
+		ParentHash:       parent,
+		Transactions:     txs,
+		TransactionCount: 1,
+	}, nil
+}
+
+func (c *fakeChain) GetBlockReceipts(ctx context.Context, blockNumber string) ([]*blockchain.Receipt, error) {
+	n, _ := blockchain.ParseQuantity(blockNumber)
+	return []*blockchain.Receipt{{
+		TransactionHash: fmt.Sprintf("0xtx%x", n),
+		Status:          "0x1",
+		Logs:            []*blockchain.Log{{Address: "0xc", LogIndex: "0x0"}},
+	}}, nil
+}
+
+// openTestStore opens a store in a temporary directory
+func openTestStore(t *testing.T) *store.Store {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "blocks.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestBackfill(t *testing.T) {
+	t.Run("ingests the full range in parallel", func(t *testing.T) {
+		chain := newFakeChain(100)
+		st := openTestStore(t)
+		ix := New(chain, st, Config{StartHeight: 10, Workers: 8})
+
+		if err := ix.Backfill(context.Background(), 10, 60); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for n := uint64(10); n <= 60; n++ {
+			if ok, _ := st.HasBlock(n); !ok {
+				t.Errorf("expected block %d to be stored", n)
+			}
+		}
+		if logs, _ := st.GetLogs(30); len(logs) != 1 {
+			t.Errorf("expected receipts and logs to be ingested")
+		}
+
+		checkpoint, ok, _ := st.Checkpoint(BackfillCheckpoint)
+		if !ok || checkpoint != 60 {
+			t.Errorf("expected checkpoint 60, got %d", checkpoint)
+		}
+	})
+
+	t.Run("resumes from the checkpoint after a failure", func(t *testing.T) {
+		chain := newFakeChain(100)
+		chain.failAt = 25
+		st := openTestStore(t)
+		ix := New(chain, st, Config{StartHeight: 0, Workers: 4})
+
+		if err := ix.Backfill(context.Background(), 0, 50); err == nil {
+			t.Fatalf("expected error but got nil")
+		}
+
+		checkpoint, ok, _ := st.Checkpoint(BackfillCheckpoint)
+		if !ok || checkpoint != 24 {
+			t.Fatalf("expected checkpoint 24, got %d (%v)", checkpoint, ok)
+		}
+
+		chain.mu.Lock()
+		chain.failAt = 0
+		alreadyFetched := make(map[uint64]int)
+		for n, count := range chain.fetched {
+			alreadyFetched[n] = count
+		}
+		chain.mu.Unlock()
+
+		from, err := ix.resumeHeight()
+		if err != nil || from != 25 {
+			t.Fatalf("expected to resume at 25, got %d (%v)", from, err)
+		}
+		if err := ix.Backfill(context.Background(), from, 50); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for n, count := range alreadyFetched {
+			if ok, _ := st.HasBlock(n); ok && chain.fetched[n] != count {
+				t.Errorf("expected stored block %d not to be fetched again", n)
+			}
+		}
+		if checkpoint, _, _ := st.Checkpoint(BackfillCheckpoint); checkpoint != 50 {
+			t.Errorf("expected checkpoint 50, got %d", checkpoint)
+		}
+	})
+}
+
+func TestIngestTipReorg(t *testing.T) {
+	chain := newFakeChain(20)
+	st := openTestStore(t)
+	ix := New(chain, st, Config{StartHeight: 10, Workers: 2})
+
+	if err := ix.Backfill(context.Background(), 10, 20); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Switch to a fork that diverges at block 19
+	chain.mu.Lock()
+	chain.fork = "f"
+	chain.mu.Unlock()
+	st.PutBlock(&store.BlockData{Block: &blockchain.Block{Number: "0x12", Hash: chain.hash(18), ParentHash: "0x11", Transactions: json.RawMessage(`[]`)}})
+
+	next := uint64(21)
+	for i := 0; i < 10 && next <= 21; i++ {
+		n, err := ix.ingestTip(context.Background(), next)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		next = n
+	}
+
+	for n := uint64(19); n <= 21; n++ {
+		block, err := st.GetBlock(n, false)
+		if err != nil {
+			t.Fatalf("expected block %d to be stored: %v", n, err)
+		}
+		if block.Hash != chain.hash(n) {
+			t.Errorf("expected block %d to be on the new fork, got %s", n, block.Hash)
+		}
+	}
+}
+
+func TestIngestTipReorgDepth(t *testing.T) {
+	chain := newFakeChain(40)
+	st := openTestStore(t)
+	ix := New(chain, st, Config{StartHeight: 10, Workers: 2, ReorgDepth: 3})
+
+	if err := ix.Backfill(context.Background(), 10, 40); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Switch to a fork that shares no stored block
+	chain.mu.Lock()
+	chain.fork = "f"
+	chain.mu.Unlock()
+
+	next := uint64(41)
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		next, err = ix.ingestTip(context.Background(), next)
+	}
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+
+	// A retry must not walk back any further
+	if _, err := ix.ingestTip(context.Background(), next); err == nil {
+		t.Fatalf("expected error on retry but got nil")
+	}
+
+	for n := uint64(38); n <= 40; n++ {
+		if ok, _ := st.HasBlock(n); ok {
+			t.Errorf("expected block %d to be rolled back", n)
+		}
+	}
+	for n := uint64(10); n <= 37; n++ {
+		if ok, _ := st.HasBlock(n); !ok {
+			t.Errorf("expected block %d below the reorg depth to be kept", n)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	chain := newFakeChain(5)
+	st := openTestStore(t)
+	ix := New(chain, st, Config{StartHeight: 0, PollInterval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- ix.Run(ctx) }()
+
+	chain.mu.Lock()
+	chain.head = 8
+	chain.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if ok, _ := st.HasBlock(8); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for block 8 to be ingested")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
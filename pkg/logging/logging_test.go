@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Level: "warn", Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "dropped")
+	logger.With("component", "test").WarnContext(ctx, "kept", "block", 16)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 log line, got %d: %q", len(lines), buf.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("invalid JSON log line: %v", err)
+	}
+	if record["msg"] != "kept" || record["level"] != "WARN" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if record["request_id"] != "req-1" || record["component"] != "test" || record["block"] != float64(16) {
+		t.Errorf("expected request ID and attributes, got %v", record)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Config{Level: "verbose"}); err == nil {
+		t.Errorf("expected error for unsupported level")
+	}
+	if _, err := New(&bytes.Buffer{}, Config{Format: "xml"}); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+
+	level, err := ParseLevel("DEBUG")
+	if err != nil || level != slog.LevelDebug {
+		t.Errorf("expected debug level, got %v (%v)", level, err)
+	}
+}
+
+func TestLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	logger, err := New(&buf, Config{Level: "warn", LevelVar: level})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if level.Level() != slog.LevelWarn {
+		t.Fatalf("expected the level var to be set to warn, got %v", level.Level())
+	}
+
+	logger.Info("dropped")
+	level.Set(slog.LevelDebug)
+	logger.Debug("kept")
+
+	if out := buf.String(); strings.Contains(out, "dropped") || !strings.Contains(out, "kept") {
+		t.Errorf("expected the level change to apply, got %q", out)
+	}
+}
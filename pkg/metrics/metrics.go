@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+const namespace = "blockchain_client"
+
+// CacheStore is the cache label for block lookups served from the indexer store
+const CacheStore = "store"
+
+// CacheRPC is the cache label for JSON-RPC results cached by method
+const CacheRPC = "rpc"
+
+// Metrics holds the Prometheus collectors for the API server and upstream client
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+
+	rpcCalls        *prometheus.CounterVec
+	rpcCallDuration *prometheus.HistogramVec
+
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	upstreamErrors   *prometheus.CounterVec
+
+	cacheRequests *prometheus.CounterVec
+
+	mu            sync.Mutex
+	head          uint64
+	headTimestamp time.Time
+}
+
+// New creates a new set of metrics registered on a dedicated registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route, JSON-RPC method and status code.",
+		}, []string{"route", "rpc_method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by route and JSON-RPC method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "rpc_method"}),
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_calls_total",
+			Help:      "JSON-RPC calls served, including calls in batches, by method and result code.",
+		}, []string{"method", "code"}),
+		rpcCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_call_duration_seconds",
+			Help:      "JSON-RPC call latency, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Upstream JSON-RPC round-trips, by endpoint and method.",
+		}, []string{"endpoint", "method"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Upstream JSON-RPC round-trip latency, by endpoint and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "method"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Failed upstream JSON-RPC round-trips, by endpoint, method and error code.",
+		}, []string{"endpoint", "method", "code"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups, by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.rpcCalls,
+		m.rpcCallDuration,
+		m.upstreamRequests,
+		m.upstreamDuration,
+		m.upstreamErrors,
+		m.cacheRequests,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "chain_head_block",
+			Help:      "Latest observed chain head block number.",
+		}, func() float64 {
+			head, _ := m.Head()
+			return float64(head)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "chain_head_lag_seconds",
+			Help:      "Seconds between wall clock time and the chain head block timestamp.",
+		}, func() float64 {
+			_, ts := m.Head()
+			if ts.IsZero() {
+				return 0
+			}
+			return time.Since(ts).Seconds()
+		}),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns the /metrics handler in Prometheus text exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled HTTP request
+func (m *Metrics) ObserveRequest(route, rpcMethod string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(route, rpcMethod, statusLabel(status)).Inc()
+	m.requestDuration.WithLabelValues(route, rpcMethod).Observe(duration.Seconds())
+}
+
+// ObserveRPCCall records a served JSON-RPC call; code is "ok" or the
+// JSON-RPC error code
+func (m *Metrics) ObserveRPCCall(method, code string, duration time.Duration) {
+	m.rpcCalls.WithLabelValues(method, code).Inc()
+	m.rpcCallDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// statusLabel formats an HTTP status code as a label value
+func statusLabel(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status)
+}
+
+// ObserveCall implements blockchain.CallObserver
+func (m *Metrics) ObserveCall(call blockchain.CallInfo) {
+	endpoint := blockchain.EndpointHost(call.Endpoint)
+	m.upstreamRequests.WithLabelValues(endpoint, call.Method).Inc()
+	m.upstreamDuration.WithLabelValues(endpoint, call.Method).Observe(call.Duration.Seconds())
+	if call.ErrorCode != "" {
+		m.upstreamErrors.WithLabelValues(endpoint, call.Method, call.ErrorCode).Inc()
+	}
+}
+
+// ObserveCache records a cache lookup
+func (m *Metrics) ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// RegisterCoalesceStats exports the client's coalescing counters as cache
+// hits (calls that shared a round-trip) and misses (calls sent upstream)
+func (m *Metrics) RegisterCoalesceStats(stats func() blockchain.CoalesceStats) {
+	m.registry.MustRegister(&coalesceCollector{stats: stats})
+}
+
+// coalesceCollector reads coalescing counters at scrape time
+type coalesceCollector struct {
+	stats func() blockchain.CoalesceStats
+}
+
+var coalesceDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "coalesced_requests_total"),
+	"Upstream calls by outcome: coalesced into an in-flight call, or sent upstream.",
+	[]string{"outcome"}, nil,
+)
+
+func (c *coalesceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- coalesceDesc
+}
+
+func (c *coalesceCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(coalesceDesc, prometheus.CounterValue, float64(stats.Coalesced), "coalesced")
+	ch <- prometheus.MustNewConstMetric(coalesceDesc, prometheus.CounterValue, float64(stats.Upstream), "upstream")
+}
+
+// SetHead records the chain head and its block timestamp
+func (m *Metrics) SetHead(head uint64, timestamp time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if head >= m.head {
+		m.head = head
+		m.headTimestamp = timestamp
+	}
+}
+
+// Head returns the recorded chain head and its block timestamp
+func (m *Metrics) Head() (uint64, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.head, m.headTimestamp
+}
+
+// HeadBlockSource is the subset of the client used to look up head timestamps
+type HeadBlockSource interface {
+	GetBlockByNumber(ctx context.Context, blockNumber string, fullTransactions bool) (*blockchain.Block, error)
+}
+
+// TrackHead records every head reported by follower until ctx is cancelled,
+// fetching each head block to learn its timestamp
+func (m *Metrics) TrackHead(ctx context.Context, follower *blockchain.HeadFollower, source HeadBlockSource) {
+	heads, unsubscribe := follower.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case head := <-heads:
+			block, err := source.GetBlockByNumber(ctx, blockchain.EncodeQuantity(head), false)
+			if err != nil {
+				slog.Warn("metrics: failed to get head block", "block", head, "error", err)
+				continue
+			}
+			ts, err := blockchain.ParseQuantity(block.Timestamp)
+			if err != nil {
+				slog.Warn("metrics: invalid head block timestamp", "block", head, "error", err)
+				continue
+			}
+			m.SetHead(head, time.Unix(int64(ts), 0))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// scrape returns the text exposition served by the metrics handler
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsExposition(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("/", "eth_blockNumber", 200, 5*time.Millisecond)
+	m.ObserveRequest("/api/blocks", "", 0, 5*time.Millisecond)
+	m.ObserveRPCCall("eth_blockNumber", "ok", 2*time.Millisecond)
+	m.ObserveRPCCall("eth_getBlockByNumber", "-32602", time.Millisecond)
+	m.ObserveCall(blockchain.CallInfo{
+		Endpoint: "https://rpc.example.com/v2/secret-key",
+		Method:   "eth_getBlockByNumber",
+		Duration: 20 * time.Millisecond,
+	})
+	m.ObserveCall(blockchain.CallInfo{
+		Endpoint:  "https://rpc.example.com/v2/secret-key",
+		Method:    "eth_blockNumber",
+		ErrorCode: "-32000",
+	})
+	m.ObserveCache(CacheStore, true)
+	m.ObserveCache(CacheStore, false)
+	m.ObserveCache(CacheStore, true)
+	m.RegisterCoalesceStats(func() blockchain.CoalesceStats {
+		return blockchain.CoalesceStats{Requests: 10, Upstream: 7, Coalesced: 3}
+	})
+	m.SetHead(100, time.Now().Add(-30*time.Second))
+
+	body := scrape(t, m)
+
+	expected := []string{
+		`blockchain_client_http_requests_total{route="/",rpc_method="eth_blockNumber",status="200"} 1`,
+		`blockchain_client_http_requests_total{route="/api/blocks",rpc_method="",status="200"} 1`,
+		`blockchain_client_http_request_duration_seconds_count{route="/",rpc_method="eth_blockNumber"} 1`,
+		`blockchain_client_rpc_calls_total{code="ok",method="eth_blockNumber"} 1`,
+		`blockchain_client_rpc_calls_total{code="-32602",method="eth_getBlockByNumber"} 1`,
+		`blockchain_client_rpc_call_duration_seconds_count{method="eth_blockNumber"} 1`,
+		`blockchain_client_upstream_requests_total{endpoint="rpc.example.com",method="eth_getBlockByNumber"} 1`,
+		`blockchain_client_upstream_errors_total{code="-32000",endpoint="rpc.example.com",method="eth_blockNumber"} 1`,
+		`blockchain_client_cache_requests_total{cache="store",result="hit"} 2`,
+		`blockchain_client_cache_requests_total{cache="store",result="miss"} 1`,
+		`blockchain_client_coalesced_requests_total{outcome="coalesced"} 3`,
+		`blockchain_client_chain_head_block 100`,
+		`go_goroutines`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+
+	if strings.Contains(body, "secret-key") {
+		t.Errorf("expected endpoint labels to omit the URL path")
+	}
+}
+
+func TestSetHeadIgnoresOlderBlocks(t *testing.T) {
+	m := New()
+	now := time.Now()
+
+	m.SetHead(10, now)
+	m.SetHead(9, now.Add(-time.Minute))
+
+	head, ts := m.Head()
+	if head != 10 || !ts.Equal(now) {
+		t.Errorf("expected head 10 at %v, got %d at %v", now, head, ts)
+	}
+}
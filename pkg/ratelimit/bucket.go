@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled continuously at a fixed rate
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewBucket creates a full bucket holding up to burst tokens and refilled at
+// rate tokens per second
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Take removes n tokens if they are available. Otherwise it removes nothing
+// and reports how long until n tokens will be available. Requests for more
+// than the burst size are charged the whole burst.
+func (b *Bucket) Take(n float64) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	if n > b.burst {
+		n = b.burst
+	}
+	if b.tokens >= n {
+		b.tokens -= n
+		return true, 0
+	}
+
+	if b.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	wait := (n - b.tokens) / b.rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// Idle reports whether the bucket has been full for at least d, meaning it
+// can be discarded without changing its behaviour
+func (b *Bucket) Idle(d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return false
+	}
+	refill := time.Duration((b.burst - b.tokens) / b.rate * float64(time.Second))
+	return b.now().Sub(b.last) >= refill+d
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := NewBucket(2, 4)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		if ok, _ := b.Take(1); !ok {
+			t.Fatalf("take %d: expected burst to be available", i)
+		}
+	}
+
+	ok, wait := b.Take(1)
+	if ok {
+		t.Fatal("expected empty bucket to refuse")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms, got %s", wait)
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := b.Take(2); !ok {
+		t.Error("expected 2 tokens after refilling for 1s")
+	}
+	if ok, _ := b.Take(1); ok {
+		t.Error("expected bucket to be empty again")
+	}
+
+	// Costs above the burst are charged the whole burst
+	now = now.Add(time.Hour)
+	if ok, _ := b.Take(10); !ok {
+		t.Error("expected oversized take to succeed on a full bucket")
+	}
+	if b.Idle(0) {
+		t.Error("expected drained bucket not to be idle")
+	}
+	now = now.Add(2 * time.Second)
+	if !b.Idle(0) {
+		t.Error("expected refilled bucket to be idle")
+	}
+}
+
+func TestComputeUnits(t *testing.T) {
+	if got := ComputeUnits("eth_blockNumber"); got != 10 {
+		t.Errorf("expected eth_blockNumber to cost 10, got %d", got)
+	}
+	if got := ComputeUnits("eth_unknown"); got != DefaultComputeUnits {
+		t.Errorf("expected unknown methods to cost %d, got %d", DefaultComputeUnits, got)
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often idle buckets are discarded
+const pruneInterval = time.Minute
+
+// Config represents the limits applied to each client
+type Config struct {
+	// Rate is the sustained compute units per second for each client
+	Rate float64
+	// Burst is the compute units a client may spend at once, defaulting to
+	// one second's worth
+	Burst int
+}
+
+// Limiter enforces a compute unit token bucket per client
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*Bucket
+	lastPrune time.Time
+}
+
+// NewLimiter creates a limiter applying cfg to every client
+func NewLimiter(cfg Config) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+// SetConfig changes the limits applied to every client. Clients start again
+// with a full bucket under the new limits.
+func (l *Limiter) SetConfig(cfg Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg = cfg
+	l.buckets = make(map[string]*Bucket)
+}
+
+// Allow charges cost compute units to client. When the client has too few
+// units left it charges nothing and reports how long to wait before retrying.
+func (l *Limiter) Allow(client string, cost int) (bool, time.Duration) {
+	if cost <= 0 {
+		return true, 0
+	}
+	return l.bucket(client).Take(float64(cost))
+}
+
+// bucket returns the bucket for client, creating it if needed
+func (l *Limiter) bucket(client string) *Bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= pruneInterval {
+		l.lastPrune = now
+		for key, b := range l.buckets {
+			if b.Idle(0) {
+				delete(l.buckets, key)
+			}
+		}
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = NewBucket(l.cfg.Rate, l.cfg.Burst)
+		b.now = l.now
+		l.buckets[client] = b
+	}
+	return b
+}
+
+// ParseCosts parses a comma-separated list of operation=units pairs, e.g.
+// "eth_getLogs=75,eth_call=26"
+func ParseCosts(s string) (map[string]int, error) {
+	costs := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		op, value, ok := strings.Cut(pair, "=")
+		if !ok || op == "" {
+			return nil, fmt.Errorf("invalid compute unit cost %q, expected operation=units", pair)
+		}
+		units, err := strconv.Atoi(value)
+		if err != nil || units < 0 {
+			return nil, fmt.Errorf("invalid compute units for %s: %q", op, value)
+		}
+		costs[op] = units
+	}
+	return costs, nil
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(Config{Rate: 10, Burst: 30})
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow("a", 25); !ok {
+		t.Fatal("expected first call to fit the burst")
+	}
+	ok, wait := l.Allow("a", 10)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("expected refusal with 500ms wait, got %v %s", ok, wait)
+	}
+	if ok, _ := l.Allow("b", 25); !ok {
+		t.Error("expected clients to have separate budgets")
+	}
+	if ok, _ := l.Allow("a", 0); !ok {
+		t.Error("expected free calls to always pass")
+	}
+
+	// Buckets that have refilled are discarded
+	now = now.Add(time.Hour)
+	l.Allow("c", 1)
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be pruned, have %d", len(l.buckets))
+	}
+}
+
+func TestParseCosts(t *testing.T) {
+	costs, err := ParseCosts("eth_getLogs=75, /api/exports=200,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if costs["eth_getLogs"] != 75 || costs["/api/exports"] != 200 || len(costs) != 2 {
+		t.Errorf("unexpected costs %v", costs)
+	}
+
+	for _, invalid := range []string{"eth_getLogs", "=5", "eth_call=-1", "eth_call=x"} {
+		if _, err := ParseCosts(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
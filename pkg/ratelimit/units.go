@@ -0,0 +1,32 @@
+package ratelimit
+
+// DefaultComputeUnits is the cost of methods missing from the cost table
+const DefaultComputeUnits = 20
+
+// computeUnits is the cost of each operation, weighted by the upstream work
+// it causes. REST operations are keyed by route.
+var computeUnits = map[string]int{
+	"eth_chainId":               0,
+	"eth_blockNumber":           10,
+	"eth_getBalance":            19,
+	"eth_getBlockByNumber":      16,
+	"eth_getTransactionByHash":  15,
+	"eth_getTransactionReceipt": 15,
+	"eth_call":                  26,
+	"eth_getLogs":               75,
+	"eth_getBlockReceipts":      500,
+
+	"/api/blocks/latest": 10,
+	"/api/blocks":        16,
+	"/api/tx":            15,
+	"/api/accounts/":     25,
+	"/api/exports":       100,
+}
+
+// ComputeUnits returns the cost of a JSON-RPC method or REST route
+func ComputeUnits(operation string) int {
+	if cost, ok := computeUnits[operation]; ok {
+		return cost
+	}
+	return DefaultComputeUnits
+}
@@ -0,0 +1,106 @@
+package rlp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is the kind of an RLP item
+type Kind int
+
+// RLP item kinds
+const (
+	String Kind = iota
+	List
+)
+
+// ErrUnexpectedEnd is returned when the input ends inside an item
+var ErrUnexpectedEnd = errors.New("rlp: unexpected end of input")
+
+// Split reads the first item of b, returning its kind, its payload and the
+// input that follows it
+func Split(b []byte) (kind Kind, content, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, nil, ErrUnexpectedEnd
+	}
+
+	prefix := b[0]
+	var offset, size int
+	switch {
+	case prefix < 0x80:
+		return String, b[:1], b[1:], nil
+	case prefix < 0xb8:
+		kind, offset, size = String, 1, int(prefix-0x80)
+	case prefix < 0xc0:
+		kind = String
+		offset, size, err = longSize(b, int(prefix-0xb7))
+	case prefix < 0xf8:
+		kind, offset, size = List, 1, int(prefix-0xc0)
+	default:
+		kind = List
+		offset, size, err = longSize(b, int(prefix-0xf7))
+	}
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(b)-offset < size {
+		return 0, nil, nil, ErrUnexpectedEnd
+	}
+	return kind, b[offset : offset+size], b[offset+size:], nil
+}
+
+// longSize reads the payload size of a long string or list, which follows
+// the prefix in sizeLen bytes
+func longSize(b []byte, sizeLen int) (offset, size int, err error) {
+	if len(b) < 1+sizeLen {
+		return 0, 0, ErrUnexpectedEnd
+	}
+	if sizeLen > 4 {
+		return 0, 0, fmt.Errorf("rlp: item size of %d bytes is too large", sizeLen)
+	}
+	for _, c := range b[1 : 1+sizeLen] {
+		size = size<<8 | int(c)
+	}
+	return 1 + sizeLen, size, nil
+}
+
+// DecodeBytes decodes b, which must hold exactly one RLP string
+func DecodeBytes(b []byte) ([]byte, error) {
+	kind, content, rest, err := Split(b)
+	if err != nil {
+		return nil, err
+	}
+	if kind != String {
+		return nil, errors.New("rlp: expected a string, got a list")
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("rlp: unexpected data after the string")
+	}
+	return content, nil
+}
+
+// DecodeList decodes b, which must hold exactly one RLP list, returning the
+// encoding of each of its items
+func DecodeList(b []byte) ([][]byte, error) {
+	kind, content, rest, err := Split(b)
+	if err != nil {
+		return nil, err
+	}
+	if kind != List {
+		return nil, errors.New("rlp: expected a list, got a string")
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("rlp: unexpected data after the list")
+	}
+
+	var items [][]byte
+	for len(content) > 0 {
+		_, _, next, err := Split(content)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, content[:len(content)-len(next)])
+		content = next
+	}
+	return items, nil
+}
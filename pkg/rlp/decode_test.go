@@ -0,0 +1,44 @@
+package rlp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	long := []byte(strings.Repeat("x", 100))
+	items, err := DecodeList(EncodeList(EncodeBytes([]byte("cat")), EncodeList(), EncodeBytes(long), EncodeUint(0)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(items))
+	}
+
+	if cat, err := DecodeBytes(items[0]); err != nil || string(cat) != "cat" {
+		t.Errorf("expected cat, got %q (%v)", cat, err)
+	}
+	if nested, err := DecodeList(items[1]); err != nil || len(nested) != 0 {
+		t.Errorf("expected an empty list, got %v (%v)", nested, err)
+	}
+	if got, err := DecodeBytes(items[2]); err != nil || !bytes.Equal(got, long) {
+		t.Errorf("expected the long string back, got %d bytes (%v)", len(got), err)
+	}
+	if zero, err := DecodeBytes(items[3]); err != nil || len(zero) != 0 {
+		t.Errorf("expected an empty string, got %x (%v)", zero, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := DecodeBytes([]byte{0x83, 'd', 'o'}); !errors.Is(err, ErrUnexpectedEnd) {
+		t.Errorf("expected a truncated string to fail, got %v", err)
+	}
+	if _, err := DecodeBytes(EncodeList()); err == nil {
+		t.Error("expected a list to be rejected as a string")
+	}
+	if _, err := DecodeList(append(EncodeList(), 0x00)); err == nil {
+		t.Error("expected trailing data to be rejected")
+	}
+}
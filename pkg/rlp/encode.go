@@ -0,0 +1,58 @@
+// Package rlp implements the Recursive Length Prefix encoding Ethereum uses
+// to serialize the headers, transactions and receipts it hashes
+package rlp
+
+import (
+	"math/big"
+)
+
+// EncodeBytes encodes b as an RLP string
+func EncodeBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(header(0x80, len(b)), b...)
+}
+
+// EncodeUint encodes n as an RLP string holding its big-endian bytes without
+// leading zeros, so 0 encodes as the empty string
+func EncodeUint(n uint64) []byte {
+	return EncodeBytes(trimUint(n))
+}
+
+// EncodeBig encodes a non-negative integer like EncodeUint
+func EncodeBig(n *big.Int) []byte {
+	return EncodeBytes(n.Bytes())
+}
+
+// EncodeList encodes items, each already RLP encoded, as an RLP list
+func EncodeList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+	out := header(0xc0, size)
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// header returns the prefix of a string (offset 0x80) or list (offset 0xc0)
+// with a payload of size bytes
+func header(offset byte, size int) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	sizeBytes := trimUint(uint64(size))
+	return append([]byte{offset + 55 + byte(len(sizeBytes))}, sizeBytes...)
+}
+
+// trimUint returns the big-endian bytes of n without leading zeros
+func trimUint(n uint64) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return b
+}
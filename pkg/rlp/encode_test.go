@@ -0,0 +1,45 @@
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		// Examples from the Ethereum RLP specification
+		{"dog", EncodeBytes([]byte("dog")), "83646f67"},
+		{"cat dog", EncodeList(EncodeBytes([]byte("cat")), EncodeBytes([]byte("dog"))), "c88363617483646f67"},
+		{"empty string", EncodeBytes(nil), "80"},
+		{"empty list", EncodeList(), "c0"},
+		{"zero", EncodeUint(0), "80"},
+		{"single byte", EncodeBytes([]byte{0x00}), "00"},
+		{"fifteen", EncodeUint(15), "0f"},
+		{"1024", EncodeUint(1024), "820400"},
+		{"set of three", EncodeList(EncodeList(), EncodeList(EncodeList()), EncodeList(EncodeList(), EncodeList(EncodeList()))), "c7c0c1c0c3c0c1c0"},
+		{"big", EncodeBig(new(big.Int).Lsh(big.NewInt(1), 64)), "89010000000000000000"},
+		{
+			"long string",
+			EncodeBytes([]byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit")),
+			"b8384c6f72656d20697073756d20646f6c6f722073697420616d65742c20636f6e7365637465747572206164697069736963696e6720656c6974",
+		},
+	}
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.want)
+		if !bytes.Equal(tt.got, want) {
+			t.Errorf("%s: expected %s, got %x", tt.name, tt.want, tt.got)
+		}
+	}
+
+	long := EncodeList(EncodeBytes([]byte(strings.Repeat("a", 60))))
+	if long[0] != 0xf8 || long[1] != 62 {
+		t.Errorf("expected a long list prefix, got %x", long[:2])
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// Address roles recorded in the activity index
+const (
+	// RoleFrom marks the sender of a transaction
+	RoleFrom = "from"
+	// RoleTo marks the recipient of a transaction, or the created contract
+	RoleTo = "to"
+	// RoleLog marks the contract that emitted a log
+	RoleLog = "log"
+	// RoleTopic marks an address found in an indexed log topic
+	RoleTopic = "topic"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 1000
+
+	addressLen     = 20
+	activityKeyLen = addressLen + 8 + 4 + 4 + 1
+
+	// noLogIndex marks transaction-level entries in the activity key
+	noLogIndex = math.MaxUint32
+)
+
+var (
+	// ErrInvalidAddress is returned for malformed addresses
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrInvalidCursor is returned for malformed pagination cursors
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidRole is returned for unknown activity roles
+	ErrInvalidRole = errors.New("invalid role")
+
+	// addressesBucket maps address, block, transaction, log and role to
+	// transaction hash
+	addressesBucket = []byte("addresses")
+
+	roleCodes = map[string]byte{RoleFrom: 1, RoleTo: 2, RoleLog: 3, RoleTopic: 4}
+	roleNames = map[byte]string{1: RoleFrom, 2: RoleTo, 3: RoleLog, 4: RoleTopic}
+)
+
+// Activity represents one appearance of an address in a transaction or log
+type Activity struct {
+	BlockNumber      string `json:"blockNumber"`
+	TransactionHash  string `json:"transactionHash"`
+	TransactionIndex string `json:"transactionIndex"`
+	LogIndex         string `json:"logIndex,omitempty"`
+	Role             string `json:"role"`
+}
+
+// ActivityQuery represents a page request against the activity index
+type ActivityQuery struct {
+	Address string
+	// Roles restricts results to the given roles; empty means all roles
+	Roles []string
+	// FromBlock and ToBlock bound the block range, inclusive; a zero
+	// ToBlock means no upper bound
+	FromBlock uint64
+	ToBlock   uint64
+	// Cursor continues from a previous page's NextCursor
+	Cursor string
+	Limit  int
+}
+
+// ActivityPage represents a page of address activity, newest first
+type ActivityPage struct {
+	Items      []*Activity `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// parseAddress decodes a 0x-prefixed 20-byte hex address
+func parseAddress(address string) ([]byte, error) {
+	if !strings.HasPrefix(address, "0x") && !strings.HasPrefix(address, "0X") {
+		return nil, ErrInvalidAddress
+	}
+	b, err := hex.DecodeString(address[2:])
+	if err != nil || len(b) != addressLen {
+		return nil, ErrInvalidAddress
+	}
+	return b, nil
+}
+
+// topicAddress extracts an address from an indexed topic, which holds one
+// when it is a 32-byte word whose upper 12 bytes are zero
+func topicAddress(topic string) ([]byte, bool) {
+	if len(topic) != 66 {
+		return nil, false
+	}
+	b, err := hex.DecodeString(topic[2:])
+	if err != nil {
+		return nil, false
+	}
+	if !bytes.Equal(b[:12], make([]byte, 12)) || bytes.Equal(b[12:], make([]byte, addressLen)) {
+		return nil, false
+	}
+	return b[12:], true
+}
+
+// activityKey builds an activity index key
+func activityKey(address []byte, number, txIndex, logIndex uint64, role byte) []byte {
+	key := make([]byte, activityKeyLen)
+	copy(key, address)
+	binary.BigEndian.PutUint64(key[20:], number)
+	binary.BigEndian.PutUint32(key[28:], uint32(txIndex))
+	binary.BigEndian.PutUint32(key[32:], uint32(logIndex))
+	key[36] = role
+	return key
+}
+
+// activityEntry is a key/value pair in the activity index
+type activityEntry struct {
+	key    []byte
+	txHash string
+}
+
+// activityEntries derives the activity index entries for a block
+func activityEntries(number uint64, txs []*blockchain.Transaction, receipts []*blockchain.Receipt) []activityEntry {
+	var entries []activityEntry
+	add := func(address string, txIndex, logIndex uint64, role string, txHash string) {
+		addr, err := parseAddress(address)
+		if err != nil {
+			return
+		}
+		entries = append(entries, activityEntry{
+			key:    activityKey(addr, number, txIndex, logIndex, roleCodes[role]),
+			txHash: txHash,
+		})
+	}
+
+	txIndexes := make(map[string]uint64, len(txs))
+	for i, tx := range txs {
+		txIndex := uint64(i)
+		if n, err := blockchain.ParseQuantity(tx.TransactionIndex); err == nil {
+			txIndex = n
+		}
+		txIndexes[strings.ToLower(tx.Hash)] = txIndex
+
+		add(tx.From, txIndex, noLogIndex, RoleFrom, tx.Hash)
+		add(tx.To, txIndex, noLogIndex, RoleTo, tx.Hash)
+	}
+
+	for _, receipt := range receipts {
+		txIndex := txIndexes[strings.ToLower(receipt.TransactionHash)]
+		if receipt.ContractAddress != "" {
+			add(receipt.ContractAddress, txIndex, noLogIndex, RoleTo, receipt.TransactionHash)
+		}
+
+		for _, l := range receipt.Logs {
+			logIndex, err := blockchain.ParseQuantity(l.LogIndex)
+			if err != nil {
+				continue
+			}
+			add(l.Address, txIndex, logIndex, RoleLog, receipt.TransactionHash)
+			for i, topic := range l.Topics {
+				// The first topic is the event signature
+				if i == 0 {
+					continue
+				}
+				if addr, ok := topicAddress(topic); ok {
+					add("0x"+hex.EncodeToString(addr), txIndex, logIndex, RoleTopic, receipt.TransactionHash)
+				}
+			}
+		}
+	}
+
+	return entries
+}
+
+// putActivity writes activity index entries within tx
+func putActivity(tx *bolt.Tx, entries []activityEntry) error {
+	b := tx.Bucket(addressesBucket)
+	for _, e := range entries {
+		if err := b.Put(e.key, []byte(e.txHash)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteActivity removes activity index entries within tx
+func deleteActivity(tx *bolt.Tx, entries []activityEntry) error {
+	b := tx.Bucket(addressesBucket)
+	for _, e := range entries {
+		if err := b.Delete(e.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeActivity converts an activity index entry into an Activity
+func decodeActivity(key, value []byte) *Activity {
+	activity := &Activity{
+		BlockNumber:      blockchain.EncodeQuantity(binary.BigEndian.Uint64(key[20:])),
+		TransactionHash:  string(value),
+		TransactionIndex: blockchain.EncodeQuantity(uint64(binary.BigEndian.Uint32(key[28:]))),
+		Role:             roleNames[key[36]],
+	}
+	if logIndex := binary.BigEndian.Uint32(key[32:]); logIndex != noLogIndex {
+		activity.LogIndex = blockchain.EncodeQuantity(uint64(logIndex))
+	}
+	return activity
+}
+
+// GetAddressActivity returns a page of transactions and logs involving an
+// address, newest first
+func (s *Store) GetAddressActivity(q ActivityQuery) (*ActivityPage, error) {
+	addr, err := parseAddress(q.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[byte]bool)
+	for _, role := range q.Roles {
+		code, ok := roleCodes[role]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+		}
+		roles[code] = true
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+
+	toBlock := q.ToBlock
+	if toBlock == 0 {
+		toBlock = math.MaxUint64
+	}
+	lower := activityKey(addr, q.FromBlock, 0, 0, 0)
+	upper := activityKey(addr, toBlock, math.MaxUint32, math.MaxUint32, math.MaxUint8)
+
+	// Cursors point at the last returned key; the next page starts below it
+	exclusive := false
+	if q.Cursor != "" {
+		cursor, err := hex.DecodeString(q.Cursor)
+		if err != nil || len(cursor) != activityKeyLen || !bytes.Equal(cursor[:addressLen], addr) {
+			return nil, ErrInvalidCursor
+		}
+		if bytes.Compare(cursor, upper) < 0 {
+			upper = cursor
+			exclusive = true
+		}
+	}
+
+	page := &ActivityPage{Items: []*Activity{}}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(addressesBucket).Cursor()
+
+		k, v := c.Seek(upper)
+		switch {
+		case k == nil:
+			k, v = c.Last()
+		case bytes.Compare(k, upper) > 0 || (exclusive && bytes.Equal(k, upper)):
+			k, v = c.Prev()
+		}
+
+		var last []byte
+		for ; k != nil && bytes.Compare(k, lower) >= 0; k, v = c.Prev() {
+			if len(roles) > 0 && !roles[k[36]] {
+				continue
+			}
+			if len(page.Items) == limit {
+				page.NextCursor = hex.EncodeToString(last)
+				return nil
+			}
+			page.Items = append(page.Items, decodeActivity(k, v))
+			last = append(last[:0], k...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
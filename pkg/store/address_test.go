@@ -0,0 +1,155 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+const (
+	alice = "0x1111111111111111111111111111111111111111"
+	bob   = "0x2222222222222222222222222222222222222222"
+	token = "0x3333333333333333333333333333333333333333"
+)
+
+// transferBlock builds a block in which alice sends bob a token transfer
+func transferBlock(number uint64) *BlockData {
+	txHash := fmt.Sprintf("0x%064x", number)
+	txs, _ := json.Marshal([]map[string]string{
+		{"hash": txHash, "from": alice, "to": token, "transactionIndex": "0x0"},
+	})
+
+	return &BlockData{
+		Block: &blockchain.Block{
+			Number:       blockchain.EncodeQuantity(number),
+			Hash:         fmt.Sprintf("0xb%063x", number),
+			Transactions: txs,
+		},
+		Receipts: []*blockchain.Receipt{{
+			TransactionHash: txHash,
+			Logs: []*blockchain.Log{{
+				Address: token,
+				Topics: []string{
+					"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+					"0x000000000000000000000000" + alice[2:],
+					"0x000000000000000000000000" + bob[2:],
+				},
+				LogIndex: "0x0",
+			}},
+		}},
+	}
+}
+
+func TestGetAddressActivity(t *testing.T) {
+	st := openTestStore(t)
+	for n := uint64(1); n <= 10; n++ {
+		if err := st.PutBlock(transferBlock(n)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	t.Run("all roles newest first", func(t *testing.T) {
+		page, err := st.GetAddressActivity(ActivityQuery{Address: alice, Limit: 4})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 4 {
+			t.Fatalf("expected 4 items, got %d", len(page.Items))
+		}
+		if page.Items[0].BlockNumber != "0xa" || page.Items[0].Role != RoleFrom {
+			t.Errorf("expected newest entry to be the sender in block 0xa, got %+v", page.Items[0])
+		}
+		if page.Items[1].Role != RoleTopic || page.Items[1].LogIndex != "0x0" {
+			t.Errorf("expected second entry to be a topic match, got %+v", page.Items[1])
+		}
+		if page.NextCursor == "" {
+			t.Errorf("expected a next cursor")
+		}
+	})
+
+	t.Run("cursor pagination covers every entry once", func(t *testing.T) {
+		seen := make(map[string]bool)
+		cursor := ""
+		pages := 0
+		for {
+			page, err := st.GetAddressActivity(ActivityQuery{Address: alice, Limit: 3, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pages++
+			for _, item := range page.Items {
+				key := item.BlockNumber + item.Role
+				if seen[key] {
+					t.Errorf("duplicate entry %s", key)
+				}
+				seen[key] = true
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if len(seen) != 20 {
+			t.Errorf("expected 20 entries, got %d", len(seen))
+		}
+		if pages != 7 {
+			t.Errorf("expected 7 pages, got %d", pages)
+		}
+	})
+
+	t.Run("direction and block range filters", func(t *testing.T) {
+		page, err := st.GetAddressActivity(ActivityQuery{Address: bob, Roles: []string{RoleTopic}, FromBlock: 3, ToBlock: 5})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 3 {
+			t.Fatalf("expected 3 items, got %d", len(page.Items))
+		}
+		if page.Items[0].BlockNumber != "0x5" || page.Items[2].BlockNumber != "0x3" {
+			t.Errorf("expected blocks 0x5 to 0x3, got %s to %s", page.Items[0].BlockNumber, page.Items[2].BlockNumber)
+		}
+
+		page, err = st.GetAddressActivity(ActivityQuery{Address: bob, Roles: []string{RoleFrom}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 0 {
+			t.Errorf("expected no entries for bob as sender, got %d", len(page.Items))
+		}
+
+		page, err = st.GetAddressActivity(ActivityQuery{Address: token, Roles: []string{RoleTo, RoleLog}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 20 {
+			t.Errorf("expected 20 entries for token, got %d", len(page.Items))
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		if _, err := st.GetAddressActivity(ActivityQuery{Address: "0x123"}); err != ErrInvalidAddress {
+			t.Errorf("expected ErrInvalidAddress, got %v", err)
+		}
+		if _, err := st.GetAddressActivity(ActivityQuery{Address: alice, Cursor: "zz"}); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+		if _, err := st.GetAddressActivity(ActivityQuery{Address: alice, Roles: []string{"sideways"}}); err == nil {
+			t.Errorf("expected error for invalid role")
+		}
+	})
+
+	t.Run("deleted blocks are removed from the index", func(t *testing.T) {
+		if err := st.DeleteBlock(10); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		page, err := st.GetAddressActivity(ActivityQuery{Address: alice, Limit: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if page.Items[0].BlockNumber != "0x9" {
+			t.Errorf("expected newest entry in block 0x9, got %s", page.Items[0].BlockNumber)
+		}
+	})
+}
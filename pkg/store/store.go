@@ -0,0 +1,367 @@
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// ErrNotFound is returned when the requested data has not been ingested
+var ErrNotFound = errors.New("not found in store")
+
+var (
+	// blocksBucket maps block number to block JSON with full transactions
+	blocksBucket = []byte("blocks")
+	// blockHashesBucket maps block hash to block number
+	blockHashesBucket = []byte("blockHashes")
+	// transactionsBucket maps transaction hash to transaction JSON
+	transactionsBucket = []byte("transactions")
+	// receiptsBucket maps transaction hash to receipt JSON
+	receiptsBucket = []byte("receipts")
+	// logsBucket maps block number and log index to log JSON
+	logsBucket = []byte("logs")
+	// metaBucket holds checkpoints
+	metaBucket = []byte("meta")
+)
+
+// Store is an embedded on-disk store of blocks, transactions, receipts and logs
+type Store struct {
+	db *bolt.DB
+}
+
+// BlockData represents everything ingested for a single block
+type BlockData struct {
+	// Block must have been fetched with full transaction objects
+	Block    *blockchain.Block
+	Receipts []*blockchain.Receipt
+}
+
+// Open opens or creates a store at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{blocksBucket, blockHashesBucket, transactionsBucket, receiptsBucket, logsBucket, addressesBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// uint64Key encodes n so that keys sort numerically
+func uint64Key(n uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	return key
+}
+
+// logKey encodes a block number and log index
+func logKey(number, logIndex uint64) []byte {
+	key := make([]byte, 12)
+	binary.BigEndian.PutUint64(key, number)
+	binary.BigEndian.PutUint32(key[8:], uint32(logIndex))
+	return key
+}
+
+// hashKey normalizes a hex hash for use as a key
+func hashKey(hash string) []byte {
+	return []byte(strings.ToLower(hash))
+}
+
+// PutBlock atomically stores a block with its transactions, receipts and logs,
+// replacing anything previously stored at the same height
+func (s *Store) PutBlock(data *BlockData) error {
+	number, err := blockchain.ParseQuantity(data.Block.Number)
+	if err != nil {
+		return fmt.Errorf("invalid block number: %w", err)
+	}
+
+	txs, err := data.Block.FullTransactions()
+	if err != nil {
+		return err
+	}
+	var rawTxs []json.RawMessage
+	if err := json.Unmarshal(data.Block.Transactions, &rawTxs); err != nil && len(data.Block.Transactions) > 0 {
+		return fmt.Errorf("failed to unmarshal transactions: %w", err)
+	}
+
+	blockBytes, err := json.Marshal(data.Block)
+	if err != nil {
+		return fmt.Errorf("failed to marshal block: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := deleteBlock(tx, number); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(blocksBucket).Put(uint64Key(number), blockBytes); err != nil {
+			return err
+		}
+		if err := tx.Bucket(blockHashesBucket).Put(hashKey(data.Block.Hash), uint64Key(number)); err != nil {
+			return err
+		}
+
+		for i, t := range txs {
+			if err := tx.Bucket(transactionsBucket).Put(hashKey(t.Hash), rawTxs[i]); err != nil {
+				return err
+			}
+		}
+
+		if err := putActivity(tx, activityEntries(number, txs, data.Receipts)); err != nil {
+			return err
+		}
+
+		for _, receipt := range data.Receipts {
+			receiptBytes, err := json.Marshal(receipt)
+			if err != nil {
+				return fmt.Errorf("failed to marshal receipt: %w", err)
+			}
+			if err := tx.Bucket(receiptsBucket).Put(hashKey(receipt.TransactionHash), receiptBytes); err != nil {
+				return err
+			}
+
+			for _, l := range receipt.Logs {
+				logIndex, err := blockchain.ParseQuantity(l.LogIndex)
+				if err != nil {
+					return fmt.Errorf("invalid log index: %w", err)
+				}
+				logBytes, err := json.Marshal(l)
+				if err != nil {
+					return fmt.Errorf("failed to marshal log: %w", err)
+				}
+				if err := tx.Bucket(logsBucket).Put(logKey(number, logIndex), logBytes); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteBlock removes a block and everything ingested with it, used when a
+// reorg replaces the block at that height
+func (s *Store) DeleteBlock(number uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteBlock(tx, number)
+	})
+}
+
+// deleteBlock removes a block and its dependent records within tx
+func deleteBlock(tx *bolt.Tx, number uint64) error {
+	blockBytes := tx.Bucket(blocksBucket).Get(uint64Key(number))
+	if blockBytes == nil {
+		return nil
+	}
+
+	var block blockchain.Block
+	if err := json.Unmarshal(blockBytes, &block); err != nil {
+		return fmt.Errorf("failed to unmarshal stored block: %w", err)
+	}
+	txs, err := block.FullTransactions()
+	if err != nil {
+		return err
+	}
+
+	var receipts []*blockchain.Receipt
+	for _, t := range txs {
+		receiptBytes := tx.Bucket(receiptsBucket).Get(hashKey(t.Hash))
+		if receiptBytes == nil {
+			continue
+		}
+		var receipt blockchain.Receipt
+		if err := json.Unmarshal(receiptBytes, &receipt); err != nil {
+			return fmt.Errorf("failed to unmarshal stored receipt: %w", err)
+		}
+		receipts = append(receipts, &receipt)
+	}
+	if err := deleteActivity(tx, activityEntries(number, txs, receipts)); err != nil {
+		return err
+	}
+
+	for _, t := range txs {
+		if err := tx.Bucket(transactionsBucket).Delete(hashKey(t.Hash)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(receiptsBucket).Delete(hashKey(t.Hash)); err != nil {
+			return err
+		}
+	}
+
+	logs := tx.Bucket(logsBucket).Cursor()
+	prefix := uint64Key(number)
+	for k, _ := logs.Seek(prefix); k != nil && string(k[:8]) == string(prefix); k, _ = logs.Seek(prefix) {
+		if err := logs.Delete(); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Bucket(blockHashesBucket).Delete(hashKey(block.Hash)); err != nil {
+		return err
+	}
+	return tx.Bucket(blocksBucket).Delete(uint64Key(number))
+}
+
+// HasBlock reports whether the block at number has been ingested
+func (s *Store) HasBlock(number uint64) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(blocksBucket).Get(uint64Key(number)) != nil
+		return nil
+	})
+	return found, err
+}
+
+// GetBlock returns the block at number, with either full transaction objects
+// or transaction hashes
+func (s *Store) GetBlock(number uint64, fullTransactions bool) (*blockchain.Block, error) {
+	var block blockchain.Block
+	err := s.db.View(func(tx *bolt.Tx) error {
+		blockBytes := tx.Bucket(blocksBucket).Get(uint64Key(number))
+		if blockBytes == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(blockBytes, &block)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !fullTransactions {
+		hashes, err := block.TransactionHashes()
+		if err != nil {
+			return nil, err
+		}
+		if block.Transactions, err = json.Marshal(hashes); err != nil {
+			return nil, fmt.Errorf("failed to marshal transaction hashes: %w", err)
+		}
+	}
+
+	return &block, nil
+}
+
+// GetBlockNumberByHash returns the number of the stored block with hash
+func (s *Store) GetBlockNumberByHash(hash string) (uint64, error) {
+	var number uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(blockHashesBucket).Get(hashKey(hash))
+		if v == nil {
+			return ErrNotFound
+		}
+		number = binary.BigEndian.Uint64(v)
+		return nil
+	})
+	return number, err
+}
+
+// GetTransaction returns the raw JSON of the transaction with hash
+func (s *Store) GetTransaction(hash string) (json.RawMessage, error) {
+	return s.getRaw(transactionsBucket, hashKey(hash))
+}
+
+// GetReceipt returns the receipt of the transaction with hash
+func (s *Store) GetReceipt(hash string) (*blockchain.Receipt, error) {
+	raw, err := s.getRaw(receiptsBucket, hashKey(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt blockchain.Receipt
+	if err := json.Unmarshal(raw, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stored receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// GetLogs returns the logs emitted in the block at number, in log index order
+func (s *Store) GetLogs(number uint64) ([]*blockchain.Log, error) {
+	logs := []*blockchain.Log{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(logsBucket).Cursor()
+		prefix := uint64Key(number)
+		for k, v := c.Seek(prefix); k != nil && string(k[:8]) == string(prefix); k, v = c.Next() {
+			var l blockchain.Log
+			if err := json.Unmarshal(v, &l); err != nil {
+				return fmt.Errorf("failed to unmarshal stored log: %w", err)
+			}
+			logs = append(logs, &l)
+		}
+		return nil
+	})
+	return logs, err
+}
+
+// getRaw returns a copy of the value stored under key in bucket
+func (s *Store) getRaw(bucket, key []byte) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key)
+		if v == nil {
+			return ErrNotFound
+		}
+		raw = append(json.RawMessage(nil), v...)
+		return nil
+	})
+	return raw, err
+}
+
+// Head returns the highest stored block number
+func (s *Store) Head() (uint64, bool, error) {
+	var head uint64
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(blocksBucket).Cursor().Last()
+		if k != nil {
+			head = binary.BigEndian.Uint64(k)
+			found = true
+		}
+		return nil
+	})
+	return head, found, err
+}
+
+// Checkpoint returns the height recorded under name
+func (s *Store) Checkpoint(name string) (uint64, bool, error) {
+	var height uint64
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metaBucket).Get([]byte("checkpoint:" + name))
+		if v != nil {
+			height = binary.BigEndian.Uint64(v)
+			found = true
+		}
+		return nil
+	})
+	return height, found, err
+}
+
+// SetCheckpoint records height under name
+func (s *Store) SetCheckpoint(name string, height uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put([]byte("checkpoint:"+name), uint64Key(height))
+	})
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"blockchain-client/pkg/blockchain"
+)
+
+// openTestStore opens a store in a temporary directory
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	st, err := Open(filepath.Join(t.TempDir(), "blocks.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// testBlockData builds a block with two transactions, one of which emitted a log
+func testBlockData(number, hash string) *BlockData {
+	return &BlockData{
+		Block: &blockchain.Block{
+			Number:     number,
+			Hash:       hash,
+			ParentHash: "0xparent",
+			Transactions: json.RawMessage(`[
+				{"hash": "0xTX1", "from": "0xaddr1", "to": "0xaddr2", "v": "0x1"},
+				{"hash": "0xtx2", "from": "0xaddr3", "to": "0xaddr4"}
+			]`),
+			TransactionCount: 2,
+		},
+		Receipts: []*blockchain.Receipt{
+			{
+				TransactionHash: "0xtx1",
+				Status:          "0x1",
+				Logs: []*blockchain.Log{
+					{Address: "0xtoken", Topics: []string{"0xtopic"}, LogIndex: "0x0", BlockNumber: number},
+				},
+			},
+			{TransactionHash: "0xtx2", Status: "0x1", Logs: []*blockchain.Log{}},
+		},
+	}
+}
+
+func TestPutAndGetBlock(t *testing.T) {
+	st := openTestStore(t)
+
+	if err := st.PutBlock(testBlockData("0x10", "0xblock16")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("full transactions", func(t *testing.T) {
+		block, err := st.GetBlock(16, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if block.Hash != "0xblock16" {
+			t.Errorf("expected hash 0xblock16, got %s", block.Hash)
+		}
+		txs, err := block.FullTransactions()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(txs) != 2 || txs[1].From != "0xaddr3" {
+			t.Errorf("expected 2 full transactions, got %+v", txs)
+		}
+	})
+
+	t.Run("transaction hashes only", func(t *testing.T) {
+		block, err := st.GetBlock(16, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var hashes []string
+		if err := json.Unmarshal(block.Transactions, &hashes); err != nil {
+			t.Fatalf("expected transaction hashes, got %s", block.Transactions)
+		}
+		if len(hashes) != 2 || hashes[0] != "0xTX1" {
+			t.Errorf("expected hashes [0xTX1 0xtx2], got %v", hashes)
+		}
+		if block.TransactionCount != 2 {
+			t.Errorf("expected transaction count 2, got %d", block.TransactionCount)
+		}
+	})
+
+	t.Run("transactions receipts and logs", func(t *testing.T) {
+		raw, err := st.GetTransaction("0xtx1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var tx map[string]interface{}
+		json.Unmarshal(raw, &tx)
+		if tx["v"] != "0x1" {
+			t.Errorf("expected raw transaction fields to be preserved, got %s", raw)
+		}
+
+		receipt, err := st.GetReceipt("0xTX1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(receipt.Logs) != 1 {
+			t.Errorf("expected 1 log in receipt, got %d", len(receipt.Logs))
+		}
+
+		logs, err := st.GetLogs(16)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(logs) != 1 || logs[0].Address != "0xtoken" {
+			t.Errorf("expected 1 log from 0xtoken, got %+v", logs)
+		}
+
+		number, err := st.GetBlockNumberByHash("0xBLOCK16")
+		if err != nil || number != 16 {
+			t.Errorf("expected block 16 by hash, got %d (%v)", number, err)
+		}
+	})
+
+	t.Run("missing block", func(t *testing.T) {
+		if _, err := st.GetBlock(17, true); err != ErrNotFound {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestDeleteBlock(t *testing.T) {
+	st := openTestStore(t)
+
+	if err := st.PutBlock(testBlockData("0x10", "0xblock16")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.DeleteBlock(16); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ok, _ := st.HasBlock(16); ok {
+		t.Errorf("expected block 16 to be deleted")
+	}
+	if _, err := st.GetTransaction("0xtx1"); err != ErrNotFound {
+		t.Errorf("expected transaction to be deleted, got %v", err)
+	}
+	if _, err := st.GetReceipt("0xtx1"); err != ErrNotFound {
+		t.Errorf("expected receipt to be deleted, got %v", err)
+	}
+	if logs, _ := st.GetLogs(16); len(logs) != 0 {
+		t.Errorf("expected logs to be deleted, got %d", len(logs))
+	}
+	if _, err := st.GetBlockNumberByHash("0xblock16"); err != ErrNotFound {
+		t.Errorf("expected hash index entry to be deleted, got %v", err)
+	}
+}
+
+func TestHeadAndCheckpoint(t *testing.T) {
+	st := openTestStore(t)
+
+	if _, ok, _ := st.Head(); ok {
+		t.Errorf("expected empty store to have no head")
+	}
+
+	st.PutBlock(testBlockData("0x100", "0xa"))
+	st.PutBlock(testBlockData("0xff", "0xb"))
+
+	head, ok, err := st.Head()
+	if err != nil || !ok || head != 256 {
+		t.Errorf("expected head 256, got %d (%v, %v)", head, ok, err)
+	}
+
+	if _, ok, _ := st.Checkpoint("backfill"); ok {
+		t.Errorf("expected no checkpoint")
+	}
+	if err := st.SetCheckpoint("backfill", 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkpoint, ok, err := st.Checkpoint("backfill")
+	if err != nil || !ok || checkpoint != 42 {
+		t.Errorf("expected checkpoint 42, got %d (%v, %v)", checkpoint, ok, err)
+	}
+}
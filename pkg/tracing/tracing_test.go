@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "exported")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read trace file: %v", err)
+	}
+	var exported struct {
+		Name     string
+		Resource []struct {
+			Key   string
+			Value struct{ Value interface{} }
+		}
+	}
+	if err := json.Unmarshal([]byte(strings.SplitN(string(data), "\n", 2)[0]), &exported); err != nil {
+		t.Fatalf("invalid span JSON: %v", err)
+	}
+	if exported.Name != "exported" {
+		t.Errorf("expected span %q, got %q", "exported", exported.Name)
+	}
+
+	found := false
+	for _, kv := range exported.Resource {
+		if kv.Key == "service.name" && kv.Value.Value == DefaultServiceName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected service.name %q in resource %+v", DefaultServiceName, exported.Resource)
+	}
+}
+
+func TestSetupValidation(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Errorf("expected error for unsupported exporter")
+	}
+	if _, err := Setup(context.Background(), Config{Exporter: ExporterFile}); err == nil {
+		t.Errorf("expected error for missing trace file")
+	}
+}
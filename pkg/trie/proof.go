@@ -0,0 +1,109 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"blockchain-client/pkg/rlp"
+)
+
+// ErrInvalidProof is returned when a Merkle proof does not lead from the root
+// to a value or to proof that the key is absent
+var ErrInvalidProof = errors.New("invalid Merkle proof")
+
+// VerifyProof walks proof, the trie nodes on the path to key such as those
+// returned by eth_getProof, from root. It returns the value stored at key,
+// or nil if the proof shows that key is absent.
+func VerifyProof(root, key []byte, proof [][]byte) ([]byte, error) {
+	nodes := make(map[string][]byte, len(proof))
+	for _, node := range proof {
+		nodes[string(keccak(node))] = node
+	}
+
+	if bytes.Equal(root, EmptyRoot) {
+		return nil, nil
+	}
+	path := nibbles(key)
+	node, ok := nodes[string(root)]
+	if !ok {
+		return nil, fmt.Errorf("%w: root node is missing", ErrInvalidProof)
+	}
+
+	for {
+		items, err := rlp.DecodeList(node)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+		}
+
+		var child []byte
+		switch len(items) {
+		case 17:
+			if len(path) == 0 {
+				return decodeValue(items[16])
+			}
+			child, path = items[path[0]], path[1:]
+
+		case 2:
+			compact, err := rlp.DecodeBytes(items[0])
+			if err != nil || len(compact) == 0 {
+				return nil, fmt.Errorf("%w: malformed node key", ErrInvalidProof)
+			}
+			nodePath, leaf := decodeCompact(compact)
+			if leaf {
+				if !bytes.Equal(path, nodePath) {
+					return nil, nil
+				}
+				return decodeValue(items[1])
+			}
+			if !bytes.HasPrefix(path, nodePath) {
+				return nil, nil
+			}
+			child, path = items[1], path[len(nodePath):]
+
+		default:
+			return nil, fmt.Errorf("%w: node with %d items", ErrInvalidProof, len(items))
+		}
+
+		// Children are embedded when shorter than a hash, referred to by
+		// hash otherwise, or empty when no key continues this way
+		kind, content, _, err := rlp.Split(child)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+		case kind == rlp.List:
+			node = child
+		case len(content) == 0:
+			return nil, nil
+		case len(content) == 32:
+			if node, ok = nodes[string(content)]; !ok {
+				return nil, fmt.Errorf("%w: node %x is missing", ErrInvalidProof, content)
+			}
+		default:
+			return nil, fmt.Errorf("%w: malformed child reference", ErrInvalidProof)
+		}
+	}
+}
+
+// decodeValue decodes a value slot, where the empty string means no value
+func decodeValue(item []byte) ([]byte, error) {
+	value, err := rlp.DecodeBytes(item)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// decodeCompact reverses compactKey, returning the nibble path and whether
+// it ends in a leaf
+func decodeCompact(compact []byte) (path []byte, leaf bool) {
+	flag := compact[0] >> 4
+	path = nibbles(compact)[2:]
+	if flag&1 == 1 {
+		path = append([]byte{compact[0] & 0x0f}, path...)
+	}
+	return path, flag&2 == 2
+}
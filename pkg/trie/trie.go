@@ -0,0 +1,138 @@
+// Package trie computes the roots of Ethereum Merkle-Patricia tries, which
+// commit block headers to their transactions and receipts
+package trie
+
+import (
+	"bytes"
+	"slices"
+
+	"golang.org/x/crypto/sha3"
+
+	"blockchain-client/pkg/rlp"
+)
+
+// EmptyRoot is the root of a trie with no entries
+var EmptyRoot = keccak(rlp.EncodeBytes(nil))
+
+// entry is a key, split into nibbles, and its value
+type entry struct {
+	key   []byte
+	value []byte
+}
+
+// DeriveRoot returns the root of the trie holding each value at the RLP
+// encoding of its index, as used for the transactions and receipts roots
+func DeriveRoot(values [][]byte) []byte {
+	keys := make([][]byte, len(values))
+	for i := range values {
+		keys[i] = rlp.EncodeUint(uint64(i))
+	}
+	return Root(keys, values)
+}
+
+// Root returns the root of the trie holding values[i] at keys[i]. Keys must
+// be unique.
+func Root(keys, values [][]byte) []byte {
+	if len(keys) == 0 {
+		return EmptyRoot
+	}
+	entries := make([]entry, len(keys))
+	for i, key := range keys {
+		entries[i] = entry{key: nibbles(key), value: values[i]}
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	return keccak(encodeNode(entries, 0))
+}
+
+// encodeNode returns the RLP encoding of the node holding entries, whose keys
+// are sorted and share their first depth nibbles
+func encodeNode(entries []entry, depth int) []byte {
+	if len(entries) == 1 {
+		return rlp.EncodeList(
+			rlp.EncodeBytes(compactKey(entries[0].key[depth:], true)),
+			rlp.EncodeBytes(entries[0].value),
+		)
+	}
+
+	// Sorted keys share a prefix exactly when the first and last do
+	first, last := entries[0].key, entries[len(entries)-1].key
+	shared := 0
+	for depth+shared < len(first) && depth+shared < len(last) && first[depth+shared] == last[depth+shared] {
+		shared++
+	}
+	if shared > 0 {
+		return rlp.EncodeList(
+			rlp.EncodeBytes(compactKey(first[depth:depth+shared], false)),
+			reference(encodeNode(entries, depth+shared)),
+		)
+	}
+
+	// A branch has a child for each next nibble and holds the value of a key
+	// that ends here
+	items := make([][]byte, 17)
+	for i := range items {
+		items[i] = rlp.EncodeBytes(nil)
+	}
+	for len(entries) > 0 {
+		if len(entries[0].key) == depth {
+			items[16] = rlp.EncodeBytes(entries[0].value)
+			entries = entries[1:]
+			continue
+		}
+		nibble := entries[0].key[depth]
+		n := 1
+		for n < len(entries) && entries[n].key[depth] == nibble {
+			n++
+		}
+		items[nibble] = reference(encodeNode(entries[:n], depth+1))
+		entries = entries[n:]
+	}
+	return rlp.EncodeList(items...)
+}
+
+// reference returns how a parent refers to a child node: nodes shorter than
+// a hash are embedded, longer ones are referred to by their hash
+func reference(node []byte) []byte {
+	if len(node) < 32 {
+		return node
+	}
+	return rlp.EncodeBytes(keccak(node))
+}
+
+// nibbles splits key into 4-bit nibbles
+func nibbles(key []byte) []byte {
+	out := make([]byte, 2*len(key))
+	for i, b := range key {
+		out[2*i], out[2*i+1] = b>>4, b&0x0f
+	}
+	return out
+}
+
+// compactKey applies hex-prefix encoding to a nibble path, flagging whether
+// it ends in a leaf and whether it has an odd length
+func compactKey(path []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+	if len(path)%2 == 1 {
+		flag++
+		path = append([]byte{flag}, path...)
+	} else {
+		path = append([]byte{flag, 0}, path...)
+	}
+	out := make([]byte, len(path)/2)
+	for i := range out {
+		out[i] = path[2*i]<<4 | path[2*i+1]
+	}
+	return out
+}
+
+// keccak returns the Keccak-256 hash of b
+func keccak(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,75 @@
+package trie
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDeriveRoot(t *testing.T) {
+	many := make([][]byte, 300)
+	for i := range many {
+		many[i] = []byte(fmt.Sprintf("value-%d", i))
+	}
+
+	// Roots computed with go-ethereum's DeriveSha
+	tests := []struct {
+		name   string
+		values [][]byte
+		want   string
+	}{
+		{"empty", nil, "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+		{"single", [][]byte{[]byte("a")}, "0934a6a4a84a455b46bf2e427c90fbc07dbce3fd6fe5b9213cc6cd7c8030792c"},
+		{"300 entries", many, "e5f9603ca27948158156a3707251f1731bf208e6bc2aa97c2ac7d5da4d51e71b"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(DeriveRoot(tt.values)); got != tt.want {
+			t.Errorf("%s: expected root %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRootIgnoresInsertionOrder(t *testing.T) {
+	keys := [][]byte{[]byte("doe"), []byte("dog"), []byte("dogglesworth")}
+	values := [][]byte{[]byte("reindeer"), []byte("puppy"), []byte("cat")}
+	reversed := [][]byte{keys[2], keys[1], keys[0]}
+	reversedValues := [][]byte{values[2], values[1], values[0]}
+
+	// Root of the example trie from the Ethereum wiki
+	want := "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3"
+	if got := hex.EncodeToString(Root(keys, values)); got != want {
+		t.Errorf("expected root %s, got %s", want, got)
+	}
+	if got := hex.EncodeToString(Root(reversed, reversedValues)); got != want {
+		t.Errorf("expected the root to be independent of order, got %s", got)
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	key, value := []byte("dog"), []byte("puppy")
+	leaf := encodeNode([]entry{{key: nibbles(key), value: value}}, 0)
+	root := Root([][]byte{key}, [][]byte{value})
+
+	got, err := VerifyProof(root, key, [][]byte{leaf})
+	if err != nil || string(got) != "puppy" {
+		t.Errorf("expected puppy, got %q (%v)", got, err)
+	}
+
+	if got, err := VerifyProof(root, []byte("cat"), [][]byte{leaf}); err != nil || got != nil {
+		t.Errorf("expected proof of absence, got %q (%v)", got, err)
+	}
+
+	if _, err := VerifyProof(root, key, nil); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("expected a missing root node to be rejected, got %v", err)
+	}
+
+	tampered := encodeNode([]entry{{key: nibbles(key), value: []byte("kitten")}}, 0)
+	if _, err := VerifyProof(root, key, [][]byte{tampered}); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("expected a tampered node to be rejected, got %v", err)
+	}
+
+	if got, err := VerifyProof(EmptyRoot, key, nil); err != nil || got != nil {
+		t.Errorf("expected every key to be absent from an empty trie, got %q (%v)", got, err)
+	}
+}
@@ -140,7 +140,7 @@ resource "aws_ecs_task_definition" "blockchain_client" {
         }
       }
       healthCheck = {
-        command     = ["CMD-SHELL", "wget -q --spider http://localhost:8080/api/blocks/latest || exit 1"]
+        command     = ["CMD", "/app/blockchain-client", "healthcheck"]
         interval    = 30
         timeout     = 5
         retries     = 3
@@ -193,7 +193,7 @@ resource "aws_lb_target_group" "blockchain_client" {
   health_check {
     enabled             = true
     interval            = 30
-    path                = "/api/blocks/latest"
+    path                = "/readyz"
     port                = "traffic-port"
     healthy_threshold   = 3
     unhealthy_threshold = 3